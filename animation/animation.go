@@ -0,0 +1,72 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+/*
+Package animation implements animations of values over time.
+
+Tweens animate from 0 to 1 over a duration, along an Easing curve.
+Keyframes animate through a sequence of values, Springs move values
+towards targets according to the physics of a damped spring, and Decays
+slow down a velocity by friction. A Transition animates a value whenever
+its target changes, and is convenient for animating between widget
+states.
+
+Animations are advanced by the time of the frame, usually
+layout.Context.Now, and request a redraw through an operation list while
+they are running. Animations that are done or stopped request no frames,
+and neither do animations advanced with a nil operation list.
+
+For example, to fade in a widget when it is first shown:
+
+	var fade animation.Transition
+	fade.Duration = 200 * time.Millisecond
+	...
+	alpha := fade.Animate(gtx.Ops, gtx.Now, 1)
+*/
+package animation
+
+import (
+	"image/color"
+
+	"github.com/xiaoshengduan/gio-fly/f32"
+	"github.com/xiaoshengduan/gio-fly/op"
+)
+
+// Lerp interpolates linearly between a and b by t, where t is 0 at a
+// and 1 at b.
+func Lerp(a, b, t float32) float32 {
+	return a + (b-a)*t
+}
+
+// LerpPoint interpolates linearly between the points a and b by t.
+func LerpPoint(a, b f32.Point, t float32) f32.Point {
+	return f32.Pt(Lerp(a.X, b.X, t), Lerp(a.Y, b.Y, t))
+}
+
+// LerpColor interpolates linearly between the colors a and b by t. The
+// color channels are clamped when t is outside [0,1].
+func LerpColor(a, b color.NRGBA, t float32) color.NRGBA {
+	return color.NRGBA{
+		R: lerpByte(a.R, b.R, t),
+		G: lerpByte(a.G, b.G, t),
+		B: lerpByte(a.B, b.B, t),
+		A: lerpByte(a.A, b.A, t),
+	}
+}
+
+func lerpByte(a, b uint8, t float32) uint8 {
+	v := Lerp(float32(a), float32(b), t) + .5
+	switch {
+	case v < 0:
+		return 0
+	case v > 0xff:
+		return 0xff
+	}
+	return uint8(v)
+}
+
+// invalidate requests a redraw if ops is not nil.
+func invalidate(ops *op.Ops) {
+	if ops != nil {
+		op.InvalidateOp{}.Add(ops)
+	}
+}
@@ -0,0 +1,217 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package animation
+
+import (
+	"image/color"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/xiaoshengduan/gio-fly/io/router"
+	"github.com/xiaoshengduan/gio-fly/op"
+)
+
+var epoch = time.Unix(1000, 0)
+
+func at(d time.Duration) time.Time {
+	return epoch.Add(d)
+}
+
+func approx(a, b float32) bool {
+	return math.Abs(float64(a-b)) < 1e-3
+}
+
+// invalidated reports whether o requests a new frame.
+func invalidated(o *op.Ops) bool {
+	var r router.Router
+	r.Frame(o)
+	_, ok := r.WakeupTime()
+	return ok
+}
+
+func TestEasing(t *testing.T) {
+	easings := map[string]Easing{
+		"Linear":      Linear,
+		"EaseIn":      EaseIn,
+		"EaseOut":     EaseOut,
+		"EaseInOut":   EaseInOut,
+		"SmoothStep":  SmoothStep,
+		"CubicBezier": CubicBezier(.25, .1, .25, 1),
+		"Steps":       Steps(4),
+	}
+	for name, e := range easings {
+		if got := e(0); !approx(got, 0) {
+			t.Errorf("%s(0) = %v, want 0", name, got)
+		}
+		if got := e(1); !approx(got, 1) {
+			t.Errorf("%s(1) = %v, want 1", name, got)
+		}
+	}
+	if got := EaseInOut(.5); !approx(got, .5) {
+		t.Errorf("EaseInOut(.5) = %v, want .5", got)
+	}
+	// A Bézier curve with control points on the diagonal is linear.
+	lin := CubicBezier(.3, .3, .7, .7)
+	for _, x := range []float32{.1, .25, .5, .9} {
+		if got := lin(x); !approx(got, x) {
+			t.Errorf("linear CubicBezier(%v) = %v", x, got)
+		}
+	}
+	if got := Steps(4)(.6); got != .5 {
+		t.Errorf("Steps(4)(.6) = %v, want .5", got)
+	}
+}
+
+func TestTween(t *testing.T) {
+	tw := Tween{Duration: time.Second}
+	o := new(op.Ops)
+	if got := tw.Progress(o, epoch); got != 0 || tw.Running() || invalidated(o) {
+		t.Errorf("unstarted tween: progress %v, running %v", got, tw.Running())
+	}
+	tw.Start(epoch)
+	o.Reset()
+	if got := tw.Progress(o, at(250*time.Millisecond)); !approx(got, .25) {
+		t.Errorf("got progress %v, want .25", got)
+	}
+	if !invalidated(o) {
+		t.Error("running tween didn't request a frame")
+	}
+	o.Reset()
+	if got := tw.Progress(o, at(2*time.Second)); got != 1 || tw.Running() {
+		t.Errorf("done tween: progress %v, running %v", got, tw.Running())
+	}
+	if invalidated(o) {
+		t.Error("done tween requested a frame")
+	}
+
+	tw = Tween{Duration: time.Second, Repeat: 2, Alternate: true, Easing: EaseIn}
+	tw.Start(epoch)
+	if got := tw.Progress(nil, at(1500*time.Millisecond)); !approx(got, EaseIn(.5)) {
+		t.Errorf("alternating: got progress %v, want %v", got, EaseIn(.5))
+	}
+	if got := tw.Progress(nil, at(1750*time.Millisecond)); !approx(got, EaseIn(.25)) {
+		t.Errorf("alternating backwards: got progress %v, want %v", got, EaseIn(.25))
+	}
+	if got := tw.Progress(nil, at(3*time.Second)); got != 1 || tw.Running() {
+		t.Errorf("alternating done: progress %v, running %v", got, tw.Running())
+	}
+
+	tw = Tween{Duration: time.Second, Repeat: -1}
+	tw.Start(epoch)
+	if got := tw.Progress(nil, at(time.Hour+100*time.Millisecond)); !approx(got, .1) || !tw.Running() {
+		t.Errorf("repeating: progress %v, running %v", got, tw.Running())
+	}
+}
+
+func TestKeyframes(t *testing.T) {
+	k := Keyframes{Frames: []Keyframe{
+		{At: 0, Value: 10},
+		{At: time.Second, Value: 20},
+		{At: 3 * time.Second, Value: 0, Easing: Steps(2)},
+	}}
+	if got := k.Value(nil, epoch); got != 10 {
+		t.Errorf("unstarted: got %v, want 10", got)
+	}
+	k.Start(epoch)
+	for _, tc := range []struct {
+		at   time.Duration
+		want float32
+	}{
+		{500 * time.Millisecond, 15},
+		{time.Second, 20},
+		{1500 * time.Millisecond, 20},
+		{2500 * time.Millisecond, 10},
+		{4 * time.Second, 0},
+	} {
+		if got := k.Value(nil, at(tc.at)); !approx(got, tc.want) {
+			t.Errorf("at %v: got %v, want %v", tc.at, got, tc.want)
+		}
+	}
+	if k.Running() {
+		t.Error("keyframes still running after the last keyframe")
+	}
+}
+
+func TestTransition(t *testing.T) {
+	tr := Transition{Duration: time.Second}
+	if got := tr.Animate(nil, epoch, 1); got != 1 || tr.Running() {
+		t.Errorf("initial value: got %v, running %v", got, tr.Running())
+	}
+	tr.Animate(nil, epoch, 3)
+	if got := tr.Animate(nil, at(500*time.Millisecond), 3); !approx(got, 2) {
+		t.Errorf("got %v, want 2", got)
+	}
+	// Retargeting starts from the current value.
+	tr.Animate(nil, at(500*time.Millisecond), 0)
+	if got := tr.Animate(nil, at(time.Second), 0); !approx(got, 1) {
+		t.Errorf("retargeted: got %v, want 1", got)
+	}
+	if got := tr.Animate(nil, at(2*time.Second), 0); got != 0 || tr.Running() {
+		t.Errorf("done: got %v, running %v", got, tr.Running())
+	}
+}
+
+func TestSpring(t *testing.T) {
+	var s Spring
+	s.Set(0)
+	s.SetTarget(1)
+	o := new(op.Ops)
+	now := epoch
+	var frames int
+	var prev float32
+	for s.Running() && frames < 1000 {
+		o.Reset()
+		v := s.Value(o, now)
+		if v < prev || v > 1 {
+			t.Fatalf("critically damped spring moved from %v to %v", prev, v)
+		}
+		if s.Running() != invalidated(o) {
+			t.Fatalf("running %v, but requested frame %v", s.Running(), invalidated(o))
+		}
+		prev = v
+		now = now.Add(time.Second / 60)
+		frames++
+	}
+	if s.Running() || s.Value(nil, now) != 1 {
+		t.Errorf("spring didn't settle at its target")
+	}
+
+	// An underdamped spring overshoots.
+	s = Spring{DampingRatio: .2}
+	s.SetTarget(1)
+	var peak float32
+	for i, now := 0, epoch; i < 100; i, now = i+1, now.Add(time.Second/60) {
+		if v := s.Value(nil, now); v > peak {
+			peak = v
+		}
+	}
+	if peak <= 1 {
+		t.Errorf("underdamped spring didn't overshoot, peak %v", peak)
+	}
+}
+
+func TestDecay(t *testing.T) {
+	var d Decay
+	d.Start(epoch, 100)
+	if got, want := d.Distance(), float32(100/4.2); !approx(got, want) {
+		t.Errorf("got distance %v, want %v", got, want)
+	}
+	if got := d.Value(nil, epoch); got != 0 {
+		t.Errorf("got %v at start, want 0", got)
+	}
+	if got := d.Value(nil, at(10*time.Second)); !approx(got, d.Distance()) || d.Running() {
+		t.Errorf("stopped: got %v, running %v", got, d.Running())
+	}
+}
+
+func TestLerpColor(t *testing.T) {
+	a := color.NRGBA{R: 0, G: 100, B: 200, A: 255}
+	b := color.NRGBA{R: 100, G: 100, B: 0, A: 0}
+	if got, want := LerpColor(a, b, .5), (color.NRGBA{R: 50, G: 100, B: 100, A: 128}); got != want {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got, want := LerpColor(a, b, 2), (color.NRGBA{R: 200, G: 100, B: 0, A: 0}); got != want {
+		t.Errorf("extrapolated: got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,87 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package animation
+
+import "math"
+
+// Easing maps the linear progress t of an animation, in the range
+// [0,1], to its eased progress. Easings map 0 to 0 and 1 to 1, but may
+// overshoot in between.
+type Easing func(t float32) float32
+
+var (
+	// Linear progresses at constant speed.
+	Linear Easing = func(t float32) float32 { return t }
+	// EaseIn starts slowly and accelerates.
+	EaseIn Easing = func(t float32) float32 { return t * t * t }
+	// EaseOut starts quickly and decelerates.
+	EaseOut Easing = func(t float32) float32 {
+		t = 1 - t
+		return 1 - t*t*t
+	}
+	// EaseInOut accelerates until the middle, then decelerates.
+	EaseInOut Easing = func(t float32) float32 {
+		if t < .5 {
+			return 4 * t * t * t
+		}
+		t = 2 - 2*t
+		return 1 - t*t*t/2
+	}
+	// SmoothStep is a gentle ease in and out, 3t²-2t³.
+	SmoothStep Easing = func(t float32) float32 { return t * t * (3 - 2*t) }
+)
+
+// CubicBezier returns the Easing of a cubic Bézier curve from (0, 0) to
+// (1, 1) with the control points (x1, y1) and (x2, y2), as in the CSS
+// cubic-bezier function. x1 and x2 must be in the range [0,1].
+func CubicBezier(x1, y1, x2, y2 float32) Easing {
+	bezier := func(p1, p2, t float32) float32 {
+		u := 1 - t
+		return 3*u*u*t*p1 + 3*u*t*t*p2 + t*t*t
+	}
+	slope := func(p1, p2, t float32) float32 {
+		u := 1 - t
+		return 3*u*u*p1 + 6*u*t*(p2-p1) + 3*t*t*(1-p2)
+	}
+	return func(x float32) float32 {
+		if x <= 0 || x >= 1 {
+			return x
+		}
+		// Find the curve parameter for x with Newton's method, falling
+		// back to bisection for flat slopes.
+		t := x
+		for i := 0; i < 8; i++ {
+			d := bezier(x1, x2, t) - x
+			if math.Abs(float64(d)) < 1e-6 {
+				return bezier(y1, y2, t)
+			}
+			s := slope(x1, x2, t)
+			if math.Abs(float64(s)) < 1e-6 {
+				break
+			}
+			t -= d / s
+		}
+		lo, hi := float32(0), float32(1)
+		t = x
+		for i := 0; i < 32; i++ {
+			if bezier(x1, x2, t) < x {
+				lo = t
+			} else {
+				hi = t
+			}
+			t = (lo + hi) / 2
+		}
+		return bezier(y1, y2, t)
+	}
+}
+
+// Steps returns an Easing that jumps in n equal steps, at the end of
+// every step.
+func Steps(n int) Easing {
+	return func(t float32) float32 {
+		if t >= 1 {
+			return 1
+		}
+		return float32(math.Floor(float64(t)*float64(n))) / float32(n)
+	}
+}
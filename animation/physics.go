@@ -0,0 +1,217 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package animation
+
+import (
+	"math"
+	"time"
+
+	"github.com/xiaoshengduan/gio-fly/op"
+)
+
+// Spring moves a value towards a target as if attached to it by a damped
+// spring. Changing the target while the spring moves preserves its
+// velocity, for natural responses to interrupted gestures.
+type Spring struct {
+	// Stiffness is the spring constant, for a unit mass. Zero means 300.
+	Stiffness float32
+	// DampingRatio is the ratio of the damping to critical damping.
+	// Ratios below 1 bounce around the target, ratios above 1 approach it
+	// slowly. Zero means 1.
+	DampingRatio float32
+	// Threshold is the precision of the spring. The spring comes to
+	// rest when it is within Threshold of its target, and moves less
+	// than Threshold per 1/60th of a second. Zero means 0.001.
+	Threshold float32
+
+	value, velocity, target float32
+	last                    time.Time
+	running                 bool
+}
+
+const (
+	defaultStiffness = 300
+	defaultThreshold = 0.001
+	// springStep is the time step of the spring simulation.
+	springStep = time.Millisecond
+	// maxFrameTime limits the simulated time between frames, to avoid
+	// long simulations after pauses.
+	maxFrameTime = 100 * time.Millisecond
+)
+
+// Set the value and target of the spring to v, at rest.
+func (s *Spring) Set(v float32) {
+	s.value, s.target, s.velocity = v, v, 0
+	s.running = false
+}
+
+// SetTarget sets the target of the spring, and sets the spring in
+// motion if the target differs from the value.
+func (s *Spring) SetTarget(v float32) {
+	s.target = v
+	s.start()
+}
+
+// Target returns the target of the spring.
+func (s *Spring) Target() float32 {
+	return s.target
+}
+
+// SetVelocity sets the velocity of the spring, in units per second.
+func (s *Spring) SetVelocity(v float32) {
+	s.velocity = v
+	s.start()
+}
+
+// Velocity returns the velocity of the spring, in units per second.
+func (s *Spring) Velocity() float32 {
+	return s.velocity
+}
+
+// Running reports whether the spring is in motion.
+func (s *Spring) Running() bool {
+	return s.running
+}
+
+func (s *Spring) start() {
+	if s.running || s.value == s.target && s.velocity == 0 {
+		return
+	}
+	s.running = true
+	s.last = time.Time{}
+}
+
+// Value returns the value of the spring at now, and requests a new frame
+// through ops while the spring is in motion. The spring starts moving at
+// the first call to Value after its target or velocity change.
+func (s *Spring) Value(ops *op.Ops, now time.Time) float32 {
+	if !s.running {
+		return s.value
+	}
+	if s.last.IsZero() {
+		s.last = now
+	}
+	dt := now.Sub(s.last)
+	if dt > maxFrameTime {
+		dt = maxFrameTime
+	}
+	if dt > 0 {
+		s.last = now
+	}
+	k := s.Stiffness
+	if k == 0 {
+		k = defaultStiffness
+	}
+	ratio := s.DampingRatio
+	if ratio == 0 {
+		ratio = 1
+	}
+	c := 2 * ratio * float32(math.Sqrt(float64(k)))
+	// Integrate with the semi-implicit Euler method.
+	for dt > 0 {
+		step := springStep
+		if dt < step {
+			step = dt
+		}
+		dt -= step
+		h := float32(step.Seconds())
+		a := -k*(s.value-s.target) - c*s.velocity
+		s.velocity += a * h
+		s.value += s.velocity * h
+	}
+	thres := s.Threshold
+	if thres == 0 {
+		thres = defaultThreshold
+	}
+	if abs(s.value-s.target) < thres && abs(s.velocity)/60 < thres {
+		s.value, s.velocity = s.target, 0
+		s.running = false
+	} else {
+		invalidate(ops)
+	}
+	return s.value
+}
+
+// Decay moves a value with an initial velocity that decays exponentially
+// by friction, such as for flinging.
+type Decay struct {
+	// Friction is the rate of decay of the velocity. The velocity at
+	// time t after the start is v₀e^(-Friction·t). Zero means 4.2.
+	Friction float32
+	// MinVelocity is the velocity, in units per second, below which the
+	// motion stops. Zero means 1.
+	MinVelocity float32
+
+	start    time.Time
+	velocity float32
+	running  bool
+	value    float32
+}
+
+const defaultFriction = 4.2
+
+// Start the motion at now with an initial velocity, in units per second.
+// The value starts from 0.
+func (d *Decay) Start(now time.Time, velocity float32) {
+	d.start = now
+	d.velocity = velocity
+	d.value = 0
+	d.running = velocity != 0
+}
+
+// Stop the motion at its current value.
+func (d *Decay) Stop() {
+	d.running = false
+}
+
+// Running reports whether the value is in motion.
+func (d *Decay) Running() bool {
+	return d.running
+}
+
+// Distance returns the total distance of the motion, from the start
+// until it stops.
+func (d *Decay) Distance() float32 {
+	return d.velocity / d.friction()
+}
+
+// Value returns the distance moved at now, and requests a new frame
+// through ops while the value is in motion.
+func (d *Decay) Value(ops *op.Ops, now time.Time) float32 {
+	if !d.running {
+		return d.value
+	}
+	f := d.friction()
+	t := now.Sub(d.start).Seconds()
+	if t < 0 {
+		t = 0
+	}
+	// Integrating the velocity v₀e^(-ft) gives the distance
+	// v₀(1-e^(-ft))/f.
+	ekt := float32(math.Exp(-float64(f) * t))
+	d.value = d.velocity * (1 - ekt) / f
+	min := d.MinVelocity
+	if min == 0 {
+		min = 1
+	}
+	if abs(d.velocity*ekt) < min {
+		d.running = false
+	} else {
+		invalidate(ops)
+	}
+	return d.value
+}
+
+func (d *Decay) friction() float32 {
+	if d.Friction == 0 {
+		return defaultFriction
+	}
+	return d.Friction
+}
+
+func abs(v float32) float32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,204 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package animation
+
+import (
+	"sort"
+	"time"
+
+	"github.com/xiaoshengduan/gio-fly/op"
+)
+
+// Tween animates its progress from 0 to 1 over a duration.
+type Tween struct {
+	// Duration is the duration of a single run.
+	Duration time.Duration
+	// Easing is the easing curve of the progress. Nil means Linear.
+	Easing Easing
+	// Repeat is the number of times the tween runs again after its first
+	// run. Negative values repeat forever.
+	Repeat int
+	// Alternate runs every other repetition backwards, from 1 to 0.
+	Alternate bool
+
+	start   time.Time
+	running bool
+	value   float32
+}
+
+// Start the tween at now, from the beginning.
+func (t *Tween) Start(now time.Time) {
+	t.start = now
+	t.running = true
+	t.value = 0
+}
+
+// Stop the tween. The progress stays at its value when stopped.
+func (t *Tween) Stop() {
+	t.running = false
+}
+
+// Running reports whether the tween is started and not done.
+func (t *Tween) Running() bool {
+	return t.running
+}
+
+// Progress returns the eased progress of the tween at now, and requests a
+// new frame through ops while the tween is running. The progress is 0
+// before the tween is started, and stays at its final value when done.
+func (t *Tween) Progress(ops *op.Ops, now time.Time) float32 {
+	if !t.running {
+		return t.value
+	}
+	elapsed := now.Sub(t.start)
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	// run is the index of the current run.
+	var run int64
+	var p float32
+	done := true
+	if d := t.Duration; d > 0 {
+		run = int64(elapsed / d)
+		p = float32(elapsed%d) / float32(d)
+		done = t.Repeat >= 0 && run > int64(t.Repeat)
+	}
+	if done {
+		run, p = 0, 1
+		if t.Repeat > 0 {
+			run = int64(t.Repeat)
+		}
+	}
+	if t.Alternate && run%2 == 1 {
+		p = 1 - p
+	}
+	if t.Easing != nil {
+		p = t.Easing(p)
+	}
+	t.value = p
+	if done {
+		t.running = false
+	} else {
+		invalidate(ops)
+	}
+	return p
+}
+
+// Keyframes animates a value through a sequence of keyframes.
+type Keyframes struct {
+	// Frames are the keyframes, in order of time. The value before the
+	// first keyframe is the value of the first keyframe.
+	Frames []Keyframe
+	// Repeat and Alternate are as for Tween.
+	Repeat    int
+	Alternate bool
+
+	tween Tween
+}
+
+// Keyframe is a value at a point in time of a Keyframes animation.
+type Keyframe struct {
+	// At is the time of the keyframe, relative to the start of the
+	// animation.
+	At time.Duration
+	// Value is the value at the keyframe.
+	Value float32
+	// Easing is the easing curve from the previous keyframe to this.
+	// Nil means Linear.
+	Easing Easing
+}
+
+// Start the animation at now, from the first keyframe.
+func (k *Keyframes) Start(now time.Time) {
+	k.tween = Tween{}
+	if n := len(k.Frames); n > 0 {
+		k.tween.Duration = k.Frames[n-1].At
+	}
+	k.tween.Repeat = k.Repeat
+	k.tween.Alternate = k.Alternate
+	k.tween.Start(now)
+}
+
+// Stop the animation at its current value.
+func (k *Keyframes) Stop() {
+	k.tween.Stop()
+}
+
+// Running reports whether the animation is started and not done.
+func (k *Keyframes) Running() bool {
+	return k.tween.Running()
+}
+
+// Value returns the value at now, and requests a new frame through ops
+// while the animation is running. The value is the value of the first
+// keyframe before the animation is started.
+func (k *Keyframes) Value(ops *op.Ops, now time.Time) float32 {
+	frames := k.Frames
+	if len(frames) == 0 {
+		return 0
+	}
+	at := time.Duration(float64(k.tween.Progress(ops, now)) * float64(k.tween.Duration))
+	i := sort.Search(len(frames), func(i int) bool {
+		return frames[i].At > at
+	})
+	switch {
+	case i == 0:
+		return frames[0].Value
+	case i == len(frames):
+		return frames[i-1].Value
+	}
+	prev, next := frames[i-1], frames[i]
+	t := float32(at-prev.At) / float32(next.At-prev.At)
+	if next.Easing != nil {
+		t = next.Easing(t)
+	}
+	return Lerp(prev.Value, next.Value, t)
+}
+
+// Transition animates a value towards its target, starting a new
+// animation from the current value whenever the target changes.
+type Transition struct {
+	// Duration is the duration of an animation between targets.
+	Duration time.Duration
+	// Easing is the easing curve of the animations. Nil means Linear.
+	Easing Easing
+
+	tween    Tween
+	from, to float32
+	value    float32
+	set      bool
+}
+
+// Animate returns the value for target at now, and requests a new frame
+// through ops while the value is animating. The first target is
+// returned without animation.
+func (t *Transition) Animate(ops *op.Ops, now time.Time, target float32) float32 {
+	if !t.set {
+		t.set = true
+		t.from, t.to, t.value = target, target, target
+		return target
+	}
+	if target != t.to {
+		t.from, t.to = t.value, target
+		t.tween = Tween{Duration: t.Duration, Easing: t.Easing}
+		t.tween.Start(now)
+	}
+	if t.tween.Running() {
+		t.value = Lerp(t.from, t.to, t.tween.Progress(ops, now))
+	} else {
+		t.value = t.to
+	}
+	return t.value
+}
+
+// Set the value to target without animation.
+func (t *Transition) Set(target float32) {
+	t.set = true
+	t.from, t.to, t.value = target, target, target
+	t.tween.Stop()
+}
+
+// Running reports whether the value is animating.
+func (t *Transition) Running() bool {
+	return t.tween.Running()
+}
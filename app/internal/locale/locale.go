@@ -0,0 +1,123 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+// Package locale converts platform locale names to system.Locale values.
+package locale
+
+import (
+	"strings"
+
+	"github.com/xiaoshengduan/gio-fly/io/system"
+)
+
+// rtlLanguages are the languages written right-to-left in their default
+// script.
+var rtlLanguages = map[string]bool{
+	"ar":  true,
+	"arc": true,
+	"ckb": true,
+	"dv":  true,
+	"fa":  true,
+	"he":  true,
+	"iw":  true,
+	"ks":  true,
+	"nqo": true,
+	"ps":  true,
+	"sd":  true,
+	"syr": true,
+	"ug":  true,
+	"ur":  true,
+	"yi":  true,
+}
+
+// rtlScripts are the ISO 15924 codes of the scripts written
+// right-to-left.
+var rtlScripts = map[string]bool{
+	"adlm": true,
+	"arab": true,
+	"hebr": true,
+	"nkoo": true,
+	"rohg": true,
+	"syrc": true,
+	"thaa": true,
+}
+
+// posixScripts maps POSIX locale modifiers to script subtags.
+var posixScripts = map[string]string{
+	"latin":      "Latn",
+	"cyrillic":   "Cyrl",
+	"devanagari": "Deva",
+}
+
+// Parse returns the Locale for a BCP-47 language tag, such as "pt-BR", or
+// a POSIX locale name, such as "pt_BR.UTF-8". The "C" and "POSIX" locales
+// and empty names result in the zero Locale.
+func Parse(name string) system.Locale {
+	var script string
+	if i := strings.IndexByte(name, '@'); i != -1 {
+		script = posixScripts[name[i+1:]]
+		name = name[:i]
+	}
+	if i := strings.IndexByte(name, '.'); i != -1 {
+		name = name[:i]
+	}
+	if name == "" || name == "C" || name == "POSIX" {
+		return system.Locale{}
+	}
+	tags := strings.FieldsFunc(name, func(r rune) bool {
+		return r == '_' || r == '-'
+	})
+	if len(tags) == 0 {
+		return system.Locale{}
+	}
+	// Normalize the case of the subtags: lower case languages, title case
+	// scripts and upper case regions.
+	tags[0] = strings.ToLower(tags[0])
+	for i := 1; i < len(tags); i++ {
+		switch t := tags[i]; {
+		case len(t) == 4 && i == 1:
+			tags[i] = strings.ToUpper(t[:1]) + strings.ToLower(t[1:])
+		case len(t) == 2, len(t) == 3 && t[0] >= '0' && t[0] <= '9':
+			tags[i] = strings.ToUpper(t)
+		}
+	}
+	if script != "" && (len(tags) < 2 || len(tags[1]) != 4) {
+		tags = append(tags[:1], append([]string{script}, tags[1:]...)...)
+	}
+	l := system.Locale{
+		Language:  strings.Join(tags, "-"),
+		Direction: system.LTR,
+	}
+	rtl := rtlLanguages[tags[0]]
+	if len(tags) > 1 && len(tags[1]) == 4 {
+		// The script overrides the default of the language.
+		rtl = rtlScripts[strings.ToLower(tags[1])]
+	}
+	if rtl {
+		l.Direction = system.RTL
+	}
+	return l
+}
+
+// FromEnv returns the Locale of the user interface language from the
+// POSIX environment variables LANGUAGE, LC_ALL, LC_MESSAGES and LANG, as
+// returned by getenv.
+func FromEnv(getenv func(string) string) system.Locale {
+	var name string
+	for _, v := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if name = getenv(v); name != "" {
+			break
+		}
+	}
+	l := Parse(name)
+	if l.Language == "" {
+		// LANGUAGE is ignored for the C locale.
+		return l
+	}
+	// LANGUAGE is a list of languages in order of preference.
+	if langs := getenv("LANGUAGE"); langs != "" {
+		if first := strings.Split(langs, ":")[0]; first != "" {
+			l = Parse(first)
+		}
+	}
+	return l
+}
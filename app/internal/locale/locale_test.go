@@ -0,0 +1,56 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package locale
+
+import (
+	"testing"
+
+	"github.com/xiaoshengduan/gio-fly/io/system"
+)
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		lang string
+		dir  system.TextDirection
+	}{
+		{"", "", system.LTR},
+		{"C", "", system.LTR},
+		{"POSIX", "", system.LTR},
+		{"C.UTF-8", "", system.LTR},
+		{"en_US.UTF-8", "en-US", system.LTR},
+		{"de_DE@euro", "de-DE", system.LTR},
+		{"ar_EG.UTF-8", "ar-EG", system.RTL},
+		{"he", "he", system.RTL},
+		{"fa-IR", "fa-IR", system.RTL},
+		{"es-419", "es-419", system.LTR},
+		{"zh-hans-cn", "zh-Hans-CN", system.LTR},
+		{"sr_RS@latin", "sr-Latn-RS", system.LTR},
+		{"pa-Arab-PK", "pa-Arab-PK", system.RTL},
+		{"sd-Deva-IN", "sd-Deva-IN", system.LTR},
+	} {
+		l := Parse(tc.name)
+		if l.Language != tc.lang || l.Direction != tc.dir {
+			t.Errorf("Parse(%q) = %+v, want %q, %v", tc.name, l, tc.lang, tc.dir)
+		}
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	for _, tc := range []struct {
+		env  map[string]string
+		lang string
+	}{
+		{map[string]string{}, ""},
+		{map[string]string{"LANG": "fr_FR.UTF-8"}, "fr-FR"},
+		{map[string]string{"LANG": "fr_FR.UTF-8", "LC_MESSAGES": "it_IT"}, "it-IT"},
+		{map[string]string{"LANG": "fr_FR.UTF-8", "LC_ALL": "ja_JP"}, "ja-JP"},
+		{map[string]string{"LANG": "fr_FR.UTF-8", "LANGUAGE": "he:en"}, "he"},
+		{map[string]string{"LANG": "C", "LANGUAGE": "he:en"}, ""},
+	} {
+		l := FromEnv(func(k string) string { return tc.env[k] })
+		if l.Language != tc.lang {
+			t.Errorf("FromEnv(%v) = %q, want %q", tc.env, l.Language, tc.lang)
+		}
+	}
+}
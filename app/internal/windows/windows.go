@@ -288,6 +288,8 @@ const (
 
 	GHND = 0x0042
 
+	LOCALE_NAME_MAX_LENGTH = 85
+
 	CF_UNICODETEXT = 13
 	IMAGE_BITMAP   = 0
 	IMAGE_ICON     = 1
@@ -305,12 +307,13 @@ const (
 )
 
 var (
-	kernel32          = syscall.NewLazySystemDLL("kernel32.dll")
-	_GetModuleHandleW = kernel32.NewProc("GetModuleHandleW")
-	_GlobalAlloc      = kernel32.NewProc("GlobalAlloc")
-	_GlobalFree       = kernel32.NewProc("GlobalFree")
-	_GlobalLock       = kernel32.NewProc("GlobalLock")
-	_GlobalUnlock     = kernel32.NewProc("GlobalUnlock")
+	kernel32                  = syscall.NewLazySystemDLL("kernel32.dll")
+	_GetModuleHandleW         = kernel32.NewProc("GetModuleHandleW")
+	_GetUserDefaultLocaleName = kernel32.NewProc("GetUserDefaultLocaleName")
+	_GlobalAlloc              = kernel32.NewProc("GlobalAlloc")
+	_GlobalFree               = kernel32.NewProc("GlobalFree")
+	_GlobalLock               = kernel32.NewProc("GlobalLock")
+	_GlobalUnlock             = kernel32.NewProc("GlobalUnlock")
 
 	user32                       = syscall.NewLazySystemDLL("user32.dll")
 	_AdjustWindowRectEx          = user32.NewProc("AdjustWindowRectEx")
@@ -495,6 +498,17 @@ func GetSystemDPI() int {
 	}
 }
 
+// GetUserDefaultLocaleName returns the name of the user's locale, or the
+// empty string if it is not available.
+func GetUserDefaultLocaleName() string {
+	var buf [LOCALE_NAME_MAX_LENGTH]uint16
+	n, _, _ := _GetUserDefaultLocaleName.Call(uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)))
+	if n == 0 {
+		return ""
+	}
+	return syscall.UTF16ToString(buf[:])
+}
+
 func GetKeyState(nVirtKey int32) int16 {
 	c, _, _ := _GetKeyState.Call(uintptr(nVirtKey))
 	return int16(c)
@@ -36,6 +36,10 @@ static void jni_DeleteGlobalRef(JNIEnv *env, jobject obj) {
 	(*env)->DeleteGlobalRef(env, obj);
 }
 
+static void jni_DeleteLocalRef(JNIEnv *env, jobject obj) {
+	(*env)->DeleteLocalRef(env, obj);
+}
+
 static jclass jni_GetObjectClass(JNIEnv *env, jobject obj) {
 	return (*env)->GetObjectClass(env, obj);
 }
@@ -134,6 +138,7 @@ import (
 	"unicode/utf16"
 	"unsafe"
 
+	"github.com/xiaoshengduan/gio-fly/app/internal/locale"
 	"github.com/xiaoshengduan/gio-fly/internal/f32color"
 
 	"github.com/xiaoshengduan/gio-fly/f32"
@@ -1293,6 +1298,30 @@ func (w *window) WriteClipboard(s string) {
 	})
 }
 
+// osLocale returns the default locale of the Java virtual machine, which
+// follows the system locale.
+func osLocale() system.Locale {
+	var l system.Locale
+	runInJVM(javaVM(), func(env *C.JNIEnv) {
+		cls := findClass(env, "java/util/Locale")
+		defer C.jni_DeleteLocalRef(env, C.jobject(cls))
+		getDefault := getStaticMethodID(env, cls, "getDefault", "()Ljava/util/Locale;")
+		def, err := callStaticObjectMethod(env, cls, getDefault)
+		if err != nil {
+			return
+		}
+		defer C.jni_DeleteLocalRef(env, def)
+		toTag := getMethodID(env, cls, "toLanguageTag", "()Ljava/lang/String;")
+		tag, err := callObjectMethod(env, def, toTag)
+		if err != nil {
+			return
+		}
+		defer C.jni_DeleteLocalRef(env, tag)
+		l = locale.Parse(goString(env, C.jstring(tag)))
+	})
+	return l
+}
+
 func (w *window) ReadClipboard() {
 	runInJVM(javaVM(), func(env *C.JNIEnv) {
 		c, err := callStaticObjectMethod(env, android.gioCls, android.mreadClipboard,
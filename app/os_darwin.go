@@ -38,6 +38,16 @@ static CFTypeRef newNSString(unichar *chars, NSUInteger length) {
 		return CFBridgingRetain(s);
 	}
 }
+
+static CFTypeRef preferredLanguage(void) {
+	@autoreleasepool {
+		NSString *lang = [[NSLocale preferredLanguages] firstObject];
+		if (lang == nil) {
+			return nil;
+		}
+		return CFBridgingRetain(lang);
+	}
+}
 */
 import "C"
 import (
@@ -48,7 +58,9 @@ import (
 	"unicode/utf16"
 	"unsafe"
 
+	"github.com/xiaoshengduan/gio-fly/app/internal/locale"
 	"github.com/xiaoshengduan/gio-fly/io/pointer"
+	"github.com/xiaoshengduan/gio-fly/io/system"
 )
 
 // displayLink is the state for a display link (CVDisplayLinkRef on macOS,
@@ -111,6 +123,16 @@ func nsstringToString(str C.CFTypeRef) string {
 	return string(utf8)
 }
 
+// osLocale returns the locale of the preferred language of the user.
+func osLocale() system.Locale {
+	lang := C.preferredLanguage()
+	if lang == 0 {
+		return system.Locale{}
+	}
+	defer C.CFRelease(lang)
+	return locale.Parse(nsstringToString(lang))
+}
+
 // stringToNSString converts a Go string to a retained NSString.
 func stringToNSString(str string) C.CFTypeRef {
 	u16 := utf16.Encode([]rune(str))
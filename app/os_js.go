@@ -12,6 +12,7 @@ import (
 	"unicode"
 	"unicode/utf8"
 
+	"github.com/xiaoshengduan/gio-fly/app/internal/locale"
 	"github.com/xiaoshengduan/gio-fly/internal/f32color"
 
 	"github.com/xiaoshengduan/gio-fly/f32"
@@ -776,3 +777,12 @@ func translateKey(k string) (string, bool) {
 }
 
 func (_ ViewEvent) ImplementsEvent() {}
+
+// osLocale returns the preferred language of the browser.
+func osLocale() system.Locale {
+	lang := js.Global().Get("navigator").Get("language")
+	if lang.Type() != js.TypeString {
+		return system.Locale{}
+	}
+	return locale.Parse(lang.String())
+}
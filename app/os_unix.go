@@ -7,9 +7,12 @@ package app
 
 import (
 	"errors"
+	"os"
 	"unsafe"
 
+	"github.com/xiaoshengduan/gio-fly/app/internal/locale"
 	"github.com/xiaoshengduan/gio-fly/io/pointer"
+	"github.com/xiaoshengduan/gio-fly/io/system"
 )
 
 // ViewEvent provides handles to the underlying window objects for the
@@ -98,3 +101,9 @@ var xCursor = [...]string{
 	pointer.CursorNorthEastSouthWestResize: "fd_double_arrow",
 	pointer.CursorNorthWestSouthEastResize: "bd_double_arrow",
 }
+
+// osLocale returns the locale of the user interface language from the
+// environment.
+func osLocale() system.Locale {
+	return locale.FromEnv(os.Getenv)
+}
@@ -17,6 +17,7 @@ import (
 
 	syscall "golang.org/x/sys/windows"
 
+	"github.com/xiaoshengduan/gio-fly/app/internal/locale"
 	"github.com/xiaoshengduan/gio-fly/app/internal/windows"
 	"github.com/xiaoshengduan/gio-fly/unit"
 	gowindows "golang.org/x/sys/windows"
@@ -950,3 +951,8 @@ func configForDPI(dpi int) unit.Metric {
 }
 
 func (_ ViewEvent) ImplementsEvent() {}
+
+// osLocale returns the locale of the user.
+func osLocale() system.Locale {
+	return locale.Parse(windows.GetUserDefaultLocaleName())
+}
@@ -74,6 +74,9 @@ type Window struct {
 	viewport image.Rectangle
 	// metric is the metric from the most recent frame.
 	metric unit.Metric
+	// locale is the system locale, updated whenever the window
+	// starts running or its configuration changes.
+	locale system.Locale
 
 	queue       queue
 	cursor      pointer.Cursor
@@ -835,6 +838,10 @@ func (w *Window) processEvent(d driver, e event.Event) bool {
 				w.ctx.Unlock()
 			}
 		}
+		if e2.Stage >= system.StageRunning && w.stage < system.StageRunning {
+			// The locale may have changed while the window was paused.
+			w.locale = osLocale()
+		}
 		w.stage = e2.Stage
 		w.updateAnimation(d)
 		w.out <- e
@@ -855,6 +862,7 @@ func (w *Window) processEvent(d driver, e event.Event) bool {
 		w.hasNextFrame = false
 		e2.Frame = w.update
 		e2.Queue = &w.queue
+		e2.Locale = w.locale
 
 		// Prepare the decorations and update the frame insets.
 		wrapper := &w.decorations.Ops
@@ -907,6 +915,8 @@ func (w *Window) processEvent(d driver, e event.Event) bool {
 		w.out <- e2
 		w.waitAck(d)
 	case ConfigEvent:
+		// The locale may have changed with the system configuration.
+		w.locale = osLocale()
 		w.decorations.Config = e2.Config
 		e2.Config = w.effectiveConfig()
 		w.out <- e2
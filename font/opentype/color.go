@@ -0,0 +1,270 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package opentype
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+
+	"github.com/benoitkugler/textlayout/fonts"
+	"github.com/benoitkugler/textlayout/fonts/truetype"
+	"golang.org/x/image/math/fixed"
+
+	"github.com/xiaoshengduan/gio-fly/f32"
+	"github.com/xiaoshengduan/gio-fly/op"
+	"github.com/xiaoshengduan/gio-fly/op/clip"
+	"github.com/xiaoshengduan/gio-fly/op/paint"
+	"github.com/xiaoshengduan/gio-fly/text"
+)
+
+// colorGlyphs are the layered color glyphs of a font, from its COLR and
+// CPAL tables.
+type colorGlyphs map[fonts.GID][]colorLayer
+
+// colorLayer is a layer of a color glyph.
+type colorLayer struct {
+	// gid is the glyph of the layer outline.
+	gid fonts.GID
+	// fg reports whether the layer is painted in the text color.
+	fg bool
+	// color of the layer, if not fg.
+	color color.NRGBA
+}
+
+// bitmapKey identifies a bitmap glyph at a size.
+type bitmapKey struct {
+	gid  fonts.GID
+	ppem uint16
+}
+
+// bitmap is a decoded bitmap glyph.
+type bitmap struct {
+	img  paint.ImageOp
+	size image.Point
+}
+
+var (
+	tagCOLR = truetype.MustNewTag("COLR")
+	tagCPAL = truetype.MustNewTag("CPAL")
+)
+
+// loadColorGlyphs reads the color glyphs of a font, if any.
+func loadColorGlyphs(pr *truetype.FontParser) colorGlyphs {
+	colr, err := pr.GetRawTable(tagCOLR)
+	if err != nil {
+		return nil
+	}
+	cpal, err := pr.GetRawTable(tagCPAL)
+	if err != nil {
+		return nil
+	}
+	palette, err := parseCPAL(cpal)
+	if err != nil {
+		return nil
+	}
+	glyphs, err := parseCOLR(colr, palette)
+	if err != nil {
+		return nil
+	}
+	return glyphs
+}
+
+// parseCPAL returns the first palette of a CPAL table.
+func parseCPAL(data []byte) ([]color.NRGBA, error) {
+	if len(data) < 12 {
+		return nil, errors.New("CPAL table too short")
+	}
+	bo := binary.BigEndian
+	entries := int(bo.Uint16(data[2:]))
+	numPalettes := int(bo.Uint16(data[4:]))
+	records := int(bo.Uint32(data[8:]))
+	if numPalettes == 0 || len(data) < 14 {
+		return nil, errors.New("CPAL table has no palettes")
+	}
+	first := int(bo.Uint16(data[12:]))
+	start := records + first*4
+	if start+entries*4 > len(data) {
+		return nil, errors.New("CPAL color records out of bounds")
+	}
+	palette := make([]color.NRGBA, entries)
+	for i := range palette {
+		// Colors are stored as BGRA.
+		c := data[start+i*4:]
+		palette[i] = color.NRGBA{R: c[2], G: c[1], B: c[0], A: c[3]}
+	}
+	return palette, nil
+}
+
+// parseCOLR returns the layered glyphs of a COLR table. Only the layers
+// of version 0 are supported; they are also present in version 1 tables.
+func parseCOLR(data []byte, palette []color.NRGBA) (colorGlyphs, error) {
+	if len(data) < 14 {
+		return nil, errors.New("COLR table too short")
+	}
+	bo := binary.BigEndian
+	numBase := int(bo.Uint16(data[2:]))
+	baseOff := int(bo.Uint32(data[4:]))
+	layerOff := int(bo.Uint32(data[8:]))
+	numLayers := int(bo.Uint16(data[12:]))
+	if baseOff+numBase*6 > len(data) || layerOff+numLayers*4 > len(data) {
+		return nil, errors.New("COLR records out of bounds")
+	}
+	glyphs := make(colorGlyphs, numBase)
+	for i := 0; i < numBase; i++ {
+		rec := data[baseOff+i*6:]
+		gid := fonts.GID(bo.Uint16(rec))
+		first, n := int(bo.Uint16(rec[2:])), int(bo.Uint16(rec[4:]))
+		if first+n > numLayers {
+			return nil, errors.New("COLR layers out of bounds")
+		}
+		layers := make([]colorLayer, n)
+		for j := range layers {
+			l := data[layerOff+(first+j)*4:]
+			layers[j].gid = fonts.GID(bo.Uint16(l))
+			switch idx := int(bo.Uint16(l[2:])); {
+			case idx == 0xffff:
+				layers[j].fg = true
+			case idx < len(palette):
+				layers[j].color = palette[idx]
+			default:
+				return nil, errors.New("COLR palette index out of bounds")
+			}
+		}
+		glyphs[gid] = layers
+	}
+	return glyphs, nil
+}
+
+// ShapeColor implements the text.ColorFace interface. Glyphs with COLR
+// layers are painted as layers of outlines in the order of the table, and
+// glyphs with PNG or JPEG bitmaps, such as those of CBDT and sbix tables,
+// as images scaled to their advance.
+func (f *Font) ShapeColor(ppem fixed.Int26_6, str text.Layout, fg color.NRGBA) op.CallOp {
+	ops := new(op.Ops)
+	macro := op.Record(ops)
+	painted := false
+	ppem16 := uint16(ppem.Round())
+	var x fixed.Int26_6
+	for _, g := range str.Glyphs {
+		pos := f32.Point{
+			X: float32(x)/64 - float32(g.XOffset)/64,
+			Y: -float32(g.YOffset) / 64,
+		}
+		x += g.XAdvance
+		face := f
+		if gf, ok := g.Face.(*Font); ok {
+			face = gf
+		}
+		fnt, err := face.face()
+		if err != nil {
+			continue
+		}
+		if layers, ok := face.colors[g.ID]; ok {
+			scale := float32(ppem.Round()) / float32(fnt.Upem())
+			for _, l := range layers {
+				outline, ok := fnt.GlyphData(l.gid, ppem16, ppem16).(fonts.GlyphOutline)
+				if !ok {
+					continue
+				}
+				c := l.color
+				if l.fg {
+					c = fg
+				}
+				cl := clip.Outline{Path: outlinePath(ops, outline, scale, pos)}.Op().Push(ops)
+				paint.ColorOp{Color: c}.Add(ops)
+				paint.PaintOp{}.Add(ops)
+				cl.Pop()
+				painted = true
+			}
+			continue
+		}
+		bm, ok := face.bitmap(g.ID, ppem16)
+		if !ok || bm.size.X == 0 {
+			continue
+		}
+		// Scale the bitmap to the advance, and center it vertically
+		// on the line.
+		scale := float32(g.XAdvance) / 64 / float32(bm.size.X)
+		m := face.Metrics(ppem)
+		center := float32(m.Descent-m.Ascent) / 64 / 2
+		pos.Y += center - float32(bm.size.Y)*scale/2
+		tr := op.Affine(f32.Affine2D{}.Scale(f32.Point{}, f32.Pt(scale, scale)).Offset(pos)).Push(ops)
+		bm.img.Add(ops)
+		paint.PaintOp{}.Add(ops)
+		tr.Pop()
+		painted = true
+	}
+	call := macro.Stop()
+	if !painted {
+		return op.CallOp{}
+	}
+	return call
+}
+
+// isColorGlyph reports whether the glyph is painted by ShapeColor.
+func (f *Font) isColorGlyph(gid fonts.GID) bool {
+	_, ok := f.colors[gid]
+	return ok
+}
+
+// bitmap returns the decoded color bitmap of a glyph, if any.
+func (f *Font) bitmap(gid fonts.GID, ppem uint16) (bitmap, bool) {
+	k := bitmapKey{gid: gid, ppem: ppem}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if bm, ok := f.bitmaps[k]; ok {
+		return bm, bm.size != (image.Point{})
+	}
+	var bm bitmap
+	if data, ok := f.font.GlyphData(gid, ppem, ppem).(fonts.GlyphBitmap); ok {
+		var img image.Image
+		var err error
+		switch data.Format {
+		case fonts.PNG:
+			img, err = png.Decode(bytes.NewReader(data.Data))
+		case fonts.JPG:
+			img, err = jpeg.Decode(bytes.NewReader(data.Data))
+		default:
+			err = errors.New("unsupported bitmap format")
+		}
+		if err == nil {
+			bm = bitmap{img: paint.NewImageOp(img), size: img.Bounds().Size()}
+		}
+	}
+	if f.bitmaps == nil {
+		f.bitmaps = make(map[bitmapKey]bitmap)
+	}
+	// Cache failures as well.
+	f.bitmaps[k] = bm
+	return bm, bm.size != (image.Point{})
+}
+
+// outlinePath returns the path of a glyph outline scaled by scale and
+// positioned at pos.
+func outlinePath(ops *op.Ops, outline fonts.GlyphOutline, scale float32, pos f32.Point) clip.PathSpec {
+	var p clip.Path
+	p.Begin(ops)
+	p.MoveTo(pos)
+	for _, seg := range outline.Segments {
+		var args [3]f32.Point
+		for i := range args {
+			args[i] = pos.Add(f32.Pt(seg.Args[i].X*scale, -seg.Args[i].Y*scale))
+		}
+		switch seg.Op {
+		case fonts.SegmentOpMoveTo:
+			p.MoveTo(args[0])
+		case fonts.SegmentOpLineTo:
+			p.LineTo(args[0])
+		case fonts.SegmentOpQuadTo:
+			p.QuadTo(args[0], args[1])
+		case fonts.SegmentOpCubeTo:
+			p.CubeTo(args[0], args[1], args[2])
+		}
+	}
+	return p.End()
+}
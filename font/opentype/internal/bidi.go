@@ -0,0 +1,669 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package internal
+
+import (
+	"sort"
+
+	"github.com/go-text/typesetting/di"
+	"golang.org/x/text/unicode/bidi"
+)
+
+// maxDepth is the maximum explicit embedding level of the Unicode
+// Bidirectional Algorithm.
+const maxDepth = 125
+
+// maxBrackets is the size of the bracket stack of rule BD16.
+const maxBrackets = 63
+
+// bidiParagraph is a paragraph with the embedding levels of its runes
+// resolved by the Unicode Bidirectional Algorithm (UAX #9).
+type bidiParagraph struct {
+	// base is the paragraph embedding level.
+	base uint8
+	// levels are the resolved levels of the runes.
+	levels []uint8
+	// classes are the original bidi classes of the runes.
+	classes []bidi.Class
+}
+
+// newBidiParagraph resolves the levels of text. The paragraph level is
+// taken from the first strong character, or from def if there is none.
+func newBidiParagraph(text []rune, def di.Direction) *bidiParagraph {
+	p := &bidiParagraph{
+		levels:  make([]uint8, len(text)),
+		classes: make([]bidi.Class, len(text)),
+	}
+	for i, r := range text {
+		props, _ := bidi.LookupRune(r)
+		p.classes[i] = props.Class()
+	}
+	p.base = firstStrong(p.classes, 0, len(p.classes))
+	if p.base > 1 {
+		p.base = 0
+		if def == di.DirectionRTL {
+			p.base = 1
+		}
+	}
+	p.resolve(text)
+	return p
+}
+
+// resolve computes the levels of text at the paragraph level p.base.
+func (p *bidiParagraph) resolve(text []rune) {
+	classes := make([]bidi.Class, len(text))
+	copy(classes, p.classes)
+	p.explicit(classes)
+	seqs := p.isolatingRuns(classes)
+	// Determine the boundaries from the explicit levels, before the
+	// sequences resolve their implicit levels.
+	type bounds struct{ sos, eos bidi.Class }
+	seqBounds := make([]bounds, len(seqs))
+	for i, seq := range seqs {
+		seqBounds[i].sos, seqBounds[i].eos = p.boundaries(classes, seq)
+	}
+	for i, seq := range seqs {
+		level := p.levels[seq[0]]
+		sos, eos := seqBounds[i].sos, seqBounds[i].eos
+		seqClasses := make([]bidi.Class, len(seq))
+		seqLevels := make([]uint8, len(seq))
+		for j, idx := range seq {
+			seqClasses[j] = classes[idx]
+			seqLevels[j] = level
+		}
+		resolveWeak(seqClasses, sos)
+		p.resolveBrackets(text, seq, seqClasses, level, sos)
+		resolveNeutral(seqClasses, level, sos, eos)
+		resolveImplicit(seqClasses, seqLevels)
+		for j, idx := range seq {
+			p.levels[idx] = seqLevels[j]
+		}
+	}
+	// Characters removed by rule X9 take the level of the preceding
+	// character.
+	prev := p.base
+	for i, c := range classes {
+		if c == bidi.BN {
+			p.levels[i] = prev
+		}
+		prev = p.levels[i]
+	}
+}
+
+// isolatingRuns splits the characters not removed by rule X9 into level
+// runs, and links them into isolating run sequences, rules X9 and X10.
+func (p *bidiParagraph) isolatingRuns(classes []bidi.Class) [][]int {
+	var runs [][]int
+	// runAt maps the index of the first character of a run to the run.
+	runAt := make(map[int]int)
+	var run []int
+	for i, c := range classes {
+		if c == bidi.BN {
+			continue
+		}
+		if len(run) > 0 && p.levels[run[0]] != p.levels[i] {
+			runAt[run[0]] = len(runs)
+			runs = append(runs, run)
+			run = nil
+		}
+		run = append(run, i)
+	}
+	if len(run) > 0 {
+		runAt[run[0]] = len(runs)
+		runs = append(runs, run)
+	}
+	var seqs [][]int
+	for _, run := range runs {
+		if first := run[0]; p.classes[first] == bidi.PDI && matchingInitiator(p.classes, first) != -1 {
+			// The run continues the sequence of its initiator.
+			continue
+		}
+		seq := append([]int(nil), run...)
+		for {
+			last := run[len(run)-1]
+			if !isIsolateInitiator(p.classes[last]) {
+				break
+			}
+			next, ok := runAt[matchingPDI(p.classes, last)]
+			if !ok {
+				break
+			}
+			run = runs[next]
+			seq = append(seq, run...)
+		}
+		seqs = append(seqs, seq)
+	}
+	return seqs
+}
+
+// boundaries returns the sos and eos classes of an isolating run
+// sequence.
+func (p *bidiParagraph) boundaries(classes []bidi.Class, seq []int) (sos, eos bidi.Class) {
+	level := p.levels[seq[0]]
+	prev, next := p.base, p.base
+	for i := seq[0] - 1; i >= 0; i-- {
+		if classes[i] != bidi.BN {
+			prev = p.levels[i]
+			break
+		}
+	}
+	if last := seq[len(seq)-1]; !isIsolateInitiator(classes[last]) {
+		for i := last + 1; i < len(classes); i++ {
+			if classes[i] != bidi.BN {
+				next = p.levels[i]
+				break
+			}
+		}
+	}
+	return levelClass(maxLevel(prev, level)), levelClass(maxLevel(next, level))
+}
+
+// firstStrong returns the level of the first strong character of
+// classes[start:end] outside isolates, rules P2 and P3. It returns 2 if
+// there is none.
+func firstStrong(classes []bidi.Class, start, end int) uint8 {
+	isolates := 0
+	for _, c := range classes[start:end] {
+		switch c {
+		case bidi.L:
+			if isolates == 0 {
+				return 0
+			}
+		case bidi.R, bidi.AL:
+			if isolates == 0 {
+				return 1
+			}
+		case bidi.LRI, bidi.RLI, bidi.FSI:
+			isolates++
+		case bidi.PDI:
+			if isolates > 0 {
+				isolates--
+			}
+		case bidi.B:
+			return 2
+		}
+	}
+	return 2
+}
+
+// explicit applies the explicit embeddings, overrides and isolates of
+// classes to the levels of p, rules X1-X8. The classes of characters
+// removed by rule X9 become BN, and the classes of overridden characters
+// become the class of the override.
+func (p *bidiParagraph) explicit(classes []bidi.Class) {
+	type status struct {
+		level    uint8
+		override bidi.Class
+		isolate  bool
+	}
+	stack := []status{{level: p.base, override: bidi.ON}}
+	overflowIsolates, overflowEmbeddings, validIsolates := 0, 0, 0
+	push := func(rtl bool, override bidi.Class, isolate bool) bool {
+		level := stack[len(stack)-1].level
+		if rtl {
+			level = (level + 1) | 1
+		} else {
+			level = (level + 2) &^ 1
+		}
+		if level > maxDepth || overflowIsolates > 0 || overflowEmbeddings > 0 {
+			return false
+		}
+		stack = append(stack, status{level: level, override: override, isolate: isolate})
+		return true
+	}
+	for i, c := range classes {
+		top := stack[len(stack)-1]
+		p.levels[i] = top.level
+		switch c {
+		case bidi.RLE, bidi.LRE, bidi.RLO, bidi.LRO:
+			override := bidi.ON
+			switch c {
+			case bidi.RLO:
+				override = bidi.R
+			case bidi.LRO:
+				override = bidi.L
+			}
+			if !push(c == bidi.RLE || c == bidi.RLO, override, false) && overflowIsolates == 0 {
+				overflowEmbeddings++
+			}
+			classes[i] = bidi.BN
+		case bidi.RLI, bidi.LRI, bidi.FSI:
+			rtl := c == bidi.RLI
+			if c == bidi.FSI {
+				rtl = firstStrong(classes, i+1, matchingPDI(classes, i)) == 1
+			}
+			if top.override != bidi.ON {
+				classes[i] = top.override
+			}
+			if push(rtl, bidi.ON, true) {
+				validIsolates++
+			} else {
+				overflowIsolates++
+			}
+		case bidi.PDI:
+			switch {
+			case overflowIsolates > 0:
+				overflowIsolates--
+			case validIsolates > 0:
+				overflowEmbeddings = 0
+				for !stack[len(stack)-1].isolate {
+					stack = stack[:len(stack)-1]
+				}
+				stack = stack[:len(stack)-1]
+				validIsolates--
+			}
+			top = stack[len(stack)-1]
+			p.levels[i] = top.level
+			if top.override != bidi.ON {
+				classes[i] = top.override
+			}
+		case bidi.PDF:
+			switch {
+			case overflowIsolates > 0:
+			case overflowEmbeddings > 0:
+				overflowEmbeddings--
+			case !top.isolate && len(stack) > 1:
+				stack = stack[:len(stack)-1]
+			}
+			classes[i] = bidi.BN
+		case bidi.B:
+			p.levels[i] = p.base
+		case bidi.BN:
+		default:
+			if top.override != bidi.ON {
+				classes[i] = top.override
+			}
+		}
+	}
+}
+
+// matchingInitiator returns the index of the isolate initiator matching
+// the PDI at i, or -1.
+func matchingInitiator(classes []bidi.Class, i int) int {
+	depth := 0
+	for j := i - 1; j >= 0; j-- {
+		switch classes[j] {
+		case bidi.PDI:
+			depth++
+		case bidi.LRI, bidi.RLI, bidi.FSI:
+			if depth == 0 {
+				return j
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// matchingPDI returns the index of the PDI matching the isolate initiator
+// at i, or len(classes).
+func matchingPDI(classes []bidi.Class, i int) int {
+	depth := 0
+	for j := i + 1; j < len(classes); j++ {
+		switch classes[j] {
+		case bidi.LRI, bidi.RLI, bidi.FSI:
+			depth++
+		case bidi.PDI:
+			if depth == 0 {
+				return j
+			}
+			depth--
+		}
+	}
+	return len(classes)
+}
+
+// resolveWeak resolves the weak types of a level run, rules W1-W7.
+func resolveWeak(classes []bidi.Class, sos bidi.Class) {
+	// W1: non-spacing marks take the class of the previous character, or
+	// are neutral after isolate initiators and PDI.
+	prev := sos
+	for i, c := range classes {
+		if c == bidi.NSM {
+			classes[i] = prev
+			if isIsolateInitiator(prev) || prev == bidi.PDI {
+				classes[i] = bidi.ON
+			}
+		}
+		prev = classes[i]
+	}
+	// W2 and W3: European numbers after Arabic letters are Arabic numbers,
+	// and Arabic letters are right-to-left.
+	strong := sos
+	for i, c := range classes {
+		switch c {
+		case bidi.L, bidi.R:
+			strong = c
+		case bidi.AL:
+			strong = c
+			classes[i] = bidi.R
+		case bidi.EN:
+			if strong == bidi.AL {
+				classes[i] = bidi.AN
+			}
+		}
+	}
+	// W4: single separators between numbers of the same kind join them.
+	for i := 1; i < len(classes)-1; i++ {
+		before, after := classes[i-1], classes[i+1]
+		switch classes[i] {
+		case bidi.ES:
+			if before == bidi.EN && after == bidi.EN {
+				classes[i] = bidi.EN
+			}
+		case bidi.CS:
+			if before == after && (before == bidi.EN || before == bidi.AN) {
+				classes[i] = before
+			}
+		}
+	}
+	// W5: terminators next to European numbers are European numbers.
+	for i := 0; i < len(classes); {
+		if classes[i] != bidi.ET {
+			i++
+			continue
+		}
+		end := i
+		for end < len(classes) && (classes[end] == bidi.ET || classes[end] == bidi.BN) {
+			end++
+		}
+		if (i > 0 && classes[i-1] == bidi.EN) || (end < len(classes) && classes[end] == bidi.EN) {
+			for j := i; j < end; j++ {
+				classes[j] = bidi.EN
+			}
+		}
+		i = end
+	}
+	// W6: remaining separators and terminators are neutral.
+	for i, c := range classes {
+		switch c {
+		case bidi.ES, bidi.ET, bidi.CS:
+			classes[i] = bidi.ON
+		}
+	}
+	// W7: European numbers after left-to-right text are left-to-right.
+	strong = sos
+	for i, c := range classes {
+		switch c {
+		case bidi.L, bidi.R:
+			strong = c
+		case bidi.EN:
+			if strong == bidi.L {
+				classes[i] = bidi.L
+			}
+		}
+	}
+}
+
+// resolveBrackets resolves the classes of the paired brackets of the
+// isolating run sequence seq, rule N0. classes are the classes of the
+// sequence after the weak rules.
+func (p *bidiParagraph) resolveBrackets(text []rune, seq []int, classes []bidi.Class, level uint8, sos bidi.Class) {
+	type opener struct {
+		pos     int
+		closing rune
+	}
+	type pair struct {
+		open, close int
+	}
+	// Identify the bracket pairs, rule BD16.
+	var stack []opener
+	var pairs []pair
+loop:
+	for i, idx := range seq {
+		if classes[i] != bidi.ON {
+			continue
+		}
+		r := text[idx]
+		props, _ := bidi.LookupRune(r)
+		if !props.IsBracket() {
+			continue
+		}
+		if props.IsOpeningBracket() {
+			if len(stack) == maxBrackets {
+				break loop
+			}
+			stack = append(stack, opener{pos: i, closing: canonicalBracket(pairedBracket(r))})
+			continue
+		}
+		r = canonicalBracket(r)
+		for j := len(stack) - 1; j >= 0; j-- {
+			if stack[j].closing == r {
+				pairs = append(pairs, pair{open: stack[j].pos, close: i})
+				stack = stack[:j]
+				break
+			}
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].open < pairs[j].open
+	})
+	dir := levelClass(level)
+	for _, b := range pairs {
+		// Find the strong direction inside the brackets, preferring the
+		// embedding direction.
+		inside := bidi.ON
+		for _, c := range classes[b.open+1 : b.close] {
+			if c := bracketStrong(c); c != bidi.ON {
+				inside = c
+				if c == dir {
+					break
+				}
+			}
+		}
+		if inside == bidi.ON {
+			continue
+		}
+		if inside != dir {
+			// Use the opposite direction only if the context before the
+			// opening bracket has it too.
+			before := sos
+			for i := b.open - 1; i >= 0; i-- {
+				if c := bracketStrong(classes[i]); c != bidi.ON {
+					before = c
+					break
+				}
+			}
+			if before != inside {
+				inside = dir
+			}
+		}
+		for _, i := range []int{b.open, b.close} {
+			classes[i] = inside
+			// Non-spacing marks following the bracket take its class.
+			for i++; i < len(seq) && p.classes[seq[i]] == bidi.NSM; i++ {
+				classes[i] = inside
+			}
+		}
+	}
+}
+
+// bracketStrong returns the strong direction of a class for rule N0,
+// where numbers are right-to-left. It returns ON for neutral classes.
+func bracketStrong(c bidi.Class) bidi.Class {
+	switch c {
+	case bidi.L:
+		return bidi.L
+	case bidi.R, bidi.AL, bidi.EN, bidi.AN:
+		return bidi.R
+	}
+	return bidi.ON
+}
+
+// pairedBracket returns the Bidi_Paired_Bracket of the opening bracket r.
+func pairedBracket(r rune) rune {
+	switch r {
+	case '[', '{', '\uff3b', '\uff5b':
+		return r + 2
+	case '\u298d':
+		return '\u2990'
+	case '\u298f':
+		return '\u298e'
+	}
+	// The other brackets are followed by their pair.
+	return r + 1
+}
+
+// canonicalBracket maps the angle brackets U+2329 and U+232A to their
+// canonical equivalents.
+func canonicalBracket(r rune) rune {
+	switch r {
+	case '\u2329':
+		return '\u3008'
+	case '\u232a':
+		return '\u3009'
+	}
+	return r
+}
+
+// resolveNeutral resolves the neutral types of a level run, rules N1 and
+// N2.
+func resolveNeutral(classes []bidi.Class, level uint8, sos, eos bidi.Class) {
+	for i := 0; i < len(classes); {
+		if !isNeutral(classes[i]) {
+			i++
+			continue
+		}
+		end := i
+		for end < len(classes) && isNeutral(classes[end]) {
+			end++
+		}
+		before, after := sos, eos
+		if i > 0 {
+			before = strongClass(classes[i-1])
+		}
+		if end < len(classes) {
+			after = strongClass(classes[end])
+		}
+		c := levelClass(level)
+		if before == after {
+			c = before
+		}
+		for j := i; j < end; j++ {
+			classes[j] = c
+		}
+		i = end
+	}
+}
+
+// resolveImplicit raises levels according to the resolved classes, rules
+// I1 and I2.
+func resolveImplicit(classes []bidi.Class, levels []uint8) {
+	for i, c := range classes {
+		switch {
+		case levels[i]%2 == 0 && c == bidi.R:
+			levels[i]++
+		case levels[i]%2 == 0 && (c == bidi.AN || c == bidi.EN):
+			levels[i] += 2
+		case levels[i]%2 == 1 && (c == bidi.L || c == bidi.AN || c == bidi.EN):
+			levels[i]++
+		}
+	}
+}
+
+func isNeutral(c bidi.Class) bool {
+	switch c {
+	case bidi.B, bidi.S, bidi.WS, bidi.ON, bidi.BN, bidi.LRI, bidi.RLI, bidi.FSI, bidi.PDI:
+		return true
+	}
+	return false
+}
+
+func isIsolateInitiator(c bidi.Class) bool {
+	switch c {
+	case bidi.LRI, bidi.RLI, bidi.FSI:
+		return true
+	}
+	return false
+}
+
+// strongClass returns the direction of a resolved class for rule N1,
+// where numbers are right-to-left.
+func strongClass(c bidi.Class) bidi.Class {
+	if c == bidi.L {
+		return bidi.L
+	}
+	return bidi.R
+}
+
+// levelClass returns the direction of an embedding level.
+func levelClass(level uint8) bidi.Class {
+	if level%2 == 1 {
+		return bidi.R
+	}
+	return bidi.L
+}
+
+func maxLevel(a, b uint8) uint8 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// mixed reports whether the runes of p are not all at the paragraph level.
+func (p *bidiParagraph) mixed() bool {
+	for _, l := range p.levels {
+		if l != p.base {
+			return true
+		}
+	}
+	return false
+}
+
+// lineLevels returns the levels of the runes in [start, end) on a line of
+// their own, with trailing white space and separators reset to the
+// paragraph level, rule L1.
+func (p *bidiParagraph) lineLevels(start, end int) []uint8 {
+	levels := make([]uint8, end-start)
+	copy(levels, p.levels[start:end])
+	trailing := true
+	for i := end - 1; i >= start; i-- {
+		switch p.classes[i] {
+		case bidi.S, bidi.B:
+			levels[i-start] = p.base
+			trailing = true
+		case bidi.WS, bidi.LRI, bidi.RLI, bidi.FSI, bidi.PDI, bidi.BN,
+			bidi.LRE, bidi.RLE, bidi.LRO, bidi.RLO, bidi.PDF:
+			if trailing {
+				levels[i-start] = p.base
+			}
+		default:
+			trailing = false
+		}
+	}
+	return levels
+}
+
+// visualOrder returns the indices of items with the given levels in
+// visual order, left to right, rule L2.
+func visualOrder(levels []uint8) []int {
+	order := make([]int, len(levels))
+	var highest, lowestOdd uint8 = 0, maxDepth + 2
+	for i, l := range levels {
+		order[i] = i
+		if l > highest {
+			highest = l
+		}
+		if l%2 == 1 && l < lowestOdd {
+			lowestOdd = l
+		}
+	}
+	for level := highest; level >= lowestOdd && level > 0; level-- {
+		for i := 0; i < len(order); {
+			if levels[order[i]] < level {
+				i++
+				continue
+			}
+			j := i
+			for j < len(order) && levels[order[j]] >= level {
+				j++
+			}
+			for a, b := i, j-1; a < b; a, b = a+1, b-1 {
+				order[a], order[b] = order[b], order[a]
+			}
+			i = j
+		}
+	}
+	return order
+}
@@ -0,0 +1,206 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-text/typesetting/di"
+	"github.com/go-text/typesetting/shaping"
+	"golang.org/x/image/math/fixed"
+
+	"github.com/xiaoshengduan/gio-fly/io/system"
+	"github.com/xiaoshengduan/gio-fly/text"
+)
+
+func TestBidiLevels(t *testing.T) {
+	tests := []struct {
+		text   string
+		def    di.Direction
+		base   uint8
+		levels []uint8
+	}{
+		{"abc", di.DirectionRTL, 0, []uint8{0, 0, 0}},
+		{"123", di.DirectionRTL, 1, []uint8{2, 2, 2}},
+		{"ab אב cd", di.DirectionLTR, 0, []uint8{0, 0, 0, 1, 1, 0, 0, 0}},
+		{"אב 12", di.DirectionLTR, 1, []uint8{1, 1, 1, 2, 2}},
+		{"אב ab.", di.DirectionLTR, 1, []uint8{1, 1, 1, 2, 2, 1}},
+		{"a ‫b‬", di.DirectionLTR, 0, []uint8{0, 0, 0, 2, 2}},
+		{"⁦ab⁩ אב", di.DirectionLTR, 1, []uint8{1, 2, 2, 1, 1, 1, 1}},
+	}
+	for _, test := range tests {
+		p := newBidiParagraph([]rune(test.text), test.def)
+		if p.base != test.base {
+			t.Errorf("%q: paragraph level %d, expected %d", test.text, p.base, test.base)
+		}
+		if !reflect.DeepEqual(p.levels, test.levels) {
+			t.Errorf("%q: levels %v, expected %v", test.text, p.levels, test.levels)
+		}
+	}
+	// Trailing white space is at the paragraph level.
+	p := newBidiParagraph([]rune("ab אב "), di.DirectionLTR)
+	if got, exp := p.lineLevels(3, 6), []uint8{1, 1, 0}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("line levels %v, expected %v", got, exp)
+	}
+}
+
+// TestBidiCharacterTest runs the conformance tests of the Unicode
+// Bidirectional Algorithm from BidiCharacterTest.txt.
+func TestBidiCharacterTest(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "BidiCharacterTest.txt.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := bufio.NewScanner(r)
+	failures := 0
+	for line := 1; s.Scan(); line++ {
+		fields := strings.Split(s.Text(), ";")
+		if len(fields) != 5 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		var text []rune
+		for _, f := range strings.Fields(fields[0]) {
+			r, err := strconv.ParseUint(f, 16, 32)
+			if err != nil {
+				t.Fatalf("line %d: %v", line, err)
+			}
+			text = append(text, rune(r))
+		}
+		p := newBidiParagraph(text, di.DirectionLTR)
+		if dir := fields[1]; dir != "2" {
+			// The paragraph level is explicit.
+			p.base = 0
+			if dir == "1" {
+				p.base = 1
+			}
+			p.resolve(text)
+		}
+		levels := p.lineLevels(0, len(text))
+		var want, got []string
+		var visible []int
+		var visibleLevels []uint8
+		for i, l := range strings.Fields(fields[3]) {
+			want = append(want, l)
+			if l == "x" {
+				got = append(got, "x")
+				continue
+			}
+			got = append(got, strconv.Itoa(int(levels[i])))
+			visible = append(visible, i)
+			visibleLevels = append(visibleLevels, levels[i])
+		}
+		var order []string
+		for _, i := range visualOrder(visibleLevels) {
+			order = append(order, strconv.Itoa(visible[i]))
+		}
+		switch {
+		case strconv.Itoa(int(p.base)) != fields[2]:
+			t.Errorf("line %d: paragraph level %d, expected %s", line, p.base, fields[2])
+		case !reflect.DeepEqual(got, want):
+			t.Errorf("line %d: levels %v, expected %v", line, got, want)
+		case strings.Join(order, " ") != fields[4]:
+			t.Errorf("line %d: order %v, expected %s", line, order, fields[4])
+		default:
+			continue
+		}
+		if failures++; failures == 10 {
+			t.Fatal("too many failures")
+		}
+	}
+	if err := s.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVisualOrder(t *testing.T) {
+	tests := []struct {
+		levels []uint8
+		order  []int
+	}{
+		{[]uint8{0, 0, 0}, []int{0, 1, 2}},
+		{[]uint8{1, 1, 1}, []int{2, 1, 0}},
+		{[]uint8{0, 0, 1, 1, 1, 0}, []int{0, 1, 4, 3, 2, 5}},
+		{[]uint8{1, 1, 2, 2, 1}, []int{4, 2, 3, 1, 0}},
+	}
+	for _, test := range tests {
+		if got := visualOrder(test.levels); !reflect.DeepEqual(got, test.order) {
+			t.Errorf("visualOrder(%v) = %v, expected %v", test.levels, got, test.order)
+		}
+	}
+}
+
+func TestEngineBidi(t *testing.T) {
+	english := system.Locale{
+		Language:  "EN",
+		Direction: system.LTR,
+	}
+	// Shape every rune as a square glyph 10 units wide, in visual order.
+	shaper := func(in shaping.Input) (shaping.Output, error) {
+		o := shaping.Output{Direction: in.Direction}
+		for i := in.RunStart; i < in.RunEnd; i++ {
+			o.Glyphs = append(o.Glyphs, simpleGlyph(i))
+		}
+		if in.Direction == di.DirectionRTL {
+			for i, j := 0, len(o.Glyphs)-1; i < j; i, j = i+1, j-1 {
+				o.Glyphs[i], o.Glyphs[j] = o.Glyphs[j], o.Glyphs[i]
+			}
+		}
+		o.RecalculateAll()
+		return o, nil
+	}
+	lines := Document(shaper, nil, 10, 1000, english, text.Parameters{}, bytes.NewBufferString("ab אבג cd\n"))
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, expected 2", len(lines))
+	}
+	l := lines[0].Layout
+	if l.Direction != system.LTR {
+		t.Errorf("line direction %v, expected LTR", l.Direction)
+	}
+	var visual []int
+	for _, g := range l.Glyphs {
+		visual = append(visual, g.ClusterIndex)
+	}
+	if exp := []int{0, 1, 2, 5, 4, 3, 6, 7, 8}; !reflect.DeepEqual(visual, exp) {
+		t.Errorf("glyph clusters %v, expected %v", visual, exp)
+	}
+	if !l.Mixed() {
+		t.Error("line is not mixed")
+	}
+	if len(l.Clusters) != 10 {
+		t.Fatalf("got %d clusters, expected 10", len(l.Clusters))
+	}
+	for i, c := range l.Clusters {
+		if c.Runes.Offset != i || c.Runes.Count != 1 {
+			t.Errorf("cluster %d covers runes %+v", i, c.Runes)
+		}
+	}
+	// The right edge of the first Hebrew letter.
+	if x, exp := l.Clusters[3].X, fixed.I(60); x != exp {
+		t.Errorf("cluster 3 starts at %v, expected %v", x, exp)
+	}
+	if a := l.Clusters[3].Advance; a != -fixed.I(10) {
+		t.Errorf("cluster 3 advance %v, expected -10", a)
+	}
+	if exp := []int{0, 1, 2, 5, 4, 3, 6, 7, 8, 9}; !reflect.DeepEqual(l.VisualOrder(), exp) {
+		t.Errorf("visual order %v, expected %v", l.VisualOrder(), exp)
+	}
+
+	// A paragraph starting with a right-to-left letter is right-to-left.
+	lines = Document(shaper, nil, 10, 1000, english, text.Parameters{}, bytes.NewBufferString("אב"))
+	if l := lines[0].Layout; l.Direction != system.RTL || l.Mixed() {
+		t.Errorf("got direction %v, mixed %v, expected a right-to-left line", l.Direction, l.Mixed())
+	}
+}
@@ -0,0 +1,115 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package internal
+
+import (
+	"fmt"
+
+	"github.com/benoitkugler/textlayout/harfbuzz"
+	"github.com/go-text/typesetting/di"
+	"github.com/go-text/typesetting/shaping"
+	"golang.org/x/image/math/fixed"
+)
+
+// scaleShift is the power of 2 scaling the coordinates of the shaper, as
+// in shaping.Shape.
+const scaleShift = 6
+
+// FeatureShaper returns a Shaper that shapes like shaping.Shape, with the
+// given OpenType features applied to every run.
+//
+// The shaper is a copy of Shape from github.com/go-text/typesetting/shaping,
+// which doesn't take features, and differs only in the features passed to
+// harfbuzz and in checking the type of the face.
+func FeatureShaper(features []harfbuzz.Feature) Shaper {
+	if len(features) == 0 {
+		return shaping.Shape
+	}
+	return func(input shaping.Input) (shaping.Output, error) {
+		start, end := input.RunStart, input.RunEnd
+		if end < start {
+			return shaping.Output{}, shaping.InvalidRunError{RunStart: start, RunEnd: end, TextLength: len(input.Text)}
+		}
+		buf := harfbuzz.NewBuffer()
+		buf.AddRunes(input.Text, start, end-start)
+		switch input.Direction {
+		case di.DirectionLTR:
+			buf.Props.Direction = harfbuzz.LeftToRight
+		case di.DirectionRTL:
+			buf.Props.Direction = harfbuzz.RightToLeft
+		default:
+			return shaping.Output{}, shaping.UnimplementedDirectionError{Direction: input.Direction}
+		}
+		buf.Props.Language = input.Language
+		buf.Props.Script = input.Script
+		face, ok := input.Face.(harfbuzz.Face)
+		if !ok {
+			return shaping.Output{}, fmt.Errorf("shaping: unsupported face type %T", input.Face)
+		}
+		font := harfbuzz.NewFont(face)
+		font.XScale = int32(input.Size.Ceil()) << scaleShift
+		font.YScale = font.XScale
+		buf.Shape(font, features)
+
+		glyphs := make([]shaping.Glyph, len(buf.Info))
+		for i := range glyphs {
+			g := buf.Info[i].Glyph
+			extents, ok := font.GlyphExtents(g)
+			if !ok {
+				return shaping.Output{}, shaping.MissingGlyphError{GID: g}
+			}
+			glyphs[i] = shaping.Glyph{
+				Width:        fixed.I(int(extents.Width)) >> scaleShift,
+				Height:       fixed.I(int(extents.Height)) >> scaleShift,
+				XBearing:     fixed.I(int(extents.XBearing)) >> scaleShift,
+				YBearing:     fixed.I(int(extents.YBearing)) >> scaleShift,
+				XAdvance:     fixed.I(int(buf.Pos[i].XAdvance)) >> scaleShift,
+				YAdvance:     fixed.I(int(buf.Pos[i].YAdvance)) >> scaleShift,
+				XOffset:      fixed.I(int(buf.Pos[i].XOffset)) >> scaleShift,
+				YOffset:      fixed.I(int(buf.Pos[i].YOffset)) >> scaleShift,
+				ClusterIndex: buf.Info[i].Cluster,
+				GlyphID:      g,
+				Mask:         buf.Info[i].Mask,
+			}
+		}
+		countClusters(glyphs, end, input.Direction)
+		out := shaping.Output{
+			Glyphs:    glyphs,
+			Direction: input.Direction,
+		}
+		extents := font.ExtentsForDirection(buf.Props.Direction)
+		out.LineBounds = shaping.Bounds{
+			Ascent:  fixed.I(int(extents.Ascender)) >> scaleShift,
+			Descent: fixed.I(int(extents.Descender)) >> scaleShift,
+			Gap:     fixed.I(int(extents.LineGap)) >> scaleShift,
+		}
+		return out, out.RecalculateAll()
+	}
+}
+
+// countClusters sets the rune and glyph counts of the clusters of glyphs
+// shaped from text ending at rune end.
+func countClusters(glyphs []shaping.Glyph, end int, dir di.Direction) {
+	prev := end
+	for i := 0; i < len(glyphs); {
+		cluster := glyphs[i].ClusterIndex
+		j := i + 1
+		for j < len(glyphs) && glyphs[j].ClusterIndex == cluster {
+			j++
+		}
+		next := end
+		if j < len(glyphs) {
+			next = glyphs[j].ClusterIndex
+		}
+		runes := next - cluster
+		if dir == di.DirectionRTL {
+			runes = prev - cluster
+		}
+		for k := i; k < j; k++ {
+			glyphs[k].GlyphCount = j - i
+			glyphs[k].RuneCount = runes
+		}
+		prev = cluster
+		i = j
+	}
+}
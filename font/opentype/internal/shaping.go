@@ -2,6 +2,8 @@ package internal
 
 import (
 	"io"
+	"sort"
+	"unicode"
 
 	"github.com/xiaoshengduan/gio-fly/io/system"
 	"github.com/xiaoshengduan/gio-fly/text"
@@ -102,6 +104,75 @@ func computeGlyphClusters(l *text.Layout) {
 	l.Clusters = clusters
 }
 
+// computeBidiClusters populates the Clusters field of a Layout whose
+// glyphs have the given embedding levels. The glyphs of every level run
+// are clustered in the direction of their level, and the clusters are
+// ordered logically.
+func computeBidiClusters(l *text.Layout, levels []uint8) {
+	var clusters []text.GlyphCluster
+	end := l.Runes.Offset
+	for start := 0; start < len(l.Glyphs); {
+		stop := start + 1
+		for stop < len(l.Glyphs) && levels[stop] == levels[start] {
+			stop++
+		}
+		run := text.Layout{
+			Glyphs:    l.Glyphs[start:stop],
+			Runes:     text.Range{Offset: l.Runes.Offset + l.Runes.Count},
+			Direction: toSystemDirection(levelDirection(levels[start])),
+		}
+		runEnd := l.Runes.Offset
+		for _, g := range run.Glyphs {
+			if g.ClusterIndex < run.Runes.Offset {
+				run.Runes.Offset = g.ClusterIndex
+			}
+			if e := g.ClusterIndex + g.RuneCount; e > runEnd {
+				runEnd = e
+			}
+		}
+		run.Runes.Count = runEnd - run.Runes.Offset
+		if runEnd > end {
+			end = runEnd
+		}
+		computeGlyphClusters(&run)
+		for _, c := range run.Clusters {
+			c.Glyphs.Offset += start
+			clusters = append(clusters, c)
+		}
+		start = stop
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		return clusters[i].Runes.Offset < clusters[j].Runes.Offset
+	})
+	if last := l.Runes.Offset + l.Runes.Count; end < last {
+		// Synthesize a cluster for the trailing newline at the end of
+		// the line.
+		newline := text.GlyphCluster{
+			Runes: text.Range{Count: 1, Offset: last - 1},
+		}
+		if l.Direction.Progression() == system.FromOrigin {
+			newline.Glyphs.Offset = len(l.Glyphs)
+		}
+		clusters = append(clusters, newline)
+	}
+	l.Clusters = clusters
+}
+
+// positionClusters sets the X field of the clusters of a Layout.
+func positionClusters(l *text.Layout) {
+	x := make([]fixed.Int26_6, len(l.Glyphs)+1)
+	for i, g := range l.Glyphs {
+		x[i+1] = x[i] + g.XAdvance
+	}
+	for i := range l.Clusters {
+		c := &l.Clusters[i]
+		c.X = x[c.Glyphs.Offset]
+		if c.Advance < 0 {
+			c.X -= c.Advance
+		}
+	}
+}
+
 // langConfig describes the language and writing system of a body of text.
 type langConfig struct {
 	// Language the text is written in.
@@ -196,11 +267,18 @@ type breakOption struct {
 	// penalties mean that the break is beneficial, and a penalty
 	// of uax14.PenaltyForMustBreak means a required break.
 	penalty int
+	// hyphen is the advance of the hyphen added to a line broken
+	// inside a word, or zero for breaks between words.
+	hyphen fixed.Int26_6
 }
 
+// hyphenPenalty is the penalty of breaking a word with a hyphen.
+const hyphenPenalty = 50
+
 // getBreakOptions returns a slice of line break candidates for the
-// text in the provided slice.
-func getBreakOptions(text []rune) []breakOption {
+// text in the provided slice. If hyphens is not nil, it adds the
+// hyphenation points of words, with a hyphen of advance hyphen.
+func getBreakOptions(text []rune, hyphens text.Hyphenator, hyphen fixed.Int26_6) []breakOption {
 	// Collect options for breaking the lines in a slice.
 	var options []breakOption
 	const adjust = -1
@@ -231,29 +309,473 @@ func getBreakOptions(text []rune) []breakOption {
 			breakAtRune: len(text) - 1,
 		})
 	}
+	if hyphens != nil {
+		options = hyphenBreaks(options, text, hyphens, hyphen)
+	}
 	return options
 }
 
+// hyphenBreaks merges the hyphenation points of the words of text into
+// the break options.
+func hyphenBreaks(options []breakOption, text []rune, hyphens text.Hyphenator, hyphen fixed.Int26_6) []breakOption {
+	var merged []breakOption
+	next := 0
+	for start := 0; start < len(text); {
+		if !unicode.IsLetter(text[start]) {
+			start++
+			continue
+		}
+		end := start + 1
+		for end < len(text) && (unicode.IsLetter(text[end]) || unicode.IsMark(text[end])) {
+			end++
+		}
+		for _, i := range hyphens.Hyphenate(text[start:end]) {
+			if i <= 0 || i >= end-start {
+				continue
+			}
+			at := start + i - 1
+			for next < len(options) && options[next].breakAtRune < at {
+				merged = append(merged, options[next])
+				next++
+			}
+			if next < len(options) && options[next].breakAtRune == at {
+				continue
+			}
+			merged = append(merged, breakOption{
+				breakAtRune: at,
+				penalty:     hyphenPenalty,
+				hyphen:      hyphen,
+			})
+		}
+		start = end
+	}
+	return append(merged, options[next:]...)
+}
+
 type Shaper func(shaping.Input) (shaping.Output, error)
 
+// Face is a font face for shaping, along with the text.Face recorded in
+// the glyphs shaped with it.
+type Face struct {
+	// Font returns the font face, or nil if it is not available. It is
+	// called only if the face is needed.
+	Font func() font.Face
+	Face text.Face
+}
+
+// fontmap is a shaping.Fontmap that resolves runes to the first face with
+// glyphs for them, calling Face.Font only as needed.
+type fontmap struct {
+	faces  []Face
+	fonts  []font.Face
+	loaded []bool
+}
+
+func newFontmap(faces []Face) *fontmap {
+	return &fontmap{
+		faces:  faces,
+		fonts:  make([]font.Face, len(faces)),
+		loaded: make([]bool, len(faces)),
+	}
+}
+
+// font returns the font of face i.
+func (m *fontmap) font(i int) font.Face {
+	if !m.loaded[i] {
+		m.loaded[i] = true
+		m.fonts[i] = m.faces[i].Font()
+	}
+	return m.fonts[i]
+}
+
+func (m *fontmap) ResolveFace(r rune) font.Face {
+	for i := range m.faces {
+		if f := m.font(i); f != nil {
+			if _, ok := f.NominalGlyph(r); ok {
+				return f
+			}
+		}
+	}
+	return m.font(0)
+}
+
+// face returns the text.Face of the font f.
+func (m *fontmap) face(f font.Face) text.Face {
+	for i, mf := range m.fonts {
+		if m.loaded[i] && mf == f {
+			return m.faces[i].Face
+		}
+	}
+	return m.faces[0].Face
+}
+
+// faceRun is a run of runes shaped with a single face.
+type faceRun struct {
+	// start is the index of the first rune of the run.
+	start int
+	face  text.Face
+}
+
 // paragraph shapes a single paragraph of text, breaking it into multiple lines
 // to fit within the provided maxWidth.
-func paragraph(shaper Shaper, face font.Face, ppem fixed.Int26_6, maxWidth int, lc langConfig, paragraph []rune) ([]output, error) {
-	// TODO: handle splitting bidi text here
+func paragraph(shaper Shaper, faces []Face, ppem fixed.Int26_6, maxWidth int, lc langConfig, params text.Parameters, paragraph []rune) ([]output, error) {
+	bidi := newBidiParagraph(paragraph, lc.Direction)
+	lc.Direction = levelDirection(bidi.base)
+	mixed := bidi.mixed()
 
-	// Shape the text.
-	input := toInput(face, ppem, lc, paragraph)
-	out, err := shaper(input)
+	// Shape the text. The runs of a paragraph mixing directions are
+	// shaped separately and kept in logical order until the lines are
+	// reordered.
+	var (
+		out  shaping.Output
+		runs []faceRun
+		err  error
+	)
+	if mixed {
+		out, runs, err = shapeBidi(shaper, faces, ppem, lc, bidi, paragraph)
+	} else {
+		out, runs, err = shapeRuns(shaper, faces, toInput(nil, ppem, lc, paragraph))
+	}
 	if err != nil {
 		return nil, err
 	}
+	letterSpacing(&out, params.LetterSpacing)
 	// Get a mapping from input runes to output glyphs.
 	runeToGlyph := mapRunesToClusterIndices(paragraph, out.Glyphs)
 
+	// Shape the hyphen of hyphenated lines.
+	var (
+		hyphen      shaping.Output
+		hyphenFaces []text.Face
+	)
+	if params.Hyphenator != nil {
+		h, hruns, err := shapeRuns(shaper, faces, toInput(nil, ppem, lc, []rune{'-'}))
+		if err != nil {
+			return nil, err
+		}
+		letterSpacing(&h, params.LetterSpacing)
+		hyphen, hyphenFaces = h, glyphFaces(h.Glyphs, hruns)
+	}
+
 	// Fetch line break candidates.
-	breaks := getBreakOptions(paragraph)
+	breaks := getBreakOptions(paragraph, params.Hyphenator, hyphen.Advance)
+
+	lines := lineWrap(out, out.Direction, paragraph, runeToGlyph, breaks, maxWidth)
+	for i := range lines {
+		lines[i].Faces = glyphFaces(lines[i].Shaped.Glyphs, runs)
+		if lines[i].Hyphen {
+			addHyphen(&lines[i], hyphen, hyphenFaces)
+		}
+		if params.Justify && i < len(lines)-1 {
+			justify(&lines[i], paragraph, maxWidth)
+		}
+		if mixed {
+			reorder(&lines[i], bidi)
+		}
+	}
+	return lines, nil
+}
 
-	return lineWrap(out, input.Direction, paragraph, runeToGlyph, breaks, maxWidth), nil
+// shapeBidi shapes every level run of a paragraph in the direction of its
+// level, with the script of its runes. The glyphs of the returned
+// left-to-right output are in logical order.
+func shapeBidi(shaper Shaper, faces []Face, ppem fixed.Int26_6, lc langConfig, bidi *bidiParagraph, paragraph []rune) (shaping.Output, []faceRun, error) {
+	var (
+		merged shaping.Output
+		runs   []faceRun
+	)
+	for start := 0; start < len(paragraph); {
+		end := start + 1
+		for end < len(paragraph) && bidi.levels[end] == bidi.levels[start] {
+			end++
+		}
+		rlc := lc
+		rlc.Direction = levelDirection(bidi.levels[start])
+		rlc.Script = runScript(paragraph[start:end], lc.Script)
+		input := toInput(nil, ppem, rlc, paragraph)
+		input.RunStart, input.RunEnd = start, end
+		out, r, err := shapeRuns(shaper, faces, input)
+		if err != nil {
+			return shaping.Output{}, nil, err
+		}
+		if rlc.Direction == di.DirectionRTL {
+			for i, j := 0, len(out.Glyphs)-1; i < j; i, j = i+1, j-1 {
+				out.Glyphs[i], out.Glyphs[j] = out.Glyphs[j], out.Glyphs[i]
+			}
+		}
+		runs = append(runs, r...)
+		if start == 0 {
+			merged = out
+		} else {
+			merged.Glyphs = append(merged.Glyphs, out.Glyphs...)
+			merged.Advance += out.Advance
+			merged.LineBounds = unionBounds(merged.LineBounds, out.LineBounds)
+			merged.GlyphBounds = unionBounds(merged.GlyphBounds, out.GlyphBounds)
+		}
+		start = end
+	}
+	merged.Direction = di.DirectionLTR
+	return merged, runs, nil
+}
+
+// runScript returns the most frequent script of runes, or def if they
+// are all common or inherited.
+func runScript(runes []rune, def language.Script) language.Script {
+	counts := make(map[language.Script]int)
+	script, max := def, 0
+	for _, r := range runes {
+		s := language.LookupScript(r)
+		if s == language.Common || s == language.Inherited {
+			continue
+		}
+		counts[s]++
+		if counts[s] > max {
+			script, max = s, counts[s]
+		}
+	}
+	return script
+}
+
+// reorder arranges the glyphs of a line of a paragraph mixing directions
+// in visual order, rules L1 and L2, and records their levels if the line
+// mixes directions.
+func reorder(l *output, bidi *bidiParagraph) {
+	start := l.RuneRange.Offset
+	runeLevels := bidi.lineLevels(start, start+l.RuneRange.Count)
+	levels := make([]uint8, len(l.Shaped.Glyphs))
+	for i, g := range l.Shaped.Glyphs {
+		levels[i] = runeLevels[g.ClusterIndex-start]
+	}
+	order := visualOrder(levels)
+	glyphs := make([]shaping.Glyph, len(order))
+	var faces []text.Face
+	if len(l.Faces) == len(glyphs) {
+		faces = make([]text.Face, len(order))
+	}
+	visual := make([]uint8, len(order))
+	mixed := false
+	for i, o := range order {
+		glyphs[i] = l.Shaped.Glyphs[o]
+		if faces != nil {
+			faces[i] = l.Faces[o]
+		}
+		visual[i] = levels[o]
+		mixed = mixed || visual[i] != bidi.base
+	}
+	l.Shaped.Glyphs = glyphs
+	l.Shaped.Direction = levelDirection(bidi.base)
+	l.Faces = faces
+	l.Levels = nil
+	if mixed {
+		l.Levels = visual
+	}
+}
+
+// levelDirection returns the direction of an embedding level.
+func levelDirection(level uint8) di.Direction {
+	if level%2 == 1 {
+		return di.DirectionRTL
+	}
+	return di.DirectionLTR
+}
+
+// addHyphen adds the hyphen glyphs to the last cluster of a line.
+func addHyphen(l *output, hyphen shaping.Output, faces []text.Face) {
+	glyphs := l.Shaped.Glyphs
+	if len(glyphs) == 0 || len(hyphen.Glyphs) == 0 {
+		return
+	}
+	rtl := l.Shaped.Direction == di.DirectionRTL
+	last := glyphs[len(glyphs)-1]
+	if rtl {
+		last = glyphs[0]
+	}
+	hglyphs := make([]shaping.Glyph, len(hyphen.Glyphs))
+	copy(hglyphs, hyphen.Glyphs)
+	count := len(hglyphs)
+	for _, g := range glyphs {
+		if g.ClusterIndex == last.ClusterIndex {
+			count++
+		}
+	}
+	for i := range hglyphs {
+		hglyphs[i].ClusterIndex = last.ClusterIndex
+		hglyphs[i].RuneCount = last.RuneCount
+	}
+	if len(faces) != len(hglyphs) {
+		faces = make([]text.Face, len(hglyphs))
+	}
+	lfaces := l.Faces
+	if len(lfaces) != len(glyphs) {
+		lfaces = make([]text.Face, len(glyphs))
+	}
+	var newGlyphs []shaping.Glyph
+	var newFaces []text.Face
+	if rtl {
+		newGlyphs = append(append(newGlyphs, hglyphs...), glyphs...)
+		newFaces = append(append(newFaces, faces...), lfaces...)
+	} else {
+		newGlyphs = append(append(newGlyphs, glyphs...), hglyphs...)
+		newFaces = append(append(newFaces, lfaces...), faces...)
+	}
+	for i := range newGlyphs {
+		if newGlyphs[i].ClusterIndex == last.ClusterIndex {
+			newGlyphs[i].GlyphCount = count
+		}
+	}
+	l.Shaped.Glyphs = newGlyphs
+	l.Faces = newFaces
+	l.Shaped.RecomputeAdvance()
+}
+
+// justify stretches the word gaps of a line to fill maxWidth. The white
+// space at the end of the line is collapsed.
+func justify(l *output, paragraph []rune, maxWidth int) {
+	start, end := l.RuneRange.Offset, l.RuneRange.Offset+l.RuneRange.Count
+	trailing := end
+	for trailing > start && unicode.IsSpace(paragraph[trailing-1]) {
+		trailing--
+	}
+	gaps := 0
+	var width fixed.Int26_6
+	for _, g := range l.Shaped.Glyphs {
+		if g.ClusterIndex >= trailing {
+			continue
+		}
+		width += g.XAdvance
+		if unicode.IsSpace(paragraph[g.ClusterIndex]) {
+			gaps++
+		}
+	}
+	extra := fixed.I(maxWidth) - width
+	if gaps == 0 || extra <= 0 {
+		return
+	}
+	glyphs := make([]shaping.Glyph, len(l.Shaped.Glyphs))
+	copy(glyphs, l.Shaped.Glyphs)
+	per, rem := extra/fixed.Int26_6(gaps), extra%fixed.Int26_6(gaps)
+	for i := range glyphs {
+		g := &glyphs[i]
+		switch {
+		case g.ClusterIndex >= trailing:
+			g.XAdvance = 0
+		case unicode.IsSpace(paragraph[g.ClusterIndex]):
+			g.XAdvance += per
+			if rem > 0 {
+				g.XAdvance++
+				rem--
+			}
+		}
+	}
+	l.Shaped.Glyphs = glyphs
+	l.Shaped.RecomputeAdvance()
+}
+
+// letterSpacing adds space after the last glyph of every cluster.
+func letterSpacing(out *shaping.Output, space fixed.Int26_6) {
+	if space == 0 || len(out.Glyphs) == 0 {
+		return
+	}
+	for i := range out.Glyphs {
+		if i == len(out.Glyphs)-1 || out.Glyphs[i+1].ClusterIndex != out.Glyphs[i].ClusterIndex {
+			out.Glyphs[i].XAdvance += space
+		}
+	}
+	out.RecomputeAdvance()
+}
+
+// shapeRuns shapes every rune of input with the first of faces that has a
+// glyph for it, or with the first face if none has. The first face must
+// be available. The runs shaped with
+// each face are merged into a single output.
+func shapeRuns(shaper Shaper, faces []Face, input shaping.Input) (shaping.Output, []faceRun, error) {
+	if len(faces) == 0 {
+		out, err := shaper(input)
+		return out, nil, err
+	}
+	fonts := newFontmap(faces)
+	input.Face = fonts.font(0)
+	inputs := []shaping.Input{input}
+	if len(faces) > 1 {
+		inputs = shaping.SplitByFace(input, fonts)
+	}
+	var (
+		merged shaping.Output
+		runs   []faceRun
+	)
+	for i, in := range inputs {
+		out, err := shaper(in)
+		if err != nil {
+			return shaping.Output{}, nil, err
+		}
+		face := fonts.face(in.Face)
+		fixRuneCounts(out.Glyphs, in.RunEnd)
+		runs = append(runs, faceRun{start: in.RunStart, face: face})
+		if i == 0 {
+			merged = out
+			continue
+		}
+		// Glyphs are in visual order, so later runs of right-to-left text
+		// go before earlier runs.
+		if input.Direction.Progression() == di.TowardTopLeft {
+			merged.Glyphs = append(out.Glyphs, merged.Glyphs...)
+		} else {
+			merged.Glyphs = append(merged.Glyphs, out.Glyphs...)
+		}
+		merged.Advance += out.Advance
+		merged.LineBounds = unionBounds(merged.LineBounds, out.LineBounds)
+		merged.GlyphBounds = unionBounds(merged.GlyphBounds, out.GlyphBounds)
+	}
+	return merged, runs, nil
+}
+
+// fixRuneCounts corrects the rune counts of the glyphs of the last cluster of
+// a run ending at rune end. The shaper counts them as if the run started at
+// the beginning of the text.
+func fixRuneCounts(glyphs []shaping.Glyph, end int) {
+	last := -1
+	for _, g := range glyphs {
+		if g.ClusterIndex > last {
+			last = g.ClusterIndex
+		}
+	}
+	for i := range glyphs {
+		if glyphs[i].ClusterIndex == last {
+			glyphs[i].RuneCount = end - last
+		}
+	}
+}
+
+// unionBounds returns bounds that contain both a and b.
+func unionBounds(a, b shaping.Bounds) shaping.Bounds {
+	if b.Ascent > a.Ascent {
+		a.Ascent = b.Ascent
+	}
+	if b.Descent < a.Descent {
+		a.Descent = b.Descent
+	}
+	if b.Gap > a.Gap {
+		a.Gap = b.Gap
+	}
+	return a
+}
+
+// glyphFaces returns the faces of glyphs shaped in runs.
+func glyphFaces(glyphs []shaping.Glyph, runs []faceRun) []text.Face {
+	if len(runs) == 0 {
+		return nil
+	}
+	faces := make([]text.Face, len(glyphs))
+	for i, g := range glyphs {
+		r := sort.Search(len(runs), func(r int) bool {
+			return runs[r].start > g.ClusterIndex
+		}) - 1
+		if r < 0 {
+			r = 0
+		}
+		faces[i] = runs[r].face
+	}
+	return faces
 }
 
 // shouldKeepSegmentOnLine decides whether the segment of text from the current
@@ -282,7 +804,7 @@ func shouldKeepSegmentOnLine(out shaping.Output, runeToGlyph []int, lineStartRun
 	candidateLine = out
 	candidateLine.Glyphs = candidateLine.Glyphs[glyphStart : glyphEnd+1]
 	candidateLine.RecomputeAdvance()
-	candidateAdvance := candidateLine.Advance.Ceil()
+	candidateAdvance := (candidateLine.Advance + b.hyphen).Ceil()
 	if candidateAdvance > curLineWidth && candidateAdvance-curLineUsed <= nextLineWidth {
 		// If it fits on the next line, put it there.
 		return candidateLine, false
@@ -330,6 +852,7 @@ func lineWrap(out shaping.Output, dir di.Direction, paragraph []rune, runeToGlyp
 		// Always keep the first segment on a line.
 		good, _ := shouldKeepSegmentOnLine(out, runeToGlyph, start, b, maxWidth, 0, maxWidth)
 		end := b.breakAtRune
+		hyphen := b.hyphen != 0
 	innerLoop:
 		for k := i + 1; k < len(breaks); k++ {
 			bb := breaks[k]
@@ -338,6 +861,7 @@ func lineWrap(out shaping.Output, dir di.Direction, paragraph []rune, runeToGlyp
 				// Use this new, longer segment.
 				good = candidate
 				end = bb.breakAtRune
+				hyphen = bb.hyphen != 0
 				i++
 			} else {
 				break innerLoop
@@ -350,6 +874,7 @@ func lineWrap(out shaping.Output, dir di.Direction, paragraph []rune, runeToGlyp
 				Count:  numRunes,
 				Offset: runesProcessed,
 			},
+			Hyphen: hyphen,
 		})
 		runesProcessed += numRunes
 		start = end + 1
@@ -362,6 +887,16 @@ func lineWrap(out shaping.Output, dir di.Direction, paragraph []rune, runeToGlyp
 type output struct {
 	Shaped    shaping.Output
 	RuneRange text.Range
+	// Faces are the faces of the Shaped glyphs, if known.
+	Faces []text.Face
+	// Truncated is the number of runes replaced by a truncator.
+	Truncated int
+	// Hyphen reports whether the line is broken inside a word.
+	Hyphen bool
+	// Levels are the embedding levels of the Shaped glyphs of a line
+	// mixing directions, or nil if the glyphs are all in the direction
+	// of the line.
+	Levels []uint8
 }
 
 func toSystemDirection(d di.Direction) system.TextDirection {
@@ -376,10 +911,15 @@ func toSystemDirection(d di.Direction) system.TextDirection {
 
 // toGioGlyphs converts text shaper glyphs into the minimal representation
 // that Gio needs.
-func toGioGlyphs(in []shaping.Glyph) []text.Glyph {
+func toGioGlyphs(in []shaping.Glyph, faces []text.Face) []text.Glyph {
 	out := make([]text.Glyph, 0, len(in))
-	for _, g := range in {
+	for i, g := range in {
+		var face text.Face
+		if i < len(faces) {
+			face = faces[i]
+		}
 		out = append(out, text.Glyph{
+			Face:         face,
 			ID:           g.GlyphID,
 			ClusterIndex: g.ClusterIndex,
 			RuneCount:    g.RuneCount,
@@ -396,7 +936,7 @@ func toGioGlyphs(in []shaping.Glyph) []text.Glyph {
 // ToLine converts the output into a text.Line
 func (o output) ToLine() text.Line {
 	layout := text.Layout{
-		Glyphs:    toGioGlyphs(o.Shaped.Glyphs),
+		Glyphs:    toGioGlyphs(o.Shaped.Glyphs, o.Faces),
 		Runes:     o.RuneRange,
 		Direction: toSystemDirection(o.Shaped.Direction),
 	}
@@ -411,9 +951,10 @@ func (o output) ToLine() text.Line {
 				Y: -o.Shaped.LineBounds.Ascent + o.Shaped.LineBounds.LineHeight(),
 			},
 		},
-		Width:   o.Shaped.Advance,
-		Ascent:  o.Shaped.LineBounds.Ascent,
-		Descent: -o.Shaped.LineBounds.Descent + o.Shaped.LineBounds.Gap,
+		Width:     o.Shaped.Advance,
+		Ascent:    o.Shaped.LineBounds.Ascent,
+		Descent:   -o.Shaped.LineBounds.Descent + o.Shaped.LineBounds.Gap,
+		Truncated: o.Truncated,
 	}
 }
 
@@ -427,15 +968,19 @@ func mapDirection(d system.TextDirection) di.Direction {
 	return di.DirectionLTR
 }
 
-// Document shapes text using the given font, ppem, maximum line width, language,
-// and sequence of runes. It returns a slice of lines corresponding to the txt,
-// broken to fit within maxWidth and on paragraph boundaries.
-func Document(shaper Shaper, face font.Face, ppem fixed.Int26_6, maxWidth int, lc system.Locale, txt io.RuneReader) []text.Line {
+// Document shapes text using the given faces, ppem, maximum line width, language,
+// and sequence of runes. Every rune is shaped with the first face that has a
+// glyph for it. It returns a slice of lines corresponding to the txt,
+// broken to fit within maxWidth and on paragraph boundaries, and
+// truncated to params.MaxLines.
+func Document(shaper Shaper, faces []Face, ppem fixed.Int26_6, maxWidth int, lc system.Locale, params text.Parameters, txt io.RuneReader) []text.Line {
 	var (
-		outputs       []text.Line
+		outputs       []output
 		startByte     int
 		startRune     int
 		paragraphText []rune
+		docText       []rune
+		lcfg          langConfig
 		done          bool
 		langs         = make(map[language.Script]int)
 	)
@@ -474,12 +1019,12 @@ func Document(shaper Shaper, face font.Face, ppem fixed.Int26_6, maxWidth int, l
 		if lc.Language == "" {
 			lc.Language = "EN"
 		}
-		lcfg := langConfig{
+		lcfg = langConfig{
 			Language:  language.NewLanguage(lc.Language),
 			Script:    primary,
 			Direction: mapDirection(lc.Direction),
 		}
-		lines, _ := paragraph(shaper, face, ppem, maxWidth, lcfg, paragraphText[:len(paragraphText)-newlineAdjust])
+		lines, _ := paragraph(shaper, faces, ppem, maxWidth, lcfg, params, paragraphText[:len(paragraphText)-newlineAdjust])
 		for i := range lines {
 			// Update the offsets of each paragraph to be correct within the
 			// whole document.
@@ -489,21 +1034,33 @@ func Document(shaper Shaper, face font.Face, ppem fixed.Int26_6, maxWidth int, l
 			for k := range lines[i].Shaped.Glyphs {
 				lines[i].Shaped.Glyphs[k].ClusterIndex += startRune
 			}
-			outputs = append(outputs, lines[i].ToLine())
+			outputs = append(outputs, lines[i])
 		}
 		// If there was a trailing newline update the byte counts to include
 		// it on the last line of the paragraph.
 		if newlineAdjust > 0 {
-			outputs[len(outputs)-1].Layout.Runes.Count += newlineAdjust
+			outputs[len(outputs)-1].RuneRange.Count += newlineAdjust
+		}
+		if params.MaxLines > 0 {
+			docText = append(docText, paragraphText...)
 		}
 		paragraphText = paragraphText[:0]
 		startByte += bytes
 		startRune += runes
 	}
+	outputs = truncate(shaper, faces, ppem, maxWidth, lcfg, params, outputs, docText)
+	lines := make([]text.Line, len(outputs))
 	for i := range outputs {
-		computeGlyphClusters(&outputs[i].Layout)
+		lines[i] = outputs[i].ToLine()
+		if outputs[i].Levels != nil {
+			computeBidiClusters(&lines[i].Layout, outputs[i].Levels)
+		} else {
+			computeGlyphClusters(&lines[i].Layout)
+		}
+		positionClusters(&lines[i].Layout)
 	}
-	return outputs
+	params.AdjustLines(lines)
+	return lines
 }
 
 // toInput converts its parameters into a shaping.Input.
@@ -847,7 +847,7 @@ func TestEngineLineWrap(t *testing.T) {
 			runeToGlyph := mapRunesToClusterIndices(tc.paragraph, tc.shaped.Glyphs)
 
 			// Fetch line break candidates.
-			breaks := getBreakOptions(tc.paragraph)
+			breaks := getBreakOptions(tc.paragraph, nil, 0)
 
 			outs := lineWrap(tc.shaped, tc.direction, tc.paragraph, runeToGlyph, breaks, tc.maxWidth)
 			if len(tc.expected) != len(outs) {
@@ -907,7 +907,7 @@ At risus viverra adipiscing at.`
 		return o, nil
 	}
 
-	lines := Document(shaper, nil, 10, 100, english, bytes.NewBufferString(doc))
+	lines := Document(shaper, nil, 10, 100, english, text.Parameters{}, bytes.NewBufferString(doc))
 
 	lineRunes := 0
 	for i, line := range lines {
@@ -924,6 +924,123 @@ At risus viverra adipiscing at.`
 	}
 }
 
+func TestEngineTruncate(t *testing.T) {
+	const doc = "Rutrum quisque non tellus orci ac auctor augue.\nAt risus viverra adipiscing at."
+	english := system.Locale{
+		Language:  "EN",
+		Direction: system.LTR,
+	}
+	docRunes := len([]rune(doc))
+	// Shape every rune as a square glyph 10 units wide.
+	shaper := func(in shaping.Input) (shaping.Output, error) {
+		var o shaping.Output
+		for i := in.RunStart; i < in.RunEnd; i++ {
+			o.Glyphs = append(o.Glyphs, simpleGlyph(i))
+		}
+		o.RecalculateAll()
+		return o, nil
+	}
+	for _, mode := range []text.Truncation{text.TruncateEnd, text.TruncateStart, text.TruncateMiddle} {
+		for _, maxLines := range []int{1, 2, 3} {
+			params := text.Parameters{MaxLines: maxLines, Truncation: mode, Truncator: "..."}
+			lines := Document(shaper, nil, 10, 200, english, params, bytes.NewBufferString(doc))
+			if len(lines) != maxLines {
+				t.Errorf("mode %d: got %d lines, expected %d", mode, len(lines), maxLines)
+				continue
+			}
+			runes, truncated := 0, 0
+			for i, line := range lines {
+				if line.Layout.Runes.Offset != runes {
+					t.Errorf("mode %d: line %d starts at rune %d, expected %d", mode, i, line.Layout.Runes.Offset, runes)
+				}
+				if line.Width > fixed.I(200) {
+					t.Errorf("mode %d: line %d is %v wide", mode, i, line.Width)
+				}
+				clusterRunes := 0
+				for _, c := range line.Layout.Clusters {
+					clusterRunes += c.Runes.Count
+				}
+				if clusterRunes != line.Layout.Runes.Count {
+					t.Errorf("mode %d: line %d clusters cover %d runes, expected %d", mode, i, clusterRunes, line.Layout.Runes.Count)
+				}
+				if line.Truncated > 0 {
+					found := false
+					for _, c := range line.Layout.Clusters {
+						if c.Runes.Count == line.Truncated && c.Glyphs.Count == 3 {
+							found = true
+						}
+					}
+					if !found {
+						t.Errorf("mode %d: line %d has no truncator cluster of %d runes", mode, i, line.Truncated)
+					}
+				}
+				runes += line.Layout.Runes.Count
+				truncated += line.Truncated
+			}
+			if runes != docRunes {
+				t.Errorf("mode %d: lines cover %d runes, expected %d", mode, runes, docRunes)
+			}
+			if truncated == 0 {
+				t.Errorf("mode %d: no runes truncated", mode)
+			}
+		}
+	}
+}
+
+// hyphenateAt hyphenates words before the rune at its index.
+type hyphenateAt int
+
+func (h hyphenateAt) Hyphenate(word []rune) []int {
+	if int(h) < len(word) {
+		return []int{int(h)}
+	}
+	return nil
+}
+
+func TestEngineJustifyHyphenate(t *testing.T) {
+	english := system.Locale{
+		Language:  "EN",
+		Direction: system.LTR,
+	}
+	// Shape every rune as a square glyph 10 units wide.
+	shaper := func(in shaping.Input) (shaping.Output, error) {
+		var o shaping.Output
+		for i := in.RunStart; i < in.RunEnd; i++ {
+			o.Glyphs = append(o.Glyphs, simpleGlyph(i))
+		}
+		o.RecalculateAll()
+		return o, nil
+	}
+	params := text.Parameters{Justify: true, Hyphenator: hyphenateAt(2)}
+	lines := Document(shaper, nil, 10, 100, english, params, bytes.NewBufferString("aaa bbbbbbbb cc"))
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, expected 2", len(lines))
+	}
+	first, last := lines[0], lines[1]
+	if got, exp := first.Layout.Runes, (text.Range{Count: 6}); got != exp {
+		t.Errorf("first line covers runes %+v, expected %+v", got, exp)
+	}
+	if got, exp := len(first.Layout.Glyphs), 7; got != exp {
+		t.Errorf("first line has %d glyphs, expected %d with the hyphen", got, exp)
+	}
+	if got, exp := first.Width, fixed.I(100); got != exp {
+		t.Errorf("justified line is %v wide, expected %v", got, exp)
+	}
+	if got, exp := first.Layout.Glyphs[3].XAdvance, fixed.I(40); got != exp {
+		t.Errorf("word gap is %v wide, expected %v", got, exp)
+	}
+	clusterRunes := 0
+	for _, c := range first.Layout.Clusters {
+		clusterRunes += c.Runes.Count
+	}
+	if clusterRunes != first.Layout.Runes.Count {
+		t.Errorf("clusters cover %d runes, expected %d", clusterRunes, first.Layout.Runes.Count)
+	}
+	if got, exp := last.Width, fixed.I(90); got != exp {
+		t.Errorf("last line is %v wide, expected %v", got, exp)
+	}
+}
+
 // simpleGlyph returns a simple square glyph with the provided cluster
 // value.
 func simpleGlyph(cluster int) shaping.Glyph {
@@ -990,7 +1107,7 @@ func TestLayoutComputeClusters(t *testing.T) {
 			name: "just newline",
 			line: text.Layout{
 				Direction: system.LTR,
-				Glyphs:    toGioGlyphs([]shaping.Glyph{}),
+				Glyphs:    toGioGlyphs([]shaping.Glyph{}, nil),
 				Runes: text.Range{
 					Count: 1,
 				},
@@ -1011,7 +1128,7 @@ func TestLayoutComputeClusters(t *testing.T) {
 					simpleGlyph(0),
 					simpleGlyph(1),
 					simpleGlyph(2),
-				}),
+				}, nil),
 				Runes: text.Range{
 					Count: 3,
 				},
@@ -1030,7 +1147,7 @@ func TestLayoutComputeClusters(t *testing.T) {
 					simpleGlyph(0),
 					simpleGlyph(1),
 					simpleGlyph(2),
-				}),
+				}, nil),
 				Runes: text.Range{
 					Count: 4,
 				},
@@ -1058,7 +1175,7 @@ func TestLayoutComputeClusters(t *testing.T) {
 					ligatureGlyph(0, 2),
 					simpleGlyph(2),
 					simpleGlyph(3),
-				}),
+				}, nil),
 				Runes: text.Range{
 					Count: 4,
 				},
@@ -1083,7 +1200,7 @@ func TestLayoutComputeClusters(t *testing.T) {
 				Direction: system.LTR,
 				Glyphs: toGioGlyphs([]shaping.Glyph{
 					ligatureGlyph(0, 2),
-				}),
+				}, nil),
 				Runes: text.Range{
 					Count: 3,
 				},
@@ -1118,7 +1235,7 @@ func TestLayoutComputeClusters(t *testing.T) {
 					expansionGlyph(0, 2),
 					simpleGlyph(1),
 					simpleGlyph(2),
-				}),
+				}, nil),
 				Runes: text.Range{
 					Count: 3,
 				},
@@ -1146,7 +1263,7 @@ func TestLayoutComputeClusters(t *testing.T) {
 					ligatureGlyph(1, 2),
 					simpleGlyph(3),
 					simpleGlyph(4),
-				}),
+				}, nil),
 				Runes: text.Range{
 					Count: 5,
 				},
@@ -1176,7 +1293,7 @@ func TestLayoutComputeClusters(t *testing.T) {
 					simpleGlyph(2),
 					simpleGlyph(1),
 					simpleGlyph(0),
-				}),
+				}, nil),
 				Runes: text.Range{
 					Count: 3,
 				},
@@ -1195,7 +1312,7 @@ func TestLayoutComputeClusters(t *testing.T) {
 					simpleGlyph(2),
 					simpleGlyph(1),
 					simpleGlyph(0),
-				}),
+				}, nil),
 				Runes: text.Range{
 					Count: 4,
 				},
@@ -1220,7 +1337,7 @@ func TestLayoutComputeClusters(t *testing.T) {
 					simpleGlyph(3),
 					simpleGlyph(2),
 					ligatureGlyph(0, 2),
-				}),
+				}, nil),
 				Runes: text.Range{
 					Count: 4,
 				},
@@ -1246,7 +1363,7 @@ func TestLayoutComputeClusters(t *testing.T) {
 				Direction: system.RTL,
 				Glyphs: toGioGlyphs([]shaping.Glyph{
 					ligatureGlyph(0, 2),
-				}),
+				}, nil),
 				Runes: text.Range{
 					Count: 3,
 				},
@@ -1278,7 +1395,7 @@ func TestLayoutComputeClusters(t *testing.T) {
 					simpleGlyph(1),
 					expansionGlyph(0, 2),
 					expansionGlyph(0, 2),
-				}),
+				}, nil),
 				Runes: text.Range{
 					Count: 3,
 				},
@@ -1307,7 +1424,7 @@ func TestLayoutComputeClusters(t *testing.T) {
 					simpleGlyph(3),
 					ligatureGlyph(1, 2),
 					simpleGlyph(0),
-				}),
+				}, nil),
 				Runes: text.Range{
 					Count: 5,
 				},
@@ -1342,7 +1459,7 @@ func TestLayoutComputeClusters(t *testing.T) {
 
 func TestGetBreakOptions(t *testing.T) {
 	if err := quick.Check(func(runes []rune) bool {
-		options := getBreakOptions(runes)
+		options := getBreakOptions(runes, nil, 0)
 		// Ensure breaks are in valid range.
 		for _, o := range options {
 			if o.breakAtRune < 0 || o.breakAtRune > len(runes)-1 {
@@ -1390,7 +1507,7 @@ func TestLayoutSlice(t *testing.T) {
 		ligatureGlyph(6, 3),
 		simpleGlyph(9),
 		simpleGlyph(10),
-	})
+	}, nil)
 	rtlGlyphs := toGioGlyphs([]shaping.Glyph{
 		simpleGlyph(10),
 		simpleGlyph(9),
@@ -1401,7 +1518,7 @@ func TestLayoutSlice(t *testing.T) {
 		complexGlyph(1, 2, 2),
 		complexGlyph(1, 2, 2),
 		simpleGlyph(0),
-	})
+	}, nil)
 
 	for _, tc := range []testcase{
 		{
@@ -0,0 +1,287 @@
+package internal
+
+import (
+	"github.com/gioui/uax/grapheme"
+	"github.com/gioui/uax/segment"
+	"github.com/go-text/typesetting/di"
+	"github.com/go-text/typesetting/shaping"
+	"golang.org/x/image/math/fixed"
+
+	"github.com/xiaoshengduan/gio-fly/text"
+)
+
+// truncate reduces lines to params.MaxLines lines, replacing the runes that
+// don't fit with the truncator. The truncated line is cut at grapheme
+// boundaries to fit maxWidth, and the truncator glyphs form a single
+// cluster covering the replaced runes. runes is the text of the document.
+func truncate(shaper Shaper, faces []Face, ppem fixed.Int26_6, maxWidth int, lc langConfig, params text.Parameters, lines []output, runes []rune) []output {
+	n := params.MaxLines
+	if n <= 0 || len(lines) <= n {
+		return lines
+	}
+	tr := params.Truncator
+	if tr == "" {
+		tr = "…"
+	}
+	// The truncator takes the direction of the line it is joined to.
+	switch params.Truncation {
+	case text.TruncateStart:
+		lc.Direction = lines[len(lines)-n].Shaped.Direction
+	case text.TruncateMiddle:
+		lc.Direction = lines[(n-1)/2].Shaped.Direction
+	default:
+		lc.Direction = lines[n-1].Shaped.Direction
+	}
+	trOut, runs, err := shapeRuns(shaper, faces, toInput(nil, ppem, lc, []rune(tr)))
+	if err != nil {
+		return lines[:n]
+	}
+	letterSpacing(&trOut, params.LetterSpacing)
+	trFaces := glyphFaces(trOut.Glyphs, runs)
+	space := fixed.I(maxWidth) - trOut.Advance
+	breaks := graphemeBreaks(runes)
+
+	var before, after output
+	switch params.Truncation {
+	case text.TruncateStart:
+		after = lines[len(lines)-n]
+		after = cutStart(after, breaks, glyphsStart(after), space)
+		lines = lines[len(lines)-n:]
+		lines[0] = join(output{}, trOut, trFaces, after)
+	case text.TruncateMiddle:
+		h := (n - 1) / 2
+		t := n - 1 - h
+		before, after = lines[h], lines[len(lines)-1-t]
+		cb, ca := glyphsEnd(before), glyphsStart(after)
+		// Cut graphemes from the longer side until both sides fit.
+		for {
+			wb, wa := advance(before.Shaped.Glyphs, -1, cb), advance(after.Shaped.Glyphs, ca, -1)
+			if wb+wa <= space {
+				break
+			}
+			if wb >= wa && cb > before.RuneRange.Offset {
+				cb = prevBreak(before, breaks, cb)
+			} else if ca < after.RuneRange.Offset+after.RuneRange.Count {
+				ca = nextBreak(after, breaks, ca)
+			} else if cb > before.RuneRange.Offset {
+				cb = prevBreak(before, breaks, cb)
+			} else {
+				break
+			}
+		}
+		end := after.RuneRange.Offset + after.RuneRange.Count
+		before = keepGlyphs(before, -1, cb)
+		before.RuneRange.Count = cb - before.RuneRange.Offset
+		after = keepGlyphs(after, ca, -1)
+		after.RuneRange = text.Range{Offset: ca, Count: end - ca}
+		merged := join(before, trOut, trFaces, after)
+		lines = append(lines[:h+1:h+1], lines[len(lines)-t:]...)
+		lines[h] = merged
+	default:
+		before = lines[n-1]
+		before = cutEnd(before, breaks, glyphsEnd(before), space)
+		lines = lines[:n]
+		lines[n-1] = join(before, trOut, trFaces, output{RuneRange: text.Range{Offset: len(runes)}})
+	}
+	return lines
+}
+
+// cutEnd drops graphemes from the end of l, starting at rune end, until
+// its glyphs fit in width.
+func cutEnd(l output, breaks []bool, end int, width fixed.Int26_6) output {
+	for end > l.RuneRange.Offset && advance(l.Shaped.Glyphs, -1, end) > width {
+		end = prevBreak(l, breaks, end)
+	}
+	l = keepGlyphs(l, -1, end)
+	l.RuneRange.Count = end - l.RuneRange.Offset
+	return l
+}
+
+// cutStart drops graphemes from the start of l, starting at rune start,
+// until its glyphs fit in width.
+func cutStart(l output, breaks []bool, start int, width fixed.Int26_6) output {
+	end := l.RuneRange.Offset + l.RuneRange.Count
+	for start < end && advance(l.Shaped.Glyphs, start, -1) > width {
+		start = nextBreak(l, breaks, start)
+	}
+	l = keepGlyphs(l, start, -1)
+	l.RuneRange = text.Range{Offset: start, Count: end - start}
+	return l
+}
+
+// join returns the line of the glyphs of before, the truncator glyphs and
+// the glyphs of after, in visual order. The truncator covers the runes
+// between the two.
+func join(before output, tr shaping.Output, trFaces []text.Face, after output) output {
+	start := before.RuneRange.Offset + before.RuneRange.Count
+	glyphs := make([]shaping.Glyph, len(tr.Glyphs))
+	copy(glyphs, tr.Glyphs)
+	for i := range glyphs {
+		glyphs[i].ClusterIndex = start
+		glyphs[i].GlyphCount = len(glyphs)
+		glyphs[i].RuneCount = after.RuneRange.Offset - start
+	}
+	parts := []struct {
+		glyphs []shaping.Glyph
+		faces  []text.Face
+		levels []uint8
+		dir    di.Direction
+	}{
+		{before.Shaped.Glyphs, before.Faces, before.Levels, before.Shaped.Direction},
+		{glyphs, trFaces, nil, tr.Direction},
+		{after.Shaped.Glyphs, after.Faces, after.Levels, after.Shaped.Direction},
+	}
+	mixed := before.Levels != nil || after.Levels != nil
+	if tr.Direction == di.DirectionRTL {
+		parts[0], parts[2] = parts[2], parts[0]
+	}
+	out := output{
+		Shaped: tr,
+		RuneRange: text.Range{
+			Offset: before.RuneRange.Offset,
+			Count:  after.RuneRange.Offset + after.RuneRange.Count - before.RuneRange.Offset,
+		},
+		Truncated: after.RuneRange.Offset - start,
+	}
+	out.Shaped.Glyphs = nil
+	for _, p := range parts {
+		out.Shaped.Glyphs = append(out.Shaped.Glyphs, p.glyphs...)
+		faces := p.faces
+		if len(faces) != len(p.glyphs) {
+			faces = make([]text.Face, len(p.glyphs))
+		}
+		out.Faces = append(out.Faces, faces...)
+		if mixed {
+			levels := p.levels
+			if len(levels) != len(p.glyphs) {
+				levels = make([]uint8, len(p.glyphs))
+				if p.dir == di.DirectionRTL {
+					for i := range levels {
+						levels[i] = 1
+					}
+				}
+			}
+			out.Levels = append(out.Levels, levels...)
+		}
+	}
+	out.Shaped.LineBounds = unionBounds(out.Shaped.LineBounds, before.Shaped.LineBounds)
+	out.Shaped.LineBounds = unionBounds(out.Shaped.LineBounds, after.Shaped.LineBounds)
+	out.Shaped.RecomputeAdvance()
+	return out
+}
+
+// keepGlyphs returns l with only the glyphs of the runes in [start, end).
+// Negative bounds are unlimited.
+func keepGlyphs(l output, start, end int) output {
+	var glyphs []shaping.Glyph
+	var faces []text.Face
+	var levels []uint8
+	for i, g := range l.Shaped.Glyphs {
+		if inRange(g, start, end) {
+			glyphs = append(glyphs, g)
+			if i < len(l.Faces) {
+				faces = append(faces, l.Faces[i])
+			}
+			if i < len(l.Levels) {
+				levels = append(levels, l.Levels[i])
+			}
+		}
+	}
+	if l.Levels != nil {
+		l.Levels = levels
+	}
+	l.Shaped.Glyphs = glyphs
+	if len(faces) == len(glyphs) {
+		l.Faces = faces
+	} else {
+		l.Faces = nil
+	}
+	l.Shaped.RecomputeAdvance()
+	return l
+}
+
+// advance returns the advance of the glyphs of the runes in [start, end).
+// Negative bounds are unlimited.
+func advance(glyphs []shaping.Glyph, start, end int) fixed.Int26_6 {
+	var adv fixed.Int26_6
+	for _, g := range glyphs {
+		if inRange(g, start, end) {
+			adv += g.XAdvance
+		}
+	}
+	return adv
+}
+
+func inRange(g shaping.Glyph, start, end int) bool {
+	return (start < 0 || g.ClusterIndex >= start) && (end < 0 || g.ClusterIndex < end)
+}
+
+// glyphsStart returns the first rune of l with glyphs.
+func glyphsStart(l output) int {
+	start := l.RuneRange.Offset + l.RuneRange.Count
+	for _, g := range l.Shaped.Glyphs {
+		if g.ClusterIndex < start {
+			start = g.ClusterIndex
+		}
+	}
+	return start
+}
+
+// glyphsEnd returns the rune after the last rune of l with glyphs.
+func glyphsEnd(l output) int {
+	end := l.RuneRange.Offset
+	for _, g := range l.Shaped.Glyphs {
+		if e := g.ClusterIndex + g.RuneCount; e > end {
+			end = e
+		}
+	}
+	return end
+}
+
+// prevBreak returns the grapheme boundary of l before rune i that doesn't
+// split a glyph cluster.
+func prevBreak(l output, breaks []bool, i int) int {
+	for i--; i > l.RuneRange.Offset; i-- {
+		if breaks[i] && !splitsCluster(l, i) {
+			break
+		}
+	}
+	return i
+}
+
+// nextBreak returns the grapheme boundary of l after rune i that doesn't
+// split a glyph cluster.
+func nextBreak(l output, breaks []bool, i int) int {
+	end := l.RuneRange.Offset + l.RuneRange.Count
+	for i++; i < end; i++ {
+		if breaks[i] && !splitsCluster(l, i) {
+			break
+		}
+	}
+	return i
+}
+
+// splitsCluster reports whether rune i is inside a glyph cluster of l.
+func splitsCluster(l output, i int) bool {
+	for _, g := range l.Shaped.Glyphs {
+		if g.ClusterIndex < i && i < g.ClusterIndex+g.RuneCount {
+			return true
+		}
+	}
+	return false
+}
+
+// graphemeBreaks returns whether a grapheme starts at each rune of runes,
+// and after the last.
+func graphemeBreaks(runes []rune) []bool {
+	breaks := make([]bool, len(runes)+1)
+	breaks[len(runes)] = true
+	segmenter := segment.NewSegmenter(grapheme.NewBreaker(1))
+	segmenter.InitFromSlice(runes)
+	pos := 0
+	for segmenter.Next() {
+		breaks[pos] = true
+		pos += len(segmenter.Runes())
+	}
+	return breaks
+}
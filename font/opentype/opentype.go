@@ -9,11 +9,14 @@ import (
 	"fmt"
 	"image"
 	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/benoitkugler/textlayout/fonts"
 	"github.com/benoitkugler/textlayout/fonts/truetype"
 	"github.com/benoitkugler/textlayout/harfbuzz"
-	"github.com/go-text/typesetting/shaping"
+	tfont "github.com/go-text/typesetting/font"
 	"golang.org/x/image/font"
 	"golang.org/x/image/math/fixed"
 
@@ -28,7 +31,45 @@ import (
 // Font implements the text.Shaper interface using a rich text
 // shaping engine.
 type Font struct {
-	font *truetype.Font
+	font   *truetype.Font
+	colors colorGlyphs
+
+	// load, if set, loads font and colors on first use.
+	load func() (*truetype.Font, colorGlyphs, error)
+	once sync.Once
+	err  error
+
+	// mu protects bitmaps.
+	mu      sync.Mutex
+	bitmaps map[bitmapKey]bitmap
+
+	// features are the OpenType features of an instance.
+	features []harfbuzz.Feature
+
+	// id identifies the font in the glyph ids of its paths.
+	id uint64
+}
+
+// lastFontID is the last id assigned to a Font.
+var lastFontID uint64
+
+// glyphID returns the id of the outline of a glyph at a size, for
+// clip.Path.Glyph.
+func (f *Font) glyphID(gid fonts.GID, ppem uint16) uint64 {
+	id := atomic.LoadUint64(&f.id)
+	if id == 0 {
+		atomic.CompareAndSwapUint64(&f.id, 0, atomic.AddUint64(&lastFontID, 1))
+		id = atomic.LoadUint64(&f.id)
+	}
+	return id<<40 | uint64(gid&0xffffff)<<16 | uint64(ppem)
+}
+
+// Resource is a source of font data, such as an *os.File or a
+// *bytes.Reader.
+type Resource interface {
+	io.Reader
+	io.ReaderAt
+	io.Seeker
 }
 
 // Parse constructs a Font from source bytes.
@@ -37,13 +78,166 @@ func Parse(src []byte) (*Font, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed parsing truetype font: %w", err)
 	}
-	return &Font{
+	f := &Font{
 		font: face,
-	}, nil
+	}
+	if pr, err := truetype.NewFontParser(bytes.NewReader(src)); err == nil {
+		f.colors = loadColorGlyphs(pr)
+	}
+	return f, nil
+}
+
+// Load returns a Font for the font at index of the OpenType font or
+// collection returned by src. Index is 0 for files that are not
+// collections. The font is loaded when it is first used; if that fails,
+// Layout returns the error and Shape draws nothing.
+func Load(src func() ([]byte, error), index int) *Font {
+	return &Font{
+		load: func() (*truetype.Font, colorGlyphs, error) {
+			data, err := src()
+			if err != nil {
+				return nil, nil, err
+			}
+			faces, err := truetype.Load(bytes.NewReader(data))
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed parsing truetype font: %w", err)
+			}
+			if index < 0 || index >= len(faces) {
+				return nil, nil, fmt.Errorf("font index %d out of range [0,%d)", index, len(faces))
+			}
+			var colors colorGlyphs
+			if prs, err := truetype.NewFontParsers(bytes.NewReader(data)); err == nil && index < len(prs) {
+				colors = loadColorGlyphs(prs[index])
+			}
+			return faces[index].(*truetype.Font), colors, nil
+		},
+	}
 }
 
-func (f *Font) Layout(ppem fixed.Int26_6, maxWidth int, lc system.Locale, txt io.RuneReader) ([]text.Line, error) {
-	return internal.Document(shaping.Shape, f.font, ppem, maxWidth, lc, txt), nil
+// Describe returns the typeface, style and weight of every font of an
+// OpenType font or collection, in collection order. Only the tables
+// needed for the descriptions are read.
+func Describe(r Resource) ([]text.Font, error) {
+	descs, err := truetype.ScanFont(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed scanning truetype font: %w", err)
+	}
+	fnts := make([]text.Font, len(descs))
+	for i, d := range descs {
+		style, weight, _ := d.Aspect()
+		fnt := text.Font{Typeface: text.Typeface(d.Family())}
+		if style == fonts.StyleItalic || style == fonts.StyleOblique {
+			fnt.Style = text.Italic
+		}
+		if weight != 0 {
+			fnt.Weight = text.Weight(weight) - 400
+		}
+		fnts[i] = fnt
+	}
+	return fnts, nil
+}
+
+// face returns the parsed font, loading it if necessary.
+func (f *Font) face() (*truetype.Font, error) {
+	f.once.Do(func() {
+		if f.load != nil {
+			f.font, f.colors, f.err = f.load()
+		}
+	})
+	return f.font, f.err
+}
+
+func (f *Font) Layout(ppem fixed.Int26_6, maxWidth int, lc system.Locale, params text.Parameters, txt io.RuneReader) ([]text.Line, error) {
+	return f.LayoutFallback(ppem, maxWidth, lc, params, txt, nil)
+}
+
+// LayoutFallback implements the text.FallbackFace interface. Fallback
+// faces that are not *Font are ignored, and fallback faces are only
+// loaded when needed for a rune.
+func (f *Font) LayoutFallback(ppem fixed.Int26_6, maxWidth int, lc system.Locale, params text.Parameters, txt io.RuneReader, fallbacks []text.Face) ([]text.Line, error) {
+	if _, err := f.face(); err != nil {
+		return nil, err
+	}
+	faces := []internal.Face{{Font: f.shapingFace, Face: f}}
+	for _, fb := range fallbacks {
+		if fb, ok := fb.(*Font); ok && fb != f {
+			faces = append(faces, internal.Face{Font: fb.shapingFace, Face: fb})
+		}
+	}
+	return internal.Document(internal.FeatureShaper(f.features), faces, ppem, maxWidth, lc, params, txt), nil
+}
+
+// Axes implements text.VariableFace.
+func (f *Font) Axes() []text.Axis {
+	fnt, err := f.face()
+	if err != nil {
+		return nil
+	}
+	var axes []text.Axis
+	for _, a := range fnt.Variations().Axis {
+		axes = append(axes, text.Axis{
+			Tag:     a.Tag.String(),
+			Min:     a.Minimum,
+			Default: a.Default,
+			Max:     a.Maximum,
+		})
+	}
+	return axes
+}
+
+// Instance implements text.VariableFace. The variations replace those of
+// f, and the features are added to those of f. The instance is loaded
+// with f.
+func (f *Font) Instance(variations []text.Variation, features []text.Feature) text.Face {
+	inst := &Font{
+		load: func() (*truetype.Font, colorGlyphs, error) {
+			fnt, err := f.face()
+			if err != nil || len(variations) == 0 {
+				return fnt, f.colors, err
+			}
+			var vars []truetype.Variation
+			for _, v := range variations {
+				if t, ok := tag(v.Tag); ok {
+					vars = append(vars, truetype.Variation{Tag: t, Value: v.Value})
+				}
+			}
+			// Vary a copy, because f may be in use.
+			varied := *fnt
+			truetype.SetVariations(&varied, vars)
+			return &varied, f.colors, nil
+		},
+		features: append([]harfbuzz.Feature(nil), f.features...),
+	}
+	for _, ft := range features {
+		t, ok := tag(ft.Tag)
+		if !ok {
+			continue
+		}
+		inst.features = append(inst.features, harfbuzz.Feature{
+			Tag:   t,
+			Value: ft.Value,
+			Start: harfbuzz.FeatureGlobalStart,
+			End:   harfbuzz.FeatureGlobalEnd,
+		})
+	}
+	return inst
+}
+
+// tag converts a tag of up to 4 characters, padded with spaces.
+func tag(s string) (truetype.Tag, bool) {
+	if s == "" || len(s) > 4 {
+		return 0, false
+	}
+	return truetype.MustNewTag(s + strings.Repeat(" ", 4-len(s))), true
+}
+
+// shapingFace returns the font for shaping, or nil if it failed to load.
+func (f *Font) shapingFace() tfont.Face {
+	fnt, err := f.face()
+	if err != nil {
+		return nil
+	}
+	return fnt
 }
 
 func (f *Font) Shape(ppem fixed.Int26_6, str text.Layout) clip.PathSpec {
@@ -52,7 +246,11 @@ func (f *Font) Shape(ppem fixed.Int26_6, str text.Layout) clip.PathSpec {
 
 func (f *Font) Metrics(ppem fixed.Int26_6) font.Metrics {
 	metrics := font.Metrics{}
-	font := harfbuzz.NewFont(f.font)
+	fnt, err := f.face()
+	if err != nil {
+		return metrics
+	}
+	font := harfbuzz.NewFont(fnt)
 	font.XScale = int32(ppem.Ceil()) << 6
 	font.YScale = font.XScale
 	// Use any horizontal direction.
@@ -72,28 +270,78 @@ func (f *Font) Metrics(ppem fixed.Int26_6) font.Metrics {
 	return metrics
 }
 
+// DecorationMetrics implements the text.DecorationFace interface, with the
+// positions and thicknesses of the post and OS/2 tables of the font.
+func (f *Font) DecorationMetrics(ppem fixed.Int26_6) text.DecorationMetrics {
+	m := text.DefaultDecorationMetrics(ppem)
+	fnt, err := f.face()
+	if err != nil {
+		return m
+	}
+	scale := float32(ppem) / float32(fnt.Upem())
+	metric := func(v *fixed.Int26_6, lm fonts.LineMetric, sign float32) {
+		if u, ok := fnt.LineMetric(lm); ok {
+			*v = fixed.Int26_6(sign * u * scale)
+		}
+	}
+	metric(&m.Underline, fonts.UnderlinePosition, -1)
+	metric(&m.UnderlineThickness, fonts.UnderlineThickness, 1)
+	if fnt.OS2 != nil {
+		metric(&m.Strikethrough, fonts.StrikethroughPosition, -1)
+		metric(&m.StrikethroughThickness, fonts.StrikethroughThickness, 1)
+	}
+	if m.UnderlineThickness <= 0 {
+		m.UnderlineThickness = ppem / 14
+	}
+	if m.StrikethroughThickness <= 0 {
+		m.StrikethroughThickness = m.UnderlineThickness
+	}
+	// The overline is drawn above the ascent.
+	m.Overline = -f.Metrics(ppem).Ascent
+	m.OverlineThickness = m.UnderlineThickness
+	return m
+}
+
 func textPath(ppem fixed.Int26_6, font *Font, str text.Layout) clip.PathSpec {
 	var lastPos f32.Point
 	var builder clip.Path
 	ops := new(op.Ops)
 	var x fixed.Int26_6
 	builder.Begin(ops)
-	rune := 0
 	ppemInt := ppem.Round()
 	ppem16 := uint16(ppemInt)
-	scaleFactor := float32(ppemInt) / float32(font.font.Upem())
 	for _, g := range str.Glyphs {
 		advance := g.XAdvance
-		outline, ok := font.font.GlyphData(g.ID, ppem16, ppem16).(fonts.GlyphOutline)
-		if !ok {
-			continue
-		}
-		// Move to glyph position.
+		// Glyph position.
 		pos := f32.Point{
 			X: float32(x)/64 - float32(g.XOffset)/64,
 			Y: -float32(g.YOffset) / 64,
 		}
+		x += advance
+		// Draw glyphs shaped with a fallback face with that face.
+		face := font
+		if f, ok := g.Face.(*Font); ok {
+			face = f
+		}
+		fnt, err := face.face()
+		if err != nil || face.isColorGlyph(g.ID) {
+			// Color glyphs are painted by ShapeColor.
+			continue
+		}
+		scaleFactor := float32(ppemInt) / float32(fnt.Upem())
+		var outline fonts.GlyphOutline
+		switch data := fnt.GlyphData(g.ID, ppem16, ppem16).(type) {
+		case fonts.GlyphOutline:
+			outline = data
+		case fonts.GlyphSVG:
+			// Draw the fallback outline of SVG glyphs.
+			outline = data.Outline
+		default:
+			continue
+		}
+		// Move to glyph position.
 		builder.Move(pos.Sub(lastPos))
+		builder.Glyph(face.glyphID(g.ID, ppem16), builder.Pos())
 		lastPos = pos
 		var lastArg f32.Point
 
@@ -131,8 +379,6 @@ func textPath(ppem fixed.Int26_6, font *Font, str text.Layout) clip.PathSpec {
 			}
 		}
 		lastPos = lastPos.Add(lastArg)
-		x += advance
-		rune++
 	}
 	return builder.End()
 }
@@ -1,13 +1,25 @@
 package opentype
 
 import (
+	"compress/gzip"
+	"encoding/binary"
+	"image/color"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
 	"strings"
 	"testing"
 
+	"eliasnaur.com/font/roboto/robotoregular"
+	"github.com/benoitkugler/textlayout/fonts"
 	"golang.org/x/image/font/gofont/goregular"
 	"golang.org/x/image/math/fixed"
 
 	"github.com/xiaoshengduan/gio-fly/io/system"
+	"github.com/xiaoshengduan/gio-fly/op"
+	"github.com/xiaoshengduan/gio-fly/text"
 )
 
 var english = system.Locale{
@@ -23,7 +35,7 @@ func TestEmptyString(t *testing.T) {
 
 	ppem := fixed.I(200)
 
-	lines, err := face.Layout(ppem, 2000, english, strings.NewReader(""))
+	lines, err := face.Layout(ppem, 2000, english, text.Parameters{}, strings.NewReader(""))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -43,3 +55,269 @@ func TestEmptyString(t *testing.T) {
 		t.Errorf("got bounds %+v for empty string; expected %+v", got, exp)
 	}
 }
+
+func TestLayoutFallback(t *testing.T) {
+	only1, only2 := loadFont(t, "only1.ttf.gz"), loadFont(t, "only2.ttf.gz")
+	ppem := fixed.I(200)
+
+	lines, err := only1.LayoutFallback(ppem, 2000, english, text.Parameters{}, strings.NewReader("1221"), []text.Face{only2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 1 {
+		t.Fatalf("got %d lines, want 1", len(lines))
+	}
+	want := []text.Face{only1, only2, only2, only1}
+	glyphs := lines[0].Layout.Glyphs
+	if len(glyphs) != len(want) {
+		t.Fatalf("got %d glyphs, want %d", len(glyphs), len(want))
+	}
+	for i, g := range glyphs {
+		if g.Face != want[i] {
+			t.Errorf("glyph %d: shaped with the wrong face", i)
+		}
+		if g.ID == 0 {
+			t.Errorf("glyph %d: missing glyph", i)
+		}
+	}
+	if got := lines[0].Layout.Clusters; len(got) != 4 {
+		t.Errorf("got %d clusters, want 4", len(got))
+	}
+	// Shape draws the glyphs with their faces.
+	only1.Shape(ppem, lines[0].Layout)
+
+	// The Cache falls back to the other registered faces.
+	cache := text.NewCache([]text.FontFace{
+		{Font: text.Font{Typeface: "Only1"}, Face: only1},
+		{Font: text.Font{Typeface: "Only2"}, Face: only2},
+	})
+	lines = cache.LayoutString(text.Font{Typeface: "Only2"}, ppem, 2000, english, text.Parameters{}, "12")
+	glyphs = lines[0].Layout.Glyphs
+	if len(glyphs) != 2 || glyphs[0].Face != only1 || glyphs[1].Face != only2 {
+		t.Errorf("Cache didn't fall back to the registered faces")
+	}
+}
+
+func TestLayoutParameters(t *testing.T) {
+	face, err := Parse(goregular.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ppem := fixed.I(20)
+	layout := func(params text.Parameters) text.Line {
+		t.Helper()
+		lines, err := face.Layout(ppem, 2000, english, params, strings.NewReader("hello"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return lines[0]
+	}
+	plain := layout(text.Parameters{})
+	spaced := layout(text.Parameters{LetterSpacing: fixed.I(2)})
+	if got, exp := spaced.Width, plain.Width+fixed.I(2*5); got != exp {
+		t.Errorf("got width %v with letter spacing, expected %v", got, exp)
+	}
+	tall := layout(text.Parameters{LineHeight: fixed.I(40)})
+	if got, exp := tall.Ascent+tall.Descent, fixed.I(40); got != exp {
+		t.Errorf("got line height %v, expected %v", got, exp)
+	}
+	if got, exp := tall.Ascent-plain.Ascent, tall.Descent-plain.Descent; got-exp > 1 || exp-got > 1 {
+		t.Errorf("extra line height split unevenly: %v above, %v below", got, exp)
+	}
+	scaled := layout(text.Parameters{LineHeightScale: 2})
+	if got, exp := scaled.Ascent+scaled.Descent, (plain.Ascent+plain.Descent)*2; got-exp > 1 || exp-got > 1 {
+		t.Errorf("got scaled line height %v, expected %v", got, exp)
+	}
+
+	m := face.DecorationMetrics(ppem)
+	if m.UnderlineThickness <= 0 || m.StrikethroughThickness <= 0 || m.OverlineThickness <= 0 {
+		t.Errorf("decoration thickness not positive: %+v", m)
+	}
+	if m.Underline <= 0 || m.Strikethrough >= 0 || m.Overline >= m.Strikethrough {
+		t.Errorf("decorations out of order: %+v", m)
+	}
+}
+
+func TestInstanceFeatures(t *testing.T) {
+	face, err := Parse(robotoregular.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if axes := face.Axes(); len(axes) != 0 {
+		t.Errorf("static font has axes %v", axes)
+	}
+	glyphs := func(f text.Face) int {
+		t.Helper()
+		lines, err := f.Layout(fixed.I(20), 2000, english, text.Parameters{}, strings.NewReader("fit"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(lines[0].Layout.Glyphs)
+	}
+	if got := glyphs(face); got != 2 {
+		t.Errorf("got %d glyphs for ligature, expected 2", got)
+	}
+	noLiga := face.Instance(nil, []text.Feature{{Tag: "liga", Value: 0}})
+	if got := glyphs(noLiga); got != 3 {
+		t.Errorf("got %d glyphs without ligatures, expected 3", got)
+	}
+	if got := glyphs(face); got != 2 {
+		t.Errorf("instance changed the features of its font")
+	}
+	// Invalid tags are ignored.
+	if got := glyphs(face.Instance(nil, []text.Feature{{Tag: "ligatures"}})); got != 2 {
+		t.Errorf("got %d glyphs with an invalid feature, expected 2", got)
+	}
+}
+
+func loadFont(t *testing.T, name string) *Font {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	r, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	face, err := Parse(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return face
+}
+
+func TestColorGlyphs(t *testing.T) {
+	plain, err := Parse(goregular.TTF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ppem := fixed.I(20)
+	gid := func(r string) uint16 {
+		lines, err := plain.Layout(ppem, 2000, english, text.Parameters{}, strings.NewReader(r))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return uint16(lines[0].Layout.Glyphs[0].ID)
+	}
+	a, b := gid("A"), gid("B")
+	red := color.NRGBA{R: 0xff, A: 0xff}
+
+	be := binary.BigEndian
+	cpal := make([]byte, 14+4)
+	be.PutUint16(cpal[2:], 1)  // numPaletteEntries
+	be.PutUint16(cpal[4:], 1)  // numPalettes
+	be.PutUint16(cpal[6:], 1)  // numColorRecords
+	be.PutUint32(cpal[8:], 14) // colorRecordsArrayOffset
+	copy(cpal[14:], []byte{0x00, 0x00, 0xff, 0xff})
+	colr := make([]byte, 14+6+2*4)
+	be.PutUint16(colr[2:], 1)       // numBaseGlyphRecords
+	be.PutUint32(colr[4:], 14)      // baseGlyphRecordsOffset
+	be.PutUint32(colr[8:], 14+6)    // layerRecordsOffset
+	be.PutUint16(colr[12:], 2)      // numLayerRecords
+	be.PutUint16(colr[14:], a)      // glyphID
+	be.PutUint16(colr[16:], 0)      // firstLayerIndex
+	be.PutUint16(colr[18:], 2)      // numLayers
+	be.PutUint16(colr[20:], a)      // layer glyph
+	be.PutUint16(colr[22:], 0)      // palette index
+	be.PutUint16(colr[24:], b)      // layer glyph
+	be.PutUint16(colr[26:], 0xffff) // text color
+
+	palette, err := parseCPAL(cpal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(palette) != 1 || palette[0] != red {
+		t.Fatalf("got palette %v, want [%v]", palette, red)
+	}
+	glyphs, err := parseCOLR(colr, palette)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []colorLayer{
+		{gid: fonts.GID(a), color: red},
+		{gid: fonts.GID(b), fg: true},
+	}
+	if got := glyphs[fonts.GID(a)]; !reflect.DeepEqual(got, want) {
+		t.Errorf("got layers %v, want %v", got, want)
+	}
+	if _, err := parseCOLR(colr[:20], palette); err == nil {
+		t.Error("parsed truncated COLR table")
+	}
+
+	face, err := Parse(withTables(goregular.TTF, map[string][]byte{"COLR": colr, "CPAL": cpal}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	shape := func(str string) op.CallOp {
+		lines, err := face.Layout(ppem, 2000, english, text.Parameters{}, strings.NewReader(str))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return face.ShapeColor(ppem, lines[0].Layout, color.NRGBA{A: 0xff})
+	}
+	if shape("A") == (op.CallOp{}) {
+		t.Error("no color glyphs painted for a COLR glyph")
+	}
+	if shape("C") != (op.CallOp{}) {
+		t.Error("color glyphs painted for a plain glyph")
+	}
+	// The Cache shapes color glyphs with the face.
+	cache := text.NewCache([]text.FontFace{{Face: face}})
+	lines := cache.LayoutString(text.Font{}, ppem, 2000, english, text.Parameters{}, "A")
+	black := cache.ShapeColor(text.Font{}, ppem, lines[0].Layout, color.NRGBA{A: 0xff})
+	if black == (op.CallOp{}) {
+		t.Error("Cache painted no color glyphs for a COLR glyph")
+	}
+	if cache.ShapeColor(text.Font{}, ppem, lines[0].Layout, color.NRGBA{A: 0xff}) != black {
+		t.Error("Cache did not reuse the color glyphs")
+	}
+	if cache.ShapeColor(text.Font{}, ppem, lines[0].Layout, red) == black {
+		t.Error("Cache reused the color glyphs of another text color")
+	}
+	if plain.ShapeColor(ppem, text.Layout{}, color.NRGBA{A: 0xff}) != (op.CallOp{}) {
+		t.Error("color glyphs painted for a font without color glyphs")
+	}
+}
+
+// withTables returns a copy of an OpenType font with tables added.
+func withTables(font []byte, tables map[string][]byte) []byte {
+	be := binary.BigEndian
+	n := int(be.Uint16(font[4:]))
+	shift := 16 * len(tables)
+	type record struct {
+		tag  string
+		off  int
+		data []byte
+	}
+	var records []record
+	for i := 0; i < n; i++ {
+		rec := font[12+16*i:]
+		off, length := int(be.Uint32(rec[8:])), int(be.Uint32(rec[12:]))
+		records = append(records, record{tag: string(rec[:4]), off: off + shift, data: font[off : off+length]})
+	}
+	out := make([]byte, len(font)+shift)
+	copy(out, font[:12])
+	copy(out[12+16*(n+len(tables)):], font[12+16*n:])
+	for tag, data := range tables {
+		for len(out)%4 != 0 {
+			out = append(out, 0)
+		}
+		records = append(records, record{tag: tag, off: len(out), data: data})
+		out = append(out, data...)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].tag < records[j].tag })
+	be.PutUint16(out[4:], uint16(len(records)))
+	for i, r := range records {
+		rec := out[12+16*i:]
+		copy(rec, r.tag)
+		be.PutUint32(rec[8:], uint32(r.off))
+		be.PutUint32(rec[12:], uint32(len(r.data)))
+	}
+	return out
+}
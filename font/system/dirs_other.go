@@ -0,0 +1,14 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+//go:build !((linux && !android) || freebsd || openbsd)
+// +build !linux android
+// +build !freebsd
+// +build !openbsd
+
+package system
+
+// Dirs returns the font directories of the system. It returns no
+// directories on this platform.
+func Dirs() []string {
+	return nil
+}
@@ -0,0 +1,56 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+//go:build (linux && !android) || freebsd || openbsd
+// +build linux,!android freebsd openbsd
+
+package system
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Dirs returns the font directories of the system, in order of priority:
+// the fonts directories of $XDG_DATA_HOME (~/.local/share by default) and
+// of $XDG_DATA_DIRS (/usr/local/share:/usr/share by default), ~/.fonts
+// and /usr/share/fonts.
+func Dirs() []string {
+	return dirs(os.Getenv)
+}
+
+func dirs(getenv func(string) string) []string {
+	home := getenv("HOME")
+	dataHome := getenv("XDG_DATA_HOME")
+	if dataHome == "" && home != "" {
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+	dataDirs := getenv("XDG_DATA_DIRS")
+	if dataDirs == "" {
+		dataDirs = "/usr/local/share:/usr/share"
+	}
+	var data []string
+	if dataHome != "" {
+		data = append(data, dataHome)
+	}
+	data = append(data, filepath.SplitList(dataDirs)...)
+	var dirs []string
+	for _, d := range data {
+		if d != "" {
+			dirs = append(dirs, filepath.Join(d, "fonts"))
+		}
+	}
+	if home != "" {
+		dirs = append(dirs, filepath.Join(home, ".fonts"))
+	}
+	dirs = append(dirs, "/usr/share/fonts")
+	// Remove duplicates.
+	seen := make(map[string]bool)
+	unique := dirs[:0]
+	for _, d := range dirs {
+		if !seen[d] {
+			seen[d] = true
+			unique = append(unique, d)
+		}
+	}
+	return unique
+}
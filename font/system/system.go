@@ -0,0 +1,189 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+// Package system finds the fonts installed on the system.
+//
+// The fonts are typically added after the fonts of another collection,
+// such as gofont.Collection, so that the first font of that collection
+// remains the default font of text.NewCache. The system fonts then
+// provide the glyphs for runes missing from the other fonts.
+package system
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xiaoshengduan/gio-fly/font/opentype"
+	"github.com/xiaoshengduan/gio-fly/text"
+)
+
+// index caches the descriptions of font files between scans.
+type index struct {
+	Version int
+	Files   map[string]indexFile
+}
+
+// indexFile is the description of a font file.
+type indexFile struct {
+	// Size and ModTime identify the version of the file.
+	Size    int64
+	ModTime int64
+	// Fonts are the fonts of the file, in collection order. Fonts that
+	// can't be described have an empty Typeface.
+	Fonts []text.Font
+}
+
+// indexVersion is the version of the index format and of the font
+// descriptions.
+const indexVersion = 1
+
+// Collection returns the fonts of the font directories returned by Dirs.
+// The descriptions of the font files are cached in the user cache
+// directory.
+func Collection() ([]text.FontFace, error) {
+	var idx string
+	if dir, err := os.UserCacheDir(); err == nil {
+		idx = filepath.Join(dir, "gio", "fonts.json")
+	}
+	return Scan(idx, Dirs()...)
+}
+
+// Scan returns the fonts of the OpenType font files and collections in
+// dirs and their subdirectories, in the order of dirs. Font files have
+// the extensions .ttf, .otf, .ttc or .otc. Missing or unreadable
+// directories and files are skipped, as are fonts without a family name
+// and fonts described by an earlier font.
+//
+// The font files are only read for their descriptions, and the fonts are
+// parsed when they are first used. If idx is not empty, it names a file
+// for caching the descriptions between scans, so that only new and
+// changed font files are read. Scan returns the fonts along with any
+// error writing the cache file.
+func Scan(idx string, dirs ...string) ([]text.FontFace, error) {
+	old := readIndex(idx)
+	cur := &index{Version: indexVersion, Files: make(map[string]indexFile)}
+	changed := false
+	seen := make(map[text.Font]bool)
+	var faces []text.FontFace
+	for _, dir := range dirs {
+		filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if d != nil && d.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() || !isFontFile(path) {
+				return nil
+			}
+			if _, ok := cur.Files[path]; ok {
+				// Directories overlap.
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			f, ok := old.Files[path]
+			if !ok || f.Size != info.Size() || f.ModTime != info.ModTime().UnixNano() {
+				f = indexFile{
+					Size:    info.Size(),
+					ModTime: info.ModTime().UnixNano(),
+					Fonts:   describe(path),
+				}
+				changed = true
+			}
+			cur.Files[path] = f
+			for i, fnt := range f.Fonts {
+				if fnt.Typeface == "" || seen[fnt] {
+					continue
+				}
+				seen[fnt] = true
+				faces = append(faces, text.FontFace{Font: fnt, Face: load(path, i)})
+			}
+			return nil
+		})
+	}
+	if idx == "" || !changed && len(cur.Files) == len(old.Files) {
+		return faces, nil
+	}
+	return faces, writeIndex(idx, cur)
+}
+
+// isFontFile reports whether the file name has the extension of an
+// OpenType font or collection.
+func isFontFile(name string) bool {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".ttf", ".otf", ".ttc", ".otc":
+		return true
+	}
+	return false
+}
+
+// describe returns the descriptions of the fonts of a font file.
+func describe(path string) []text.Font {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	fonts, err := opentype.Describe(f)
+	if err != nil {
+		return nil
+	}
+	return fonts
+}
+
+// load returns a face for the font at index of a font file, parsed on
+// first use.
+func load(path string, index int) text.Face {
+	return opentype.Load(func() ([]byte, error) {
+		return os.ReadFile(path)
+	}, index)
+}
+
+// readIndex reads the index file name. It returns an empty index if the
+// file is missing, invalid or of another version.
+func readIndex(name string) *index {
+	empty := &index{Version: indexVersion}
+	if name == "" {
+		return empty
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return empty
+	}
+	idx := new(index)
+	if err := json.Unmarshal(data, idx); err != nil || idx.Version != indexVersion {
+		return empty
+	}
+	return idx
+}
+
+// writeIndex replaces the index file name with idx.
+func writeIndex(name string, idx *index) error {
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), name)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
@@ -0,0 +1,170 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package system
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goitalic"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/math/fixed"
+
+	"github.com/xiaoshengduan/gio-fly/io/system"
+	"github.com/xiaoshengduan/gio-fly/text"
+)
+
+func TestScan(t *testing.T) {
+	dir := t.TempDir()
+	dir1, dir2 := filepath.Join(dir, "1"), filepath.Join(dir, "2")
+	writeFile(t, filepath.Join(dir1, "sub", "GoRegular.TTF"), goregular.TTF)
+	writeFile(t, filepath.Join(dir1, "gobold.ttf"), gobold.TTF)
+	writeFile(t, filepath.Join(dir1, "broken.ttf"), []byte("not a font"))
+	writeFile(t, filepath.Join(dir1, "readme.txt"), []byte("not a font"))
+	writeFile(t, filepath.Join(dir2, "dup.ttf"), goregular.TTF)
+	writeFile(t, filepath.Join(dir2, "go.ttc"), collection(goitalic.TTF, gomono.TTF))
+	idx := filepath.Join(dir, "cache", "fonts.json")
+
+	faces, err := Scan(idx, dir1, dir2, filepath.Join(dir, "missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []text.Font{
+		// Go Bold declares the weight of SemiBold.
+		{Typeface: "Go", Weight: text.SemiBold},
+		{Typeface: "Go"},
+		{Typeface: "Go", Style: text.Italic},
+		{Typeface: "Go Mono"},
+	}
+	var got []text.Font
+	for _, f := range faces {
+		got = append(got, f.Font)
+	}
+	if !equalFonts(got, want) {
+		t.Fatalf("got fonts %v, want %v", got, want)
+	}
+
+	// The second font of the collection lays out text.
+	ppem := fixed.I(20)
+	lines, err := faces[3].Face.Layout(ppem, 1000, system.Locale{}, text.Parameters{}, strings.NewReader("abc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(lines[0].Layout.Glyphs); n != 3 {
+		t.Errorf("got %d glyphs, want 3", n)
+	}
+	// Fonts are loaded on first use.
+	if err := os.Remove(filepath.Join(dir1, "gobold.ttf")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := faces[0].Face.Layout(ppem, 1000, system.Locale{}, text.Parameters{}, strings.NewReader("abc")); err == nil {
+		t.Error("font of removed file laid out text")
+	}
+
+	// Unchanged files are described by the index.
+	if err := os.Remove(filepath.Join(dir2, "dup.ttf")); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir1, "sub", "GoRegular.TTF")
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, path, make([]byte, len(goregular.TTF)))
+	if err := os.Chtimes(path, time.Now(), info.ModTime()); err != nil {
+		t.Fatal(err)
+	}
+	faces, err = Scan(idx, dir1, dir2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = got[:0]
+	for _, f := range faces {
+		got = append(got, f.Font)
+	}
+	if !equalFonts(got, want[1:]) {
+		t.Errorf("got fonts %v from index, want %v", got, want[1:])
+	}
+	// Without the index, the overwritten file is no longer a font.
+	faces, err = Scan("", dir1, dir2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = got[:0]
+	for _, f := range faces {
+		got = append(got, f.Font)
+	}
+	if want := want[2:]; !equalFonts(got, want) {
+		t.Errorf("got fonts %v, want %v", got, want)
+	}
+}
+
+func TestDirs(t *testing.T) {
+	env := map[string]string{
+		"HOME":          "/home/gopher",
+		"XDG_DATA_DIRS": "/opt/share::/usr/share",
+	}
+	got := dirs(func(k string) string { return env[k] })
+	want := []string{
+		"/home/gopher/.local/share/fonts",
+		"/opt/share/fonts",
+		"/usr/share/fonts",
+		"/home/gopher/.fonts",
+	}
+	if strings.Join(got, ":") != strings.Join(want, ":") {
+		t.Errorf("got directories %v, want %v", got, want)
+	}
+}
+
+func equalFonts(a, b []text.Font) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func writeFile(t *testing.T, name string, data []byte) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// collection returns an OpenType collection of fonts.
+func collection(fonts ...[]byte) []byte {
+	be := binary.BigEndian
+	header := 12 + 4*len(fonts)
+	data := make([]byte, header)
+	copy(data, "ttcf")
+	be.PutUint32(data[4:], 0x00010000)
+	be.PutUint32(data[8:], uint32(len(fonts)))
+	for i, f := range fonts {
+		for len(data)%4 != 0 {
+			data = append(data, 0)
+		}
+		off := len(data)
+		be.PutUint32(data[12+4*i:], uint32(off))
+		data = append(data, f...)
+		// Make the table offsets relative to the collection.
+		font := data[off:]
+		for j := 0; j < int(be.Uint16(font[4:])); j++ {
+			rec := font[12+16*j:]
+			be.PutUint32(rec[8:], be.Uint32(rec[8:])+uint32(off))
+		}
+	}
+	return data
+}
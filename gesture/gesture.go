@@ -95,7 +95,9 @@ type ClickEvent struct {
 	Source    pointer.Source
 	Modifiers key.Modifiers
 	// NumClicks records successive clicks occurring
-	// within a short duration of each other.
+	// within a short duration of each other. For TypePress
+	// events, NumClicks is the number of clicks the press
+	// completes if released immediately.
 	NumClicks int
 }
 
@@ -123,6 +125,31 @@ type Scroll struct {
 	last      int
 	// Leftover scroll.
 	scroll float32
+
+	// y tracks the vertical axis of Both scrolls.
+	y struct {
+		estimator fling.Extrapolation
+		flinger   fling.Animation
+		last      int
+		scroll    float32
+	}
+	// pinch tracks a two finger zoom gesture.
+	pinch struct {
+		active bool
+		pid    pointer.ID
+		// pos are the positions of the first and second finger.
+		pos [2]f32.Point
+	}
+}
+
+// Pan is the result of a scroll gesture along both axes.
+type Pan struct {
+	// Distance is the distance scrolled.
+	Distance image.Point
+	// Zoom is the factor to scale by. It is 1 if no zoom occurred.
+	Zoom float32
+	// Center is the focal point of the zoom.
+	Center f32.Point
 }
 
 type ScrollState uint8
@@ -159,6 +186,9 @@ const (
 
 const touchSlop = unit.Dp(3)
 
+// zoomScrollDistance is the scroll distance that zooms by a factor of e.
+const zoomScrollDistance = unit.Dp(200)
+
 // Add the handler to the operation list to receive click events.
 func (c *Click) Add(ops *op.Ops) {
 	pointer.InputOp{
@@ -224,7 +254,11 @@ func (c *Click) Events(q event.Queue) []ClickEvent {
 				break
 			}
 			c.pressed = true
-			events = append(events, ClickEvent{Type: TypePress, Position: e.Position.Round(), Source: e.Source, Modifiers: e.Modifiers})
+			clicks := 1
+			if e.Time-c.clickedAt < doubleClickDuration {
+				clicks = c.clicks + 1
+			}
+			events = append(events, ClickEvent{Type: TypePress, Position: e.Position.Round(), Source: e.Source, Modifiers: e.Modifiers, NumClicks: clicks})
 		case pointer.Leave:
 			if !c.pressed {
 				c.pid = e.PointerID
@@ -258,7 +292,7 @@ func (s *Scroll) Add(ops *op.Ops, bounds image.Rectangle) {
 		ScrollBounds: bounds,
 	}
 	oph.Add(ops)
-	if s.flinger.Active() {
+	if s.flinger.Active() || s.y.flinger.Active() {
 		op.InvalidateOp{}.Add(ops)
 	}
 }
@@ -266,6 +300,13 @@ func (s *Scroll) Add(ops *op.Ops, bounds image.Rectangle) {
 // Stop any remaining fling movement.
 func (s *Scroll) Stop() {
 	s.flinger = fling.Animation{}
+	s.y.flinger = fling.Animation{}
+}
+
+// FlingDistance returns the distance left to scroll by the fling in
+// progress, if any.
+func (s *Scroll) FlingDistance() int {
+	return int(s.flinger.Distance())
 }
 
 // Scroll detects the scrolling distance from the available events and
@@ -343,6 +384,143 @@ func (s *Scroll) Scroll(cfg unit.Metric, q event.Queue, t time.Time, axis Axis)
 	return total
 }
 
+// Scroll2D is like Scroll, but detects scrolling along both axes. If zoom
+// is set, Scroll2D also detects zooming by pinching with two fingers, or
+// by scrolling with the Shortcut modifier.
+func (s *Scroll) Scroll2D(cfg unit.Metric, q event.Queue, t time.Time, zoom bool) Pan {
+	pan := Pan{Zoom: 1}
+	if s.axis != Both {
+		s.axis = Both
+		return pan
+	}
+	for _, evt := range q.Events(s) {
+		e, ok := evt.(pointer.Event)
+		if !ok {
+			continue
+		}
+		switch e.Type {
+		case pointer.Press:
+			if s.dragging {
+				if zoom && !s.pinch.active && e.Source == pointer.Touch && e.PointerID != s.pid {
+					s.pinch.active = true
+					s.pinch.pid = e.PointerID
+					s.pinch.pos[1] = e.Position
+					s.grab = true
+				}
+				break
+			}
+			// Only scroll on touch drags, or on Android where mice
+			// drags also scroll by convention.
+			if e.Source != pointer.Touch && runtime.GOOS != "android" {
+				break
+			}
+			s.Stop()
+			s.estimator = fling.Extrapolation{}
+			s.y.estimator = fling.Extrapolation{}
+			s.last = int(math.Round(float64(e.Position.X)))
+			s.y.last = int(math.Round(float64(e.Position.Y)))
+			s.estimator.Sample(e.Time, e.Position.X)
+			s.y.estimator.Sample(e.Time, e.Position.Y)
+			s.pinch.pos[0] = e.Position
+			s.dragging = true
+			s.pid = e.PointerID
+		case pointer.Release:
+			if s.pinch.active && (e.PointerID == s.pid || e.PointerID == s.pinch.pid) {
+				// Lifting a finger ends the pinch, without fling.
+				s.pinch.active = false
+				s.dragging = false
+				s.grab = false
+				break
+			}
+			if s.pid != e.PointerID {
+				break
+			}
+			slop := float32(cfg.Dp(touchSlop))
+			if f := s.estimator.Estimate(); f.Distance < -slop || f.Distance > slop {
+				s.flinger.Start(cfg, t, f.Velocity)
+			}
+			if f := s.y.estimator.Estimate(); f.Distance < -slop || f.Distance > slop {
+				s.y.flinger.Start(cfg, t, f.Velocity)
+			}
+			s.dragging = false
+			s.grab = false
+		case pointer.Cancel:
+			s.dragging = false
+			s.grab = false
+			s.pinch.active = false
+		case pointer.Scroll:
+			if zoom && e.Modifiers.Contain(key.ModShortcut) {
+				pan.Zoom *= float32(math.Exp(-float64(e.Scroll.Y) / float64(cfg.Dp(zoomScrollDistance))))
+				pan.Center = e.Position
+				break
+			}
+			pan.Distance = pan.Distance.Add(s.accumulate(e.Scroll))
+		case pointer.Drag:
+			if !s.dragging {
+				continue
+			}
+			if s.pinch.active {
+				var i int
+				switch e.PointerID {
+				case s.pid:
+					i = 0
+				case s.pinch.pid:
+					i = 1
+				default:
+					continue
+				}
+				old := s.pinch.pos
+				s.pinch.pos[i] = e.Position
+				oldMid := old[0].Add(old[1]).Mul(.5)
+				mid := s.pinch.pos[0].Add(s.pinch.pos[1]).Mul(.5)
+				if d := distance(old[0], old[1]); d > 0 {
+					pan.Zoom *= distance(s.pinch.pos[0], s.pinch.pos[1]) / d
+				}
+				pan.Center = mid
+				pan.Distance = pan.Distance.Add(s.accumulate(oldMid.Sub(mid)))
+				continue
+			}
+			if s.pid != e.PointerID {
+				continue
+			}
+			s.pinch.pos[0] = e.Position
+			s.estimator.Sample(e.Time, e.Position.X)
+			s.y.estimator.Sample(e.Time, e.Position.Y)
+			x := int(math.Round(float64(e.Position.X)))
+			y := int(math.Round(float64(e.Position.Y)))
+			dist := image.Pt(s.last-x, s.y.last-y)
+			if e.Priority < pointer.Grabbed {
+				slop := cfg.Dp(touchSlop)
+				if dist.X*dist.X+dist.Y*dist.Y >= slop*slop {
+					s.grab = true
+				}
+			} else {
+				s.last, s.y.last = x, y
+				pan.Distance = pan.Distance.Add(dist)
+			}
+		}
+	}
+	pan.Distance.X += s.flinger.Tick(t)
+	pan.Distance.Y += s.y.flinger.Tick(t)
+	return pan
+}
+
+// accumulate adds d to the leftover scroll, and returns the whole pixels
+// to scroll.
+func (s *Scroll) accumulate(d f32.Point) image.Point {
+	s.scroll += d.X
+	s.y.scroll += d.Y
+	ix, iy := int(s.scroll), int(s.y.scroll)
+	s.scroll -= float32(ix)
+	s.y.scroll -= float32(iy)
+	return image.Pt(ix, iy)
+}
+
+func distance(a, b f32.Point) float32 {
+	d := a.Sub(b)
+	return float32(math.Hypot(float64(d.X), float64(d.Y)))
+}
+
 func (s *Scroll) val(p f32.Point) float32 {
 	if s.axis == Horizontal {
 		return p.X
@@ -354,7 +532,7 @@ func (s *Scroll) val(p f32.Point) float32 {
 // State reports the scroll state.
 func (s *Scroll) State() ScrollState {
 	switch {
-	case s.flinger.Active():
+	case s.flinger.Active(), s.y.flinger.Active():
 		return StateFlinging
 	case s.dragging:
 		return StateDragging
@@ -439,6 +617,8 @@ func (a Axis) String() string {
 		return "Horizontal"
 	case Vertical:
 		return "Vertical"
+	case Both:
+		return "Both"
 	default:
 		panic("invalid Axis")
 	}
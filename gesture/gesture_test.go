@@ -4,6 +4,7 @@ package gesture
 
 import (
 	"image"
+	"reflect"
 	"testing"
 	"time"
 
@@ -90,6 +91,29 @@ func TestMouseClicks(t *testing.T) {
 	}
 }
 
+func TestMousePressNumClicks(t *testing.T) {
+	var click Click
+	var ops op.Ops
+	click.Add(&ops)
+
+	var r router.Router
+	r.Frame(&ops)
+	r.Queue(mouseClickEvents(
+		100*time.Millisecond,
+		100*time.Millisecond+doubleClickDuration/2,
+		100*time.Millisecond+doubleClickDuration)...)
+
+	var presses []int
+	for _, ev := range click.Events(&r) {
+		if ev.Type == TypePress {
+			presses = append(presses, ev.NumClicks)
+		}
+	}
+	if got, want := presses, []int{1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got press clicks %v, expected %v", got, want)
+	}
+}
+
 func mouseClickEvents(times ...time.Duration) []event.Event {
 	press := pointer.Event{
 		Type:    pointer.Press,
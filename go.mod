@@ -12,6 +12,5 @@ require (
 	golang.org/x/exp/shiny v0.0.0-20220827204233-334a2380cb91
 	golang.org/x/image v0.0.0-20220722155232-062f8c9fd539
 	golang.org/x/sys v0.0.0-20220825204002-c680a09ffe64
+	golang.org/x/text v0.3.7
 )
-
-require golang.org/x/text v0.3.7 // indirect
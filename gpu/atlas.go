@@ -0,0 +1,278 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package gpu
+
+import (
+	"image"
+	"math"
+
+	"github.com/xiaoshengduan/gio-fly/gpu/internal/driver"
+	"github.com/xiaoshengduan/gio-fly/internal/f32"
+)
+
+// glyphAtlas caches the coverage of the glyphs of paths marked with
+// clip.PathSpec.Atlas between frames, such as the glyphs of small text.
+// A glyph outline is stenciled into the atlas the first time it is drawn
+// at a subpixel position, and every glyph is drawn as a textured quad
+// covered by the atlas, skipping the stencil pass.
+//
+// Space is never freed from a full atlas. Instead, the atlas is cleared
+// and filled again with the glyphs of the frame.
+type glyphAtlas struct {
+	ctx     driver.Device
+	packer  packer
+	pages   []atlasPage
+	entries map[atlasKey]*atlasEntry
+	// pending are the glyphs to stencil into the atlas this frame.
+	pending []*pathOp
+}
+
+type atlasPage struct {
+	fbo stencilFBO
+	// clear reports whether the page must be cleared before use.
+	clear bool
+}
+
+// atlasKey identifies the coverage of a glyph outline at a subpixel
+// position. The outline id of clip.Path.Glyph covers its font, glyph
+// index and size.
+type atlasKey struct {
+	glyph uint64
+	sub   image.Point
+}
+
+type atlasEntry struct {
+	place placement
+	// rect is the area of the coverage, relative to the integer part of
+	// the glyph position.
+	rect image.Rectangle
+	used bool
+}
+
+const (
+	// atlasSubpixels is the number of subpixel positions of a path
+	// in each direction.
+	atlasSubpixels = 4
+	// atlasPageSize is the size of an atlas page.
+	atlasPageSize = 1024
+	// atlasMaxPages is the maximum number of atlas pages.
+	atlasMaxPages = 4
+	// atlasMaxHeight is the height of the tallest glyph in the atlas.
+	// Taller glyphs are drawn as vector paths.
+	atlasMaxHeight = 128
+)
+
+func newGlyphAtlas(ctx driver.Device) *glyphAtlas {
+	size := atlasPageSize
+	if max := ctx.Caps().MaxTextureSize; size > max {
+		size = max
+	}
+	a := &glyphAtlas{
+		ctx:     ctx,
+		entries: make(map[atlasKey]*atlasEntry),
+	}
+	a.packer.maxDims = image.Pt(size, size)
+	return a
+}
+
+// place the coverage of the glyphs of atlas paths in the atlas,
+// stenciling the missing ones later in stencil. The clip and placement of
+// the glyphs are updated to refer to their coverage in the atlas. Glyphs
+// that don't fit are added to the paths of d, to be drawn as vector paths.
+func (a *glyphAtlas) place(d *drawOps) {
+	a.pending = a.pending[:0]
+	glyphs := d.glyphOps
+	reset := false
+	for i := 0; i < len(glyphs); i++ {
+		p := glyphs[i]
+		if p.clip.Empty() || p.glyphData == nil {
+			// The glyph is not drawn, or drawn as a vector path.
+			continue
+		}
+		k, rect, off := atlasPosition(p)
+		e, exists := a.entries[k]
+		if !exists {
+			if rect.Dx() > a.packer.maxDims.X || rect.Dy() > atlasMaxHeight {
+				a.vector(d, p)
+				continue
+			}
+			place, ok := a.add(rect.Size())
+			if !ok {
+				if reset {
+					// Not even the glyphs of this frame fit.
+					a.vector(d, p)
+					continue
+				}
+				// Start over with an empty atlas.
+				a.reset()
+				reset = true
+				a.pending = a.pending[:0]
+				for _, p := range glyphs {
+					p.cached = false
+				}
+				i = -1
+				continue
+			}
+			e = &atlasEntry{place: place, rect: rect}
+			a.entries[k] = e
+			a.pending = append(a.pending, p)
+		}
+		e.used = true
+		p.cached = true
+		p.place = e.place
+		p.clip = e.rect.Add(off)
+	}
+}
+
+// vector adds the glyph p to the paths of d, to be drawn as a vector
+// path.
+func (a *glyphAtlas) vector(d *drawOps, p *pathOp) {
+	if v, ok := d.pathCache.get(p.pathKey); !ok || v.data.data == nil {
+		p.pathVerts, _ = d.buildVerts(p.glyphData, f32.Affine2D{}, true, 0)
+	}
+	p.glyphData = nil
+	d.pathOps = append(d.pathOps, p)
+}
+
+// atlasPosition returns the key of a glyph, the area of its coverage and
+// the integer part of its position.
+func atlasPosition(p *pathOp) (atlasKey, image.Rectangle, image.Point) {
+	pos := p.off.Add(p.glyph.Origin)
+	off, sub := splitOffset(pos.X)
+	offY, subY := splitOffset(pos.Y)
+	k := atlasKey{glyph: p.glyph.ID, sub: image.Pt(sub, subY)}
+	b := p.glyph.Bounds.Add(subpixelOffset(k.sub))
+	// Pad the area by a pixel to cover the rounding of the exact clip
+	// bounds.
+	rect := image.Rectangle{
+		Min: image.Pt(int(math.Floor(float64(b.Min.X)))-1, int(math.Floor(float64(b.Min.Y)))-1),
+		Max: image.Pt(int(math.Ceil(float64(b.Max.X)))+1, int(math.Ceil(float64(b.Max.Y)))+1),
+	}
+	return k, rect, image.Pt(off, offY)
+}
+
+// splitOffset splits an offset into its integer part and its subpixel
+// position.
+func splitOffset(v float32) (int, int) {
+	i := math.Floor(float64(v))
+	sub := int(math.Round((float64(v) - i) * atlasSubpixels))
+	if sub == atlasSubpixels {
+		i++
+		sub = 0
+	}
+	return int(i), sub
+}
+
+func subpixelOffset(sub image.Point) f32.Point {
+	return f32.Point{
+		X: float32(sub.X) / atlasSubpixels,
+		Y: float32(sub.Y) / atlasSubpixels,
+	}
+}
+
+// add allocates space in the atlas.
+func (a *glyphAtlas) add(sz image.Point) (placement, bool) {
+	if place, ok := a.packer.tryAdd(sz); ok {
+		return place, true
+	}
+	if len(a.packer.sizes) == atlasMaxPages {
+		return placement{}, false
+	}
+	a.packer.newPage()
+	place, ok := a.packer.tryAdd(sz)
+	if !ok {
+		return placement{}, false
+	}
+	if place.Idx == len(a.pages) {
+		size := a.packer.maxDims
+		tex, err := a.ctx.NewTexture(driver.TextureFormatFloat, size.X, size.Y, driver.FilterNearest, driver.FilterNearest,
+			driver.BufferBindingTexture|driver.BufferBindingFramebuffer)
+		if err != nil {
+			panic(err)
+		}
+		a.pages = append(a.pages, atlasPage{fbo: stencilFBO{size: size, tex: tex}})
+	}
+	a.pages[place.Idx].clear = true
+	return place, true
+}
+
+// reset empties the atlas.
+func (a *glyphAtlas) reset() {
+	for k := range a.entries {
+		delete(a.entries, k)
+	}
+	a.packer.clear()
+}
+
+// stencil the coverage of the pending glyphs into the atlas.
+func (a *glyphAtlas) stencil(d *drawOps, pather *pather) {
+	if len(a.pending) == 0 {
+		return
+	}
+	var paths []pathData
+	s := pather.stenciler
+	for idx := range a.pages {
+		page := &a.pages[idx]
+		begun := false
+		for _, p := range a.pending {
+			if p.place.Idx != idx {
+				continue
+			}
+			if !begun {
+				begun = true
+				d := driver.LoadDesc{Action: driver.LoadActionKeep}
+				if page.clear {
+					page.clear = false
+					d.Action = driver.LoadActionClear
+				}
+				a.ctx.BeginRenderPass(page.fbo.tex, d)
+				a.ctx.BindPipeline(s.pipeline.pipeline.pipeline)
+				a.ctx.BindIndexBuffer(s.indexBuf)
+			}
+			k, rect, _ := atlasPosition(p)
+			verts, _ := d.buildVerts(p.glyphData, f32.Affine2D{}, true, 0)
+			data := buildPath(a.ctx, verts)
+			paths = append(paths, data)
+			// Stencil the glyph origin at the subpixel position.
+			s.stencilPath(rect, subpixelOffset(k.sub).Sub(p.glyph.Origin), p.place.Pos, data)
+			p.glyphData = nil
+		}
+		if begun {
+			a.ctx.EndRenderPass()
+		}
+	}
+	for _, p := range paths {
+		p.release()
+	}
+}
+
+// prepare the atlas pages for covering.
+func (a *glyphAtlas) prepare() {
+	for _, p := range a.pages {
+		a.ctx.PrepareTexture(p.fbo.tex)
+	}
+}
+
+func (a *glyphAtlas) page(idx int) stencilFBO {
+	return a.pages[idx].fbo
+}
+
+// frame drops the entries not used in the frame. Their space is
+// reclaimed when the atlas is reset.
+func (a *glyphAtlas) frame() {
+	for k, e := range a.entries {
+		if !e.used {
+			delete(a.entries, k)
+			continue
+		}
+		e.used = false
+	}
+}
+
+func (a *glyphAtlas) release() {
+	for _, p := range a.pages {
+		p.fbo.tex.Release()
+	}
+	a.pages = nil
+	a.entries = nil
+}
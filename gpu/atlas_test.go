@@ -0,0 +1,25 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package gpu
+
+import "testing"
+
+func TestSplitOffset(t *testing.T) {
+	tests := []struct {
+		v        float32
+		off, sub int
+	}{
+		{0, 0, 0},
+		{2.25, 2, 1},
+		{2.6, 2, 2},
+		{2.9, 3, 0},
+		{-0.25, -1, 3},
+		{-1.9, -2, 0},
+	}
+	for _, test := range tests {
+		off, sub := splitOffset(test.v)
+		if off != test.off || sub != test.sub {
+			t.Errorf("splitOffset(%v) = %d, %d, want %d, %d", test.v, off, sub, test.off, test.sub)
+		}
+	}
+}
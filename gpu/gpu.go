@@ -66,6 +66,7 @@ type renderer struct {
 	ctx           driver.Device
 	blitter       *blitter
 	pather        *pather
+	atlas         *glyphAtlas
 	packer        packer
 	intersections packer
 }
@@ -84,6 +85,10 @@ type drawOps struct {
 	pathOpCache []pathOp
 	qs          quadSplitter
 	pathCache   *opCache
+	// glyphOps are the pathOps of the glyphs of atlas paths, and
+	// glyphData their segments.
+	glyphOps  []*pathOp
+	glyphData []byte
 }
 
 type drawState struct {
@@ -120,6 +125,16 @@ type pathOp struct {
 	pathVerts []byte
 	parent    *pathOp
 	place     placement
+	// glyphs are the pathOps of the glyphs of an atlas path, drawn
+	// instead of the path.
+	glyphs []*pathOp
+	// glyph is the glyph of a pathOp in glyphs, and glyphData its
+	// segments until they are stenciled.
+	glyph     ops.Glyph
+	glyphData []byte
+	// cached reports whether place refers to the coverage of the glyph
+	// in the glyph atlas.
+	cached bool
 }
 
 type imageOp struct {
@@ -139,12 +154,17 @@ func decodeStrokeOp(data []byte) float32 {
 type quadsOp struct {
 	key opKey
 	aux []byte
+	// glyphs are the glyphs of the path, if any.
+	glyphs *[]ops.Glyph
 }
 
 type opKey struct {
 	outline        bool
 	strokeWidth    float32
 	sx, hx, sy, hy float32
+	// glyph is the index plus one of a glyph of the path drawn by
+	// itself.
+	glyph int
 	ops.Key
 }
 
@@ -290,6 +310,8 @@ const (
 	clipTypeNone clipType = iota
 	clipTypePath
 	clipTypeIntersection
+	// clipTypeAtlas is clipTypePath covered by the glyph atlas.
+	clipTypeAtlas
 )
 
 const (
@@ -377,13 +399,16 @@ func (g *gpu) frame(target RenderTarget) error {
 	viewport := g.renderer.blitter.viewport
 	defFBO := g.ctx.BeginFrame(target, g.drawOps.clear, viewport)
 	defer g.ctx.EndFrame()
-	g.drawOps.buildPaths(g.ctx)
 	for _, img := range g.drawOps.imageOps {
 		expandPathOp(img.path, img.clip)
 	}
+	g.renderer.atlas.place(&g.drawOps)
+	g.drawOps.buildPaths(g.ctx)
 	g.stencilTimer.begin()
 	g.renderer.packStencils(&g.drawOps.pathOps)
 	g.renderer.stencilClips(g.drawOps.pathCache, g.drawOps.pathOps)
+	g.renderer.atlas.stencil(&g.drawOps, g.renderer.pather)
+	g.renderer.atlas.prepare()
 	g.renderer.packIntersections(g.drawOps.imageOps)
 	g.renderer.prepareIntersections(g.drawOps.imageOps)
 	g.renderer.intersect(g.drawOps.imageOps)
@@ -406,6 +431,7 @@ func (g *gpu) frame(target RenderTarget) error {
 	g.cleanupTimer.begin()
 	g.cache.frame()
 	g.drawOps.pathCache.frame()
+	g.renderer.atlas.frame()
 	g.cleanupTimer.end()
 	if g.drawOps.profile && g.timers.ready() {
 		st, covt, cleant := g.stencilTimer.Elapsed, g.coverTimer.Elapsed, g.cleanupTimer.Elapsed
@@ -456,6 +482,7 @@ func newRenderer(ctx driver.Device) *renderer {
 		ctx:     ctx,
 		blitter: newBlitter(ctx),
 		pather:  newPather(ctx),
+		atlas:   newGlyphAtlas(ctx),
 	}
 
 	maxDim := ctx.Caps().MaxTextureSize
@@ -471,6 +498,7 @@ func newRenderer(ctx driver.Device) *renderer {
 }
 
 func (r *renderer) release() {
+	r.atlas.release()
 	r.pather.release()
 	r.blitter.release()
 }
@@ -644,8 +672,7 @@ func (r *renderer) prepareIntersections(ops []imageOp) {
 		if img.clipType != clipTypeIntersection {
 			continue
 		}
-		fbo := r.pather.stenciler.cover(img.path.place.Idx)
-		r.ctx.PrepareTexture(fbo.tex)
+		r.ctx.PrepareTexture(r.coverFBO(img.path).tex)
 	}
 }
 
@@ -695,7 +722,7 @@ func (r *renderer) intersectPath(p *pathOp, clip image.Rectangle) {
 		Min: o,
 		Max: o.Add(clip.Size()),
 	}
-	fbo := r.pather.stenciler.cover(p.place.Idx)
+	fbo := r.coverFBO(p)
 	r.ctx.BindTexture(0, fbo.tex)
 	coverScale, coverOff := texSpaceTransform(f32.FRect(uv), fbo.size)
 	subScale, subOff := texSpaceTransform(f32.FRect(sub), p.clip.Size())
@@ -705,6 +732,14 @@ func (r *renderer) intersectPath(p *pathOp, clip image.Rectangle) {
 	r.ctx.DrawArrays(0, 4)
 }
 
+// coverFBO returns the texture with the coverage of a path.
+func (r *renderer) coverFBO(p *pathOp) stencilFBO {
+	if p.cached {
+		return r.atlas.page(p.place.Idx)
+	}
+	return r.pather.stenciler.cover(p.place.Idx)
+}
+
 func (r *renderer) packIntersections(ops []imageOp) {
 	r.intersections.clear()
 	for i, img := range ops {
@@ -723,6 +758,9 @@ func (r *renderer) packIntersections(ops []imageOp) {
 			place.Pos = place.Pos.Sub(onePath.clip.Min).Add(img.clip.Min)
 			ops[i].place = place
 			ops[i].clipType = clipTypePath
+			if onePath.cached {
+				ops[i].clipType = clipTypeAtlas
+			}
 		default:
 			sz := image.Point{X: img.clip.Dx(), Y: img.clip.Dy()}
 			place, ok := r.intersections.add(sz)
@@ -766,6 +804,8 @@ func (d *drawOps) reset(viewport image.Point) {
 	d.imageOps = d.imageOps[:0]
 	d.pathOps = d.pathOps[:0]
 	d.pathOpCache = d.pathOpCache[:0]
+	d.glyphOps = d.glyphOps[:0]
+	d.glyphData = d.glyphData[:0]
 	d.vertCache = d.vertCache[:0]
 	d.transStack = d.transStack[:0]
 }
@@ -823,6 +863,38 @@ func (d *drawOps) addClipPath(state *drawState, aux []byte, auxKey opKey, bounds
 	state.cpath = npath
 }
 
+// addGlyphs adds the pathOps of the glyphs of the atlas path p, whose
+// segments are in aux.
+func (d *drawOps) addGlyphs(p *pathOp, glyphs []ops.Glyph, aux []byte) {
+	start := len(d.glyphOps)
+	for i, g := range glyphs {
+		if g.End > len(aux) {
+			d.glyphOps = d.glyphOps[:start]
+			return
+		}
+		dataStart := len(d.glyphData)
+		d.glyphData = append(d.glyphData, aux[g.Start:g.End]...)
+		bounds := g.Bounds.Add(g.Origin)
+		gp := d.newPathOp()
+		*gp = pathOp{
+			parent:    p.parent,
+			bounds:    bounds,
+			off:       p.off,
+			intersect: bounds.Add(p.off),
+			path:      true,
+			glyph:     g,
+			glyphData: d.glyphData[dataStart:],
+		}
+		gp.pathKey = p.pathKey
+		gp.pathKey.glyph = i + 1
+		if gp.parent != nil {
+			gp.intersect = gp.parent.intersect.Intersect(gp.intersect)
+		}
+		d.glyphOps = append(d.glyphOps, gp)
+	}
+	p.glyphs = d.glyphOps[start:len(d.glyphOps):len(d.glyphOps)]
+}
+
 func (d *drawOps) save(id int, state f32.Affine2D) {
 	if extra := id - len(d.states) + 1; extra > 0 {
 		d.states = append(d.states, make([]f32.Affine2D, extra)...)
@@ -870,6 +942,7 @@ loop:
 			quads.key.strokeWidth = decodeStrokeOp(encOp.Data)
 
 		case ops.TypePath:
+			quads.glyphs, _ = encOp.Refs[0].(*[]ops.Glyph)
 			encOp, ok = r.Decode()
 			if !ok {
 				break loop
@@ -883,6 +956,7 @@ loop:
 			quads.key.outline = op.Outline
 			bounds := f32.FRect(op.Bounds)
 			trans, off := state.t.Split()
+			aux := quads.aux
 			if len(quads.aux) > 0 {
 				// There is a clipping path, build the gpu data and update the
 				// cache key such that it will be equal only if the transform is the
@@ -907,6 +981,9 @@ loop:
 				quads.key = opKey{Key: encOp.Key}
 			}
 			d.addClipPath(&state, quads.aux, quads.key, bounds, off, true)
+			if op.Atlas && op.Outline && quads.glyphs != nil && trans == (f32.Affine2D{}) {
+				d.addGlyphs(state.cpath, *quads.glyphs, aux)
+			}
 			quads = quadsOp{}
 		case ops.TypePopClip:
 			state.cpath = state.cpath.parent
@@ -946,6 +1023,23 @@ loop:
 				continue
 			}
 
+			if clipData == nil && state.cpath != nil && state.cpath.glyphs != nil {
+				// Draw the glyphs of an atlas path as quads.
+				for _, gp := range state.cpath.glyphs {
+					gcl := gp.intersect.Intersect(cl)
+					if gcl.Empty() {
+						continue
+					}
+					bounds := gcl.Round()
+					d.imageOps = append(d.imageOps, imageOp{
+						path:     gp,
+						clip:     bounds,
+						material: state.materialFor(bnd, off, partialTrans, bounds),
+					})
+				}
+				continue
+			}
+
 			if clipData != nil {
 				// The paint operation is sheared or rotated, add a clip path representing
 				// this transformed rectangle.
@@ -1062,6 +1156,8 @@ func (r *renderer) prepareDrawOps(cache *resourceCache, ops []imageOp) {
 			continue
 		case clipTypePath:
 			fbo = r.pather.stenciler.cover(img.place.Idx)
+		case clipTypeAtlas:
+			fbo = r.atlas.page(img.place.Idx)
 		case clipTypeIntersection:
 			fbo = r.pather.stenciler.intersections.fbos[img.place.Idx]
 		}
@@ -1090,6 +1186,8 @@ func (r *renderer) drawOps(cache *resourceCache, ops []imageOp) {
 			continue
 		case clipTypePath:
 			fbo = r.pather.stenciler.cover(img.place.Idx)
+		case clipTypeAtlas:
+			fbo = r.atlas.page(img.place.Idx)
 		case clipTypeIntersection:
 			fbo = r.pather.stenciler.intersections.fbos[img.place.Idx]
 		}
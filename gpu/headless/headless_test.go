@@ -3,14 +3,21 @@
 package headless
 
 import (
+	"fmt"
 	"image"
 	"image/color"
 	"testing"
 
+	"golang.org/x/image/math/fixed"
+
+	"github.com/xiaoshengduan/gio-fly/f32"
+	"github.com/xiaoshengduan/gio-fly/font/gofont"
 	"github.com/xiaoshengduan/gio-fly/internal/f32color"
+	"github.com/xiaoshengduan/gio-fly/io/system"
 	"github.com/xiaoshengduan/gio-fly/op"
 	"github.com/xiaoshengduan/gio-fly/op/clip"
 	"github.com/xiaoshengduan/gio-fly/op/paint"
+	"github.com/xiaoshengduan/gio-fly/text"
 )
 
 func TestHeadless(t *testing.T) {
@@ -147,3 +154,63 @@ func newTestWindow(t *testing.T) (*Window, func()) {
 		w.Release()
 	}
 }
+
+func TestGlyphAtlas(t *testing.T) {
+	w, release := newTestWindow(t)
+	defer release()
+
+	vector := text.NewCache(gofont.Collection())
+	atlas := text.NewCache(gofont.Collection())
+	atlas.AtlasSize = fixed.I(100)
+	frame := func(cache *text.Cache) *image.RGBA {
+		var ops op.Ops
+		paint.ColorOp{Color: color.NRGBA{A: 0xff}}.Add(&ops)
+		size := fixed.I(13)
+		lines := cache.LayoutString(text.Font{}, size, 1000, system.Locale{}, text.Parameters{}, "Glyph atlas: 0123456789 (repeated glyphs)")
+		// Draw the text at a range of subpixel positions.
+		for i, x := range []float32{10, 10.2, 10.4, 10.65, 10.9} {
+			pos := f32.Pt(x, float32(30+i*30)+float32(i)*.3)
+			t := op.Affine(f32.Affine2D{}.Offset(pos)).Push(&ops)
+			cl := clip.Outline{Path: cache.Shape(text.Font{}, size, lines[0].Layout)}.Op().Push(&ops)
+			paint.PaintOp{}.Add(&ops)
+			cl.Pop()
+			t.Pop()
+		}
+		if err := w.Frame(&ops); err != nil {
+			t.Fatal(err)
+		}
+		img := image.NewRGBA(image.Rectangle{Max: w.Size()})
+		if err := w.Screenshot(img); err != nil {
+			t.Fatal(err)
+		}
+		return img
+	}
+	want := frame(vector)
+	// The first frame stencils the glyphs into the atlas, and the second
+	// draws them from the atlas.
+	for i := 0; i < 2; i++ {
+		got := frame(atlas)
+		if *dumpImages {
+			if err := saveImage(fmt.Sprintf("atlas%d.png", i), got); err != nil {
+				t.Fatal(err)
+			}
+		}
+		var maxDiff, sum, ink int
+		for j := 0; j < len(got.Pix); j += 4 {
+			d := int(got.Pix[j+3]) - int(want.Pix[j+3])
+			if d < 0 {
+				d = -d
+			}
+			if d > maxDiff {
+				maxDiff = d
+			}
+			sum += d
+			ink += int(want.Pix[j+3])
+		}
+		// The atlas rasterizes glyphs at a quarter pixel position, so the
+		// coverage of edges may differ slightly.
+		if maxDiff > 0x60 || sum > ink/10 {
+			t.Errorf("frame %d: atlas coverage differs from vector coverage by up to %d, %d in total of %d", i, maxDiff, sum, ink)
+		}
+	}
+}
@@ -3,20 +3,17 @@
 package fling
 
 import (
-	"math"
 	"runtime"
 	"time"
 
+	"github.com/xiaoshengduan/gio-fly/animation"
 	"github.com/xiaoshengduan/gio-fly/unit"
 )
 
 type Animation struct {
 	// Current offset in pixels.
-	x float32
-	// Initial time.
-	t0 time.Time
-	// Initial velocity in pixels pr second.
-	v0 float32
+	x     float32
+	decay animation.Decay
 }
 
 const (
@@ -40,18 +37,17 @@ func (f *Animation) Start(c unit.Metric, now time.Time, velocity float32) bool {
 	} else if v < -max {
 		v = -max
 	}
-	f.init(now, v)
-	return true
-}
-
-func (f *Animation) init(now time.Time, v0 float32) {
-	f.t0 = now
-	f.v0 = v0
 	f.x = 0
+	f.decay = animation.Decay{
+		Friction:    friction(),
+		MinVelocity: thresholdVelocity,
+	}
+	f.decay.Start(now, v)
+	return true
 }
 
 func (f *Animation) Active() bool {
-	return f.v0 != 0
+	return f.decay.Running()
 }
 
 // Tick computes and returns a fling distance since
@@ -60,36 +56,25 @@ func (f *Animation) Tick(now time.Time) int {
 	if !f.Active() {
 		return 0
 	}
-	var k float32
-	if runtime.GOOS == "darwin" {
-		k = -2 // iOS
-	} else {
-		k = -4.2 // Android and default
-	}
-	t := now.Sub(f.t0)
-	// The acceleration x''(t) of a point mass with a drag
-	// force, f, proportional with velocity, x'(t), is
-	// governed by the equation
-	//
-	// x''(t) = kx'(t)
-	//
-	// Given the starting position x(0) = 0, the starting
-	// velocity x'(0) = v0, the position is then
-	// given by
-	//
-	// x(t) = v0*e^(k*t)/k - v0/k
-	//
-	ekt := float32(math.Exp(float64(k) * t.Seconds()))
-	x := f.v0*ekt/k - f.v0/k
-	dist := x - f.x
+	dist := f.decay.Value(nil, now) - f.x
 	idist := int(dist)
 	f.x += float32(idist)
-	// Solving for the velocity x'(t) gives us
-	//
-	// x'(t) = v0*e^(k*t)
-	v := f.v0 * ekt
-	if -thresholdVelocity < v && v < thresholdVelocity {
-		f.v0 = 0
-	}
 	return idist
 }
+
+// Distance returns the distance left to travel before the fling
+// stops.
+func (f *Animation) Distance() float32 {
+	if !f.Active() {
+		return 0
+	}
+	return f.decay.Distance() - f.x
+}
+
+// friction returns the rate of decay of the fling velocity.
+func friction() float32 {
+	if runtime.GOOS == "darwin" {
+		return 2 // iOS
+	}
+	return 4.2 // Android and default
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/xiaoshengduan/gio-fly/f32"
 	"github.com/xiaoshengduan/gio-fly/internal/byteslice"
+	f32internal "github.com/xiaoshengduan/gio-fly/internal/f32"
 	"github.com/xiaoshengduan/gio-fly/internal/scene"
 )
 
@@ -104,7 +105,9 @@ type StackKind uint8
 type ClipOp struct {
 	Bounds  image.Rectangle
 	Outline bool
-	Shape   Shape
+	// Atlas marks the path for drawing from a texture atlas.
+	Atlas bool
+	Shape Shape
 }
 
 const (
@@ -114,6 +117,26 @@ const (
 	_StackKind
 )
 
+// Glyph is a glyph of a path, as marked by clip.Path.Glyph. The glyphs of
+// a path are the reference of its TypePath op.
+type Glyph struct {
+	// ID identifies the outline of the glyph.
+	ID uint64
+	// Origin is the position of the glyph in the path.
+	Origin f32.Point
+	// Bounds of the outline, relative to Origin.
+	Bounds f32internal.Rectangle
+	// Start and End are the range of the glyph segments in the path
+	// data.
+	Start, End int
+}
+
+// Flags of the ClipOp encoding.
+const (
+	ClipOutline = 1 << iota
+	ClipAtlas
+)
+
 const (
 	Path Shape = iota
 	Ellipse
@@ -172,7 +195,8 @@ func (op *ClipOp) Decode(data []byte) {
 	op.Bounds.Min.Y = int(int32(bo.Uint32(data[5:])))
 	op.Bounds.Max.X = int(int32(bo.Uint32(data[9:])))
 	op.Bounds.Max.Y = int(int32(bo.Uint32(data[13:])))
-	op.Outline = data[17] == 1
+	op.Outline = data[17]&ClipOutline != 0
+	op.Atlas = data[17]&ClipAtlas != 0
 	op.Shape = Shape(data[18])
 }
 
@@ -407,7 +431,7 @@ var opProps = [0x100]opProp{
 	TypePopClip:          {Size: TypePopClipLen, NumRefs: 0},
 	TypeProfile:          {Size: TypeProfileLen, NumRefs: 1},
 	TypeCursor:           {Size: TypeCursorLen, NumRefs: 0},
-	TypePath:             {Size: TypePathLen, NumRefs: 0},
+	TypePath:             {Size: TypePathLen, NumRefs: 1},
 	TypeStroke:           {Size: TypeStrokeLen, NumRefs: 0},
 	TypeSemanticLabel:    {Size: TypeSemanticLabelLen, NumRefs: 1},
 	TypeSemanticDesc:     {Size: TypeSemanticDescLen, NumRefs: 1},
@@ -14,7 +14,9 @@ type Event struct {
 }
 
 // ReadOp requests the text of the clipboard, delivered to
-// the current handler through an Event.
+// the current handler through an Event. If the text was copied by a
+// WriteOp with a Type, the Event is preceded by a transfer.DataEvent
+// with its Data.
 type ReadOp struct {
 	Tag event.Tag
 }
@@ -22,6 +24,12 @@ type ReadOp struct {
 // WriteOp copies Text to the clipboard.
 type WriteOp struct {
 	Text string
+	// Type is the MIME type of Data, if set. Data is the copied content
+	// in a richer format than Text, such as styled text. System
+	// clipboards carry only text, so Data is kept by the window and
+	// offered to its readers while the clipboard holds Text.
+	Type string
+	Data []byte
 }
 
 func (h ReadOp) Add(o *op.Ops) {
@@ -30,7 +38,7 @@ func (h ReadOp) Add(o *op.Ops) {
 }
 
 func (h WriteOp) Add(o *op.Ops) {
-	data := ops.Write1(&o.Internal, ops.TypeClipboardWriteLen, &h.Text)
+	data := ops.Write1(&o.Internal, ops.TypeClipboardWriteLen, &h)
 	data[0] = byte(ops.TypeClipboardWrite)
 }
 
@@ -3,7 +3,12 @@
 package router
 
 import (
+	"bytes"
+	"io"
+
+	"github.com/xiaoshengduan/gio-fly/io/clipboard"
 	"github.com/xiaoshengduan/gio-fly/io/event"
+	"github.com/xiaoshengduan/gio-fly/io/transfer"
 )
 
 type clipboardQueue struct {
@@ -11,6 +16,8 @@ type clipboardQueue struct {
 	// request avoid read clipboard every frame while waiting.
 	requested bool
 	text      *string
+	// typed is the most recent write with typed data.
+	typed *clipboard.WriteOp
 }
 
 // WriteClipboard returns the most recent text to be copied
@@ -34,15 +41,32 @@ func (q *clipboardQueue) ReadClipboard() bool {
 	return true
 }
 
-func (q *clipboardQueue) Push(e event.Event, events *handlerEvents) {
+func (q *clipboardQueue) Push(e clipboard.Event, events *handlerEvents) {
+	var data *transfer.DataEvent
+	if t := q.typed; t != nil && t.Text == e.Text {
+		data = &transfer.DataEvent{
+			Type: t.Type,
+			Open: func() io.ReadCloser {
+				return io.NopCloser(bytes.NewReader(t.Data))
+			},
+		}
+	}
 	for r := range q.receivers {
+		if data != nil {
+			events.Add(r, *data)
+		}
 		events.Add(r, e)
 		delete(q.receivers, r)
 	}
 }
 
 func (q *clipboardQueue) ProcessWriteClipboard(refs []interface{}) {
-	q.text = refs[0].(*string)
+	op := refs[0].(*clipboard.WriteOp)
+	q.text = &op.Text
+	q.typed = nil
+	if op.Type != "" {
+		q.typed = op
+	}
 }
 
 func (q *clipboardQueue) ProcessReadClipboard(refs []interface{}) {
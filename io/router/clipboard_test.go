@@ -1,10 +1,12 @@
 package router
 
 import (
+	"io"
 	"testing"
 
 	"github.com/xiaoshengduan/gio-fly/io/clipboard"
 	"github.com/xiaoshengduan/gio-fly/io/event"
+	"github.com/xiaoshengduan/gio-fly/io/transfer"
 	"github.com/xiaoshengduan/gio-fly/op"
 )
 
@@ -102,6 +104,53 @@ func TestQueueProcessWriteClipboard(t *testing.T) {
 	ops.Reset()
 }
 
+func TestClipboardTypedData(t *testing.T) {
+	ops, router, handler := new(op.Ops), new(Router), new(int)
+
+	clipboard.WriteOp{Text: "bold", Type: "application/x-test", Data: []byte("<b>bold</b>")}.Add(ops)
+	clipboard.ReadOp{Tag: handler}.Add(ops)
+	router.Frame(ops)
+	assertClipboardWriteOp(t, router, "bold")
+	router.Queue(clipboard.Event{Text: "bold"})
+	evs := router.Events(handler)
+	if len(evs) != 2 {
+		t.Fatalf("got %d events, expected 2", len(evs))
+	}
+	data, ok := evs[0].(transfer.DataEvent)
+	if !ok || data.Type != "application/x-test" {
+		t.Fatalf("got %v, expected a transfer.DataEvent", evs[0])
+	}
+	rc := data.Open()
+	content, _ := io.ReadAll(rc)
+	rc.Close()
+	if got := string(content); got != "<b>bold</b>" {
+		t.Errorf("got data %q, expected %q", got, "<b>bold</b>")
+	}
+	if _, ok := evs[1].(clipboard.Event); !ok {
+		t.Errorf("got %v, expected a clipboard.Event", evs[1])
+	}
+	ops.Reset()
+
+	// The typed data is dropped when the clipboard text changes.
+	clipboard.ReadOp{Tag: handler}.Add(ops)
+	router.Frame(ops)
+	router.Queue(clipboard.Event{Text: "copied elsewhere"})
+	evs = router.Events(handler)
+	if len(evs) != 1 {
+		t.Fatalf("got %d events for other text, expected 1", len(evs))
+	}
+	ops.Reset()
+
+	// Plain writes replace typed data.
+	clipboard.WriteOp{Text: "bold"}.Add(ops)
+	clipboard.ReadOp{Tag: handler}.Add(ops)
+	router.Frame(ops)
+	router.Queue(clipboard.Event{Text: "bold"})
+	if evs := router.Events(handler); len(evs) != 1 {
+		t.Errorf("got %d events after a plain write, expected 1", len(evs))
+	}
+}
+
 func assertClipboardEvent(t *testing.T, events []event.Event, expected bool) {
 	t.Helper()
 	var evtClipboard int
@@ -26,6 +26,8 @@ type FrameEvent struct {
 	Size image.Point
 	// Insets represent the space occupied by system decorations and controls.
 	Insets Insets
+	// Locale is the language and text direction of the system.
+	Locale Locale
 	// Frame completes the FrameEvent by drawing the graphical operations
 	// from ops into the window.
 	Frame func(frame *op.Ops)
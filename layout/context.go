@@ -28,8 +28,6 @@ type Context struct {
 	Now time.Time
 
 	// Locale provides information on the system's language preferences.
-	// BUG(whereswaldon): this field is not currently populated automatically.
-	// Interested users must look up and populate these values manually.
 	Locale system.Locale
 
 	*op.Ops
@@ -42,6 +40,7 @@ type Context struct {
 //	  Now: e.Now,
 //	  Queue: e.Queue,
 //	  Config: e.Config,
+//	  Locale: e.Locale,
 //	  Constraints: Exact(e.Size),
 //	}
 //
@@ -68,6 +67,7 @@ func NewContext(ops *op.Ops, e system.FrameEvent) Context {
 		Now:         e.Now,
 		Queue:       e.Queue,
 		Metric:      e.Metric,
+		Locale:      e.Locale,
 		Constraints: Exact(size),
 	}
 }
@@ -22,6 +22,10 @@ type Flex struct {
 	// size of Flexed children. If WeightSum is zero, the sum
 	// of all Flexed weights is used.
 	WeightSum float32
+	// Mirror, if set, reverses the order of Horizontal children when
+	// gtx.Locale.Direction is right-to-left, so the first child is laid
+	// out at the right.
+	Mirror bool
 }
 
 // FlexChild is the descriptor for a Flex child.
@@ -169,6 +173,9 @@ func (f Flex) Layout(gtx Context, children ...FlexChild) Dimensions {
 			mainSize += space / (len(children) * 2)
 		}
 	}
+	mirror := f.Mirror && f.Axis == Horizontal && rtl(gtx)
+	// mirrorSize is the main size mirrored children are laid out in.
+	mirrorSize := min(max(size, mainMin), mainMax)
 	for i, child := range children {
 		dims := child.dims
 		b := dims.Size.Y - dims.Baseline
@@ -183,7 +190,11 @@ func (f Flex) Layout(gtx Context, children ...FlexChild) Dimensions {
 				cross = maxBaseline - b
 			}
 		}
-		pt := f.Axis.Convert(image.Pt(mainSize, cross))
+		main := mainSize
+		if mirror {
+			main = mirrorSize - mainSize - dims.Size.X
+		}
+		pt := f.Axis.Convert(image.Pt(main, cross))
 		trans := op.Offset(pt).Push(gtx.Ops)
 		child.call.Add(gtx.Ops)
 		trans.Pop()
@@ -0,0 +1,304 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package layout
+
+import (
+	"image"
+
+	"github.com/xiaoshengduan/gio-fly/op"
+	"github.com/xiaoshengduan/gio-fly/unit"
+)
+
+// Grid lays out child elements in cells of rows and columns.
+type Grid struct {
+	// Columns and Rows are the sizes of the tracks of the grid. Children
+	// placed outside the specified tracks add automatically sized tracks.
+	Columns, Rows []Track
+	// ColumnGap and RowGap are the spaces between columns and rows.
+	ColumnGap, RowGap unit.Dp
+	// Alignment is the alignment of children smaller than their
+	// cells, unless overridden by GridChild.Align.
+	Alignment Direction
+}
+
+// Track is the size of a Grid row or column. The zero value is a track
+// sized to fit its content.
+type Track struct {
+	// Size is the fixed size of the track.
+	Size unit.Dp
+	// Weight, if non-zero, sizes the track to a Weight fraction of the
+	// space left over from the other tracks. The fraction is Weight
+	// divided by the sum of the weights of all tracks in the same
+	// dimension. Rows with weights should be laid out with a bounded
+	// maximum height.
+	Weight float32
+}
+
+// GridChild is the descriptor for a Grid child.
+type GridChild struct {
+	row, col         int
+	rowSpan, colSpan int
+	align            *Direction
+
+	widget Widget
+
+	// Scratch space.
+	call op.CallOp
+	dims Dimensions
+	done bool
+}
+
+// trackKind classifies a track for sizing.
+type trackKind uint8
+
+const (
+	trackAuto trackKind = iota
+	trackFixed
+	trackWeighted
+)
+
+// Cell returns a Grid child occupying the cell at row and column. Negative
+// rows and columns are treated as zero.
+func Cell(row, col int, w Widget) GridChild {
+	return GridChild{
+		row:     max(row, 0),
+		col:     max(col, 0),
+		rowSpan: 1,
+		colSpan: 1,
+		widget:  w,
+	}
+}
+
+// Span returns a copy of the child that spans rows and cols cells,
+// starting at its cell.
+func (c GridChild) Span(rows, cols int) GridChild {
+	c.rowSpan = max(rows, 1)
+	c.colSpan = max(cols, 1)
+	return c
+}
+
+// Align returns a copy of the child that is aligned within its cells
+// according to d instead of the Grid alignment.
+func (c GridChild) Align(d Direction) GridChild {
+	c.align = &d
+	return c
+}
+
+// Layout the children in the grid. Columns are laid out from right to
+// left in right-to-left locales. Every child is laid out once, with no
+// minimum constraints and the maximum constraints of its cells if they
+// are known. Children spanning only fixed and content sized columns are
+// laid out before children spanning weighted columns.
+func (g Grid) Layout(gtx Context, children ...GridChild) Dimensions {
+	cs := gtx.Constraints
+	nrows, ncols := len(g.Rows), len(g.Columns)
+	for _, c := range children {
+		nrows = max(nrows, c.row+c.rowSpan)
+		ncols = max(ncols, c.col+c.colSpan)
+	}
+	colGap, rowGap := gtx.Dp(g.ColumnGap), gtx.Dp(g.RowGap)
+	cols := newTracks(gtx, g.Columns, ncols)
+	rows := newTracks(gtx, g.Rows, nrows)
+	availX := cs.Max.X - colGap*max(ncols-1, 0)
+	availY := cs.Max.Y - rowGap*max(nrows-1, 0)
+
+	measure := func(i int) {
+		c := &children[i]
+		cgtx := gtx
+		cgtx.Constraints.Min = image.Point{}
+		cgtx.Constraints.Max = image.Point{
+			X: cols.spanMax(c.col, c.colSpan, colGap, availX),
+			Y: rows.spanMax(c.row, c.rowSpan, rowGap, availY),
+		}
+		macro := op.Record(gtx.Ops)
+		c.dims = c.widget(cgtx)
+		c.call = macro.Stop()
+		c.done = true
+	}
+	// Lay out children that determine the size of content sized columns.
+	for i, c := range children {
+		children[i].done = false
+		if !cols.spans(trackWeighted, c.col, c.colSpan) {
+			measure(i)
+		}
+	}
+	cols.fit(children, colGap, func(c *GridChild) (int, int, int) {
+		return c.col, c.colSpan, c.dims.Size.X
+	})
+	cols.share(availX)
+	// Lay out the remaining children with the final column widths.
+	for i, c := range children {
+		if !c.done {
+			measure(i)
+		}
+	}
+	rows.fit(children, rowGap, func(c *GridChild) (int, int, int) {
+		return c.row, c.rowSpan, c.dims.Size.Y
+	})
+	rows.share(availY)
+
+	sz := image.Point{
+		X: cols.span(0, ncols, colGap),
+		Y: rows.span(0, nrows, rowGap),
+	}
+	sz = cs.Constrain(sz)
+	mirror := rtl(gtx)
+	for _, c := range children {
+		cell := image.Point{
+			X: cols.span(c.col, c.colSpan, colGap),
+			Y: rows.span(c.row, c.rowSpan, rowGap),
+		}
+		align := g.Alignment
+		if c.align != nil {
+			align = *c.align
+		}
+		pt := image.Point{
+			X: cols.offset(c.col, colGap),
+			Y: rows.offset(c.row, rowGap),
+		}
+		if mirror {
+			// Columns start at the right edge.
+			pt.X = sz.X - pt.X - cell.X
+		}
+		pt = pt.Add(align.Physical(gtx.Locale.Direction).Position(c.dims.Size, cell))
+		trans := op.Offset(pt).Push(gtx.Ops)
+		c.call.Add(gtx.Ops)
+		trans.Pop()
+	}
+	return Dimensions{Size: sz}
+}
+
+// gridTracks are the rows or columns of a Grid during layout.
+type gridTracks struct {
+	kinds   []trackKind
+	weights []float32
+	sizes   []int
+	// shared reports whether the weighted tracks are sized.
+	shared bool
+}
+
+func newTracks(gtx Context, tracks []Track, n int) *gridTracks {
+	t := &gridTracks{
+		kinds:   make([]trackKind, n),
+		weights: make([]float32, n),
+		sizes:   make([]int, n),
+	}
+	for i, tr := range tracks {
+		switch {
+		case tr.Weight > 0:
+			t.kinds[i] = trackWeighted
+			t.weights[i] = tr.Weight
+		case tr.Size > 0:
+			t.kinds[i] = trackFixed
+			t.sizes[i] = gtx.Dp(tr.Size)
+		}
+	}
+	return t
+}
+
+// spans reports whether any of the n tracks from start is of kind k.
+func (t *gridTracks) spans(k trackKind, start, n int) bool {
+	for i := start; i < start+n; i++ {
+		if t.kinds[i] == k {
+			return true
+		}
+	}
+	return false
+}
+
+// spanMax returns the maximum size available to a child spanning n
+// tracks from start: their size if they are sized, or the available space
+// otherwise.
+func (t *gridTracks) spanMax(start, n, gap, avail int) int {
+	if t.spans(trackAuto, start, n) || !t.shared && t.spans(trackWeighted, start, n) {
+		return max(avail, 0)
+	}
+	return t.span(start, n, gap)
+}
+
+// fit grows content sized and weighted tracks to fit the children
+// measured so far. Children spanning a single track are fitted first; the
+// extra size needed by spanning children is distributed evenly among
+// their content sized tracks.
+func (t *gridTracks) fit(children []GridChild, gap int, dim func(c *GridChild) (start, n, size int)) {
+	for i := range children {
+		c := &children[i]
+		start, n, size := dim(c)
+		if c.done && n == 1 && t.kinds[start] != trackFixed {
+			t.sizes[start] = max(t.sizes[start], size)
+		}
+	}
+	for i := range children {
+		c := &children[i]
+		start, n, size := dim(c)
+		if !c.done || n == 1 {
+			continue
+		}
+		var auto int
+		for j := start; j < start+n; j++ {
+			if t.kinds[j] == trackAuto {
+				auto++
+			}
+		}
+		extra := size - t.span(start, n, gap)
+		if auto == 0 || extra <= 0 {
+			continue
+		}
+		for j := start; j < start+n; j++ {
+			if t.kinds[j] != trackAuto {
+				continue
+			}
+			inc := extra / auto
+			t.sizes[j] += inc
+			extra -= inc
+			auto--
+		}
+	}
+}
+
+// share sizes the weighted tracks from the space left over in avail.
+// Weighted tracks are never smaller than the content fitted to them.
+func (t *gridTracks) share(avail int) {
+	t.shared = true
+	var total float32
+	for i, k := range t.kinds {
+		if k == trackWeighted {
+			total += t.weights[i]
+		} else {
+			avail -= t.sizes[i]
+		}
+	}
+	if total == 0 {
+		return
+	}
+	avail = max(avail, 0)
+	// fraction is the rounding error from a weighting.
+	var fraction float32
+	for i, k := range t.kinds {
+		if k != trackWeighted {
+			continue
+		}
+		size := float32(avail) * t.weights[i] / total
+		isize := int(size + fraction + .5)
+		fraction = size - float32(isize)
+		t.sizes[i] = max(t.sizes[i], isize)
+	}
+}
+
+// span returns the size of n tracks from start, including the gaps
+// between them.
+func (t *gridTracks) span(start, n, gap int) int {
+	size := 0
+	for i := start; i < start+n; i++ {
+		size += t.sizes[i]
+	}
+	if n > 1 {
+		size += gap * (n - 1)
+	}
+	return size
+}
+
+// offset returns the position of track i.
+func (t *gridTracks) offset(i, gap int) int {
+	return t.span(0, i, gap) + gap*min(i, 1)
+}
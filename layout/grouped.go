@@ -0,0 +1,124 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package layout
+
+import (
+	"image"
+	"sort"
+
+	"github.com/xiaoshengduan/gio-fly/op"
+	"github.com/xiaoshengduan/gio-fly/op/clip"
+)
+
+// GroupedList is a List of items grouped in sections. Every section
+// starts with a header, and the header of the first visible section sticks
+// to the start of the list, until the next header pushes it out of view.
+//
+// The List Position counts headers as items; use Index and Locate to
+// convert between list indices and section items.
+type GroupedList struct {
+	List
+
+	// starts is the list index of every section header.
+	starts []int
+	// headers are the headers laid out by the list in the current frame.
+	headers []headerCall
+}
+
+// headerCall is a recorded section header.
+type headerCall struct {
+	section int
+	call    op.CallOp
+	dims    Dimensions
+}
+
+// SectionHeader is a function that computes the dimensions of a section
+// header.
+type SectionHeader func(gtx Context, section int) Dimensions
+
+// SectionElement is a function that computes the dimensions of an item
+// of a section.
+type SectionElement func(gtx Context, section, index int) Dimensions
+
+// Layout the sections, where sections[i] is the number of items in section
+// i. The stuck header is drawn on top of the list items.
+func (g *GroupedList) Layout(gtx Context, sections []int, header SectionHeader, el SectionElement) Dimensions {
+	g.starts = g.starts[:0]
+	n := 0
+	for _, count := range sections {
+		g.starts = append(g.starts, n)
+		n += 1 + count
+	}
+	g.headers = g.headers[:0]
+	dims := g.List.Layout(gtx, n, func(gtx Context, index int) Dimensions {
+		s, i := g.Locate(index)
+		if i == -1 {
+			// Record the header for reuse as the stuck header.
+			macro := op.Record(gtx.Ops)
+			dims := header(gtx, s)
+			call := macro.Stop()
+			call.Add(gtx.Ops)
+			g.headers = append(g.headers, headerCall{section: s, call: call, dims: dims})
+			return dims
+		}
+		return el(gtx, s, i)
+	})
+	if g.Position.First >= n {
+		return dims
+	}
+	s, _ := g.Locate(g.Position.First)
+	if pos, _, ok := g.itemPosition(g.starts[s]); ok && pos >= 0 {
+		// The header is in place.
+		return dims
+	}
+	// Lay out the header only if the list hasn't, so that it runs once
+	// per frame.
+	var call op.CallOp
+	var hdims Dimensions
+	found := false
+	for _, h := range g.headers {
+		if h.section == s {
+			call, hdims, found = h.call, h.dims, true
+		}
+	}
+	if !found {
+		cgtx := gtx
+		crossMin, crossMax := g.Axis.crossConstraint(gtx.Constraints)
+		cgtx.Constraints = g.Axis.constraints(0, inf, crossMin, crossMax)
+		macro := op.Record(gtx.Ops)
+		hdims = header(cgtx, s)
+		call = macro.Stop()
+	}
+	var off int
+	if s+1 < len(g.starts) {
+		// Push the header out of view by the next header.
+		if next, _, ok := g.itemPosition(g.starts[s+1]); ok {
+			if size := g.Axis.Convert(hdims.Size).X; next < size {
+				off = next - size
+			}
+		}
+	}
+	defer clip.Rect(image.Rectangle{Max: dims.Size}).Push(gtx.Ops).Pop()
+	defer op.Offset(g.Axis.Convert(image.Pt(off, 0))).Push(gtx.Ops).Pop()
+	call.Add(gtx.Ops)
+	return dims
+}
+
+// Index returns the list index of item i of a section, or of the section
+// header if i is -1. It is valid after Layout.
+func (g *GroupedList) Index(section, i int) int {
+	return g.starts[section] + 1 + i
+}
+
+// Locate returns the section and section item of the list index. The
+// item is -1 for section headers. It is valid after Layout, and returns
+// (-1, -1) if there are no sections or index is negative.
+func (g *GroupedList) Locate(index int) (section, i int) {
+	section = sort.Search(len(g.starts), func(s int) bool {
+		return g.starts[s] > index
+	}) - 1
+	if section < 0 {
+		return -1, -1
+	}
+	return section, index - g.starts[section] - 1
+}
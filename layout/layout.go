@@ -6,6 +6,7 @@ import (
 	"image"
 
 	"github.com/xiaoshengduan/gio-fly/f32"
+	"github.com/xiaoshengduan/gio-fly/io/system"
 	"github.com/xiaoshengduan/gio-fly/op"
 	"github.com/xiaoshengduan/gio-fly/unit"
 )
@@ -63,6 +64,18 @@ const (
 	SW
 	W
 	Center
+
+	// TopStart, CenterStart and BottomStart are at the start of the
+	// horizontal axis: NW, W and SW when gtx.Locale.Direction is
+	// left-to-right, and NE, E and SE when it is right-to-left.
+	TopStart
+	CenterStart
+	BottomStart
+	// TopEnd, CenterEnd and BottomEnd are at the end of the horizontal
+	// axis, opposite their start counterparts.
+	TopEnd
+	CenterEnd
+	BottomEnd
 )
 
 const (
@@ -107,6 +120,9 @@ func (c Constraints) Constrain(size image.Point) image.Point {
 // they do not exceed the maximum.
 type Inset struct {
 	Top, Bottom, Left, Right unit.Dp
+	// Start and End are added to Left and Right, or to Right and Left
+	// when gtx.Locale.Direction is right-to-left.
+	Start, End unit.Dp
 }
 
 // Layout a widget.
@@ -115,6 +131,12 @@ func (in Inset) Layout(gtx Context, w Widget) Dimensions {
 	right := gtx.Dp(in.Right)
 	bottom := gtx.Dp(in.Bottom)
 	left := gtx.Dp(in.Left)
+	start, end := gtx.Dp(in.Start), gtx.Dp(in.End)
+	if rtl(gtx) {
+		start, end = end, start
+	}
+	left += start
+	right += end
 	mcs := gtx.Constraints
 	mcs.Max.X -= left + right
 	if mcs.Max.X < 0 {
@@ -153,6 +175,7 @@ func UniformInset(v unit.Dp) Inset {
 // Layout a widget according to the direction.
 // The widget is called with the context constraints minimum cleared.
 func (d Direction) Layout(gtx Context, w Widget) Dimensions {
+	d = d.Physical(gtx.Locale.Direction)
 	macro := op.Record(gtx.Ops)
 	csn := gtx.Constraints.Min
 	switch d {
@@ -184,8 +207,10 @@ func (d Direction) Layout(gtx Context, w Widget) Dimensions {
 }
 
 // Position calculates widget position according to the direction.
+// Start and end directions are positioned as for left-to-right text.
 func (d Direction) Position(widget, bounds image.Point) image.Point {
 	var p image.Point
+	d = d.Physical(system.LTR)
 
 	switch d {
 	case N, S, Center:
@@ -204,6 +229,37 @@ func (d Direction) Position(widget, bounds image.Point) image.Point {
 	return p
 }
 
+// Physical returns the compass direction for a start or end direction,
+// according to the text direction dir. Other directions are returned
+// unchanged.
+func (d Direction) Physical(dir system.TextDirection) Direction {
+	rtl := dir.Progression() == system.TowardOrigin
+	switch d {
+	case TopStart, TopEnd:
+		if (d == TopStart) == rtl {
+			return NE
+		}
+		return NW
+	case CenterStart, CenterEnd:
+		if (d == CenterStart) == rtl {
+			return E
+		}
+		return W
+	case BottomStart, BottomEnd:
+		if (d == BottomStart) == rtl {
+			return SE
+		}
+		return SW
+	}
+	return d
+}
+
+// rtl reports whether the horizontal layout direction of gtx is
+// right-to-left.
+func rtl(gtx Context) bool {
+	return gtx.Locale.Direction.Progression() == system.TowardOrigin
+}
+
 // Spacer adds space between widgets.
 type Spacer struct {
 	Width, Height unit.Dp
@@ -308,7 +364,33 @@ func (d Direction) String() string {
 		return "W"
 	case Center:
 		return "Center"
+	case TopStart:
+		return "TopStart"
+	case CenterStart:
+		return "CenterStart"
+	case BottomStart:
+		return "BottomStart"
+	case TopEnd:
+		return "TopEnd"
+	case CenterEnd:
+		return "CenterEnd"
+	case BottomEnd:
+		return "BottomEnd"
 	default:
 		panic("unreachable")
 	}
 }
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -6,7 +6,12 @@ import (
 	"image"
 	"testing"
 
+	"github.com/xiaoshengduan/gio-fly/io/router"
+	"github.com/xiaoshengduan/gio-fly/io/semantic"
+	"github.com/xiaoshengduan/gio-fly/io/system"
 	"github.com/xiaoshengduan/gio-fly/op"
+	"github.com/xiaoshengduan/gio-fly/op/clip"
+	"github.com/xiaoshengduan/gio-fly/unit"
 )
 
 func TestStack(t *testing.T) {
@@ -76,3 +81,242 @@ func TestDirection(t *testing.T) {
 		})
 	}
 }
+
+func TestRTL(t *testing.T) {
+	sz := image.Pt(30, 10)
+	for _, tc := range []struct {
+		name string
+		w    Widget
+		ltr  image.Point
+		rtl  image.Point
+	}{
+		{
+			name: "Inset",
+			w: func(gtx Context) Dimensions {
+				return Inset{Start: 10, End: 20}.Layout(gtx, labeled("a", sz))
+			},
+			ltr: image.Pt(10, 0),
+			rtl: image.Pt(20, 0),
+		},
+		{
+			name: "Flex",
+			w: func(gtx Context) Dimensions {
+				return Flex{Mirror: true}.Layout(gtx,
+					Rigid(labeled("b", sz)),
+					Rigid(labeled("a", sz)),
+				)
+			},
+			ltr: image.Pt(30, 0),
+			rtl: image.Pt(40, 0),
+		},
+		{
+			name: "Direction",
+			w: func(gtx Context) Dimensions {
+				return BottomStart.Layout(gtx, labeled("a", sz))
+			},
+			ltr: image.Pt(0, 90),
+			rtl: image.Pt(70, 90),
+		},
+		{
+			name: "Stack",
+			w: func(gtx Context) Dimensions {
+				return Stack{Alignment: CenterEnd}.Layout(gtx,
+					Expanded(func(gtx Context) Dimensions {
+						return Dimensions{Size: gtx.Constraints.Min}
+					}),
+					Stacked(labeled("a", sz)),
+				)
+			},
+			ltr: image.Pt(70, 45),
+			rtl: image.Pt(0, 45),
+		},
+		{
+			name: "Grid",
+			w: func(gtx Context) Dimensions {
+				return Grid{Columns: []Track{{Size: 40}, {Size: 40}}, ColumnGap: 10}.Layout(gtx,
+					Cell(0, 0, labeled("b", sz)),
+					Cell(0, 1, labeled("a", sz)).Align(NW),
+				)
+			},
+			ltr: image.Pt(50, 0),
+			rtl: image.Pt(10, 0),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, dir := range []system.TextDirection{system.LTR, system.RTL} {
+				gtx := Context{
+					Ops:         new(op.Ops),
+					Constraints: Exact(image.Pt(100, 100)),
+					Metric:      unit.Metric{PxPerDp: 1},
+					Locale:      system.Locale{Direction: dir},
+				}
+				want := tc.ltr
+				if dir == system.RTL {
+					want = tc.rtl
+				}
+				tc.w(gtx)
+				if got := labelPosition(t, gtx.Ops, "a"); got != want {
+					t.Errorf("%v: got position %v, want %v", dir, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDirectionPhysical(t *testing.T) {
+	for _, tc := range []struct {
+		dir      Direction
+		ltr, rtl Direction
+	}{
+		{TopStart, NW, NE},
+		{CenterStart, W, E},
+		{BottomStart, SW, SE},
+		{TopEnd, NE, NW},
+		{CenterEnd, E, W},
+		{BottomEnd, SE, SW},
+		{N, N, N},
+		{Center, Center, Center},
+	} {
+		if got := tc.dir.Physical(system.LTR); got != tc.ltr {
+			t.Errorf("%v left-to-right: got %v, want %v", tc.dir, got, tc.ltr)
+		}
+		if got := tc.dir.Physical(system.RTL); got != tc.rtl {
+			t.Errorf("%v right-to-left: got %v, want %v", tc.dir, got, tc.rtl)
+		}
+	}
+}
+
+// labeled returns a widget of size sz, labeled for labelPosition.
+func labeled(label string, sz image.Point) Widget {
+	return func(gtx Context) Dimensions {
+		defer clip.Rect{Max: sz}.Push(gtx.Ops).Pop()
+		semantic.LabelOp(label).Add(gtx.Ops)
+		return Dimensions{Size: sz}
+	}
+}
+
+// labelPosition returns the position of the labeled widget in ops.
+func labelPosition(t *testing.T, ops *op.Ops, label string) image.Point {
+	t.Helper()
+	var r router.Router
+	r.Frame(ops)
+	for _, n := range r.AppendSemantics(nil) {
+		if n.Desc.Label == label {
+			return n.Desc.Bounds.Min
+		}
+	}
+	t.Fatalf("no widget labeled %q", label)
+	return image.Point{}
+}
+
+func TestGrid(t *testing.T) {
+	gtx := Context{
+		Ops: new(op.Ops),
+		Constraints: Constraints{
+			Max: image.Pt(200, 200),
+		},
+		Metric: unit.Metric{PxPerDp: 1},
+	}
+	var cs [4]Constraints
+	child := func(i int, sz image.Point) Widget {
+		return func(gtx Context) Dimensions {
+			cs[i] = gtx.Constraints
+			return Dimensions{Size: sz}
+		}
+	}
+	g := Grid{
+		Columns:   []Track{{Size: 50}, {Weight: 1}},
+		Rows:      []Track{{}, {}, {Weight: 1}},
+		ColumnGap: 10,
+		RowGap:    5,
+	}
+	dims := g.Layout(gtx,
+		Cell(0, 0, child(0, image.Pt(30, 20))),
+		Cell(0, 1, child(1, image.Pt(100, 40))),
+		Cell(1, 0, child(2, image.Pt(150, 10))).Span(1, 2),
+		Cell(2, 1, child(3, image.Pt(10, 10))).Align(SE),
+	)
+	want := [4]Constraints{
+		{Max: image.Pt(50, 190)},
+		{Max: image.Pt(140, 190)},
+		{Max: image.Pt(200, 190)},
+		{Max: image.Pt(140, 190)},
+	}
+	if cs != want {
+		t.Errorf("got constraints %v, want %v", cs, want)
+	}
+	// The weighted row takes the height left after the 40 and 10 pixel
+	// rows and gaps.
+	if got, want := dims.Size, image.Pt(200, 200); got != want {
+		t.Errorf("got size %v, want %v", got, want)
+	}
+
+	// Content sized tracks fit their children.
+	dims = Grid{ColumnGap: 10}.Layout(gtx,
+		Cell(0, 0, child(0, image.Pt(30, 20))),
+		Cell(1, 0, child(1, image.Pt(50, 20))),
+		Cell(0, 1, child(2, image.Pt(20, 30))),
+		Cell(2, 0, child(3, image.Pt(100, 10))).Span(1, 2),
+	)
+	if got, want := dims.Size, image.Pt(100, 60); got != want {
+		t.Errorf("got size %v, want %v", got, want)
+	}
+
+	// Negative cells are the first row and column.
+	dims = Grid{}.Layout(gtx,
+		Cell(-1, -2, child(0, image.Pt(30, 20))),
+	)
+	if got, want := dims.Size, image.Pt(30, 20); got != want {
+		t.Errorf("got size %v, want %v", got, want)
+	}
+}
+
+func TestWrap(t *testing.T) {
+	gtx := Context{
+		Ops: new(op.Ops),
+		Constraints: Constraints{
+			Max: image.Pt(100, 100),
+		},
+		Metric: unit.Metric{PxPerDp: 1},
+	}
+	var cs []Constraints
+	el := func(gtx Context, i int) Dimensions {
+		cs = append(cs, gtx.Constraints)
+		return Dimensions{Size: image.Pt(40, 10+i)}
+	}
+	w := Wrap{MainGap: 10, CrossGap: 5}
+	dims := w.Layout(gtx, 5, el)
+	// Lines of 2, 2 and 1 children, as tall as their tallest child.
+	if got, want := dims.Size, image.Pt(90, 11+13+14+2*5); got != want {
+		t.Errorf("got size %v, want %v", got, want)
+	}
+	for i, c := range cs {
+		if want := (Constraints{Max: gtx.Constraints.Max}); c != want {
+			t.Errorf("child %d: got constraints %v, want %v", i, c, want)
+		}
+	}
+	gtx.Constraints.Min = image.Pt(100, 0)
+	dims = Wrap{Axis: Vertical}.Layout(gtx, 12, el)
+	if got, want := dims.Size, image.Pt(100, 95); got != want {
+		t.Errorf("got size %v, want %v", got, want)
+	}
+}
+
+func TestSpacingDistribute(t *testing.T) {
+	for _, tc := range []struct {
+		s              Spacing
+		start, between int
+	}{
+		{SpaceEnd, 0, 0},
+		{SpaceStart, 60, 0},
+		{SpaceSides, 30, 0},
+		{SpaceAround, 10, 20},
+		{SpaceBetween, 0, 30},
+		{SpaceEvenly, 15, 15},
+	} {
+		start, between := tc.s.distribute(60, 3)
+		if start != tc.start || between != tc.between {
+			t.Errorf("%v: got %d, %d, want %d, %d", tc.s, start, between, tc.start, tc.between)
+		}
+	}
+}
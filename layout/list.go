@@ -4,10 +4,13 @@ package layout
 
 import (
 	"image"
+	"math"
+	"time"
 
 	"github.com/xiaoshengduan/gio-fly/gesture"
 	"github.com/xiaoshengduan/gio-fly/op"
 	"github.com/xiaoshengduan/gio-fly/op/clip"
+	"github.com/xiaoshengduan/gio-fly/unit"
 )
 
 type scrollChild struct {
@@ -27,6 +30,18 @@ type List struct {
 	ScrollToEnd bool
 	// Alignment is the cross axis alignment of list elements.
 	Alignment Alignment
+	// ScrollDuration is the duration of the scroll animation of ScrollTo,
+	// ScrollBy and EnsureVisible. Zero means no animation.
+	ScrollDuration time.Duration
+	// Snap, if not SnapNone, settles the list on an item when the user
+	// releases a drag or fling.
+	Snap Snap
+	// SnapAlignment is the alignment of the item settled on by Snap:
+	// Start, Middle or End.
+	SnapAlignment Alignment
+	// Overscroll configures the response to touch drags and flings past
+	// the ends of the list.
+	Overscroll Overscroll
 
 	cs          Constraints
 	scroll      gesture.Scroll
@@ -44,6 +59,51 @@ type List struct {
 	maxSize  int
 	children []scrollChild
 	dir      iterationDir
+	// childFirst is the index of the first of children.
+	childFirst int
+
+	// anim is the programmatic scroll in progress.
+	anim struct {
+		active bool
+		// to reports whether the scroll is towards an item, or by a
+		// distance.
+		to    bool
+		index int
+		align Alignment
+		// dist is the distance left to scroll by.
+		dist  int
+		start time.Time
+		// done is the eased fraction of the animation completed.
+		done float64
+		// duration is the duration of the animation.
+		duration time.Duration
+	}
+	// snap tracks drag gestures for Snap.
+	snap struct {
+		dragging bool
+		// from is the item settled on before the drag.
+		from int
+	}
+	// over tracks overscrolling.
+	over struct {
+		dragging bool
+		// raw is the overscroll distance before resistance, negative
+		// before the start of the list.
+		raw int
+		// limit is the pixel size of Overscroll.Limit.
+		limit int
+		// release is the start time of the spring back, and from the
+		// raw distance it started from.
+		release time.Time
+		from    int
+		// refreshed is set when a pull to refresh is triggered.
+		refreshed bool
+	}
+	// target is the item to align after it is measured.
+	target struct {
+		set   bool
+		align Alignment
+	}
 }
 
 // ListElement is a function that computes the dimensions of
@@ -52,6 +112,62 @@ type ListElement func(gtx Context, index int) Dimensions
 
 type iterationDir uint8
 
+// Snap is the snapping behavior of a List.
+type Snap uint8
+
+const (
+	// SnapNone lets the list come to rest anywhere.
+	SnapNone Snap = iota
+	// SnapItem settles the list on the item closest to where a drag or
+	// fling would have come to rest.
+	SnapItem
+	// SnapPage settles the list on the next or previous item, in the
+	// direction of the gesture, or back on the current item if the
+	// gesture was too short. Every gesture advances at most one item.
+	SnapPage
+)
+
+// Overscroll configures the overscrolling of a List, which is enabled
+// if the effect is not OverscrollNone or RefreshDistance is set.
+type Overscroll struct {
+	// Effect is the visual effect of overscrolling.
+	Effect OverscrollEffect
+	// Limit is the largest overscroll distance, approached with
+	// increasing resistance. Zero means 100dp.
+	Limit unit.Dp
+	// RefreshDistance, if not zero, enables pull to refresh: releasing a
+	// drag past the start of the list by RefreshDistance or more is
+	// reported by Refreshed. RefreshDistance must be less than Limit.
+	RefreshDistance unit.Dp
+}
+
+// OverscrollEffect is the visual effect of overscrolling a List.
+type OverscrollEffect uint8
+
+const (
+	// OverscrollNone stops the list abruptly at its ends.
+	OverscrollNone OverscrollEffect = iota
+	// OverscrollStretch moves the content past the ends of the list, and
+	// springs it back on release, like a rubber band.
+	OverscrollStretch
+	// OverscrollGlow leaves the content in place. The List reports the
+	// overscroll distance for drawing a glow at the end of the list, as
+	// material.List does.
+	OverscrollGlow
+)
+
+const (
+	// defaultOverscrollLimit is the overscroll limit for a zero
+	// Overscroll.Limit.
+	defaultOverscrollLimit = unit.Dp(100)
+	// overscrollDuration is the duration of the spring back.
+	overscrollDuration = 300 * time.Millisecond
+)
+
+// snapDuration is the duration of the settling animation when
+// ScrollDuration is zero.
+const snapDuration = 250 * time.Millisecond
+
 // Position is a List scroll offset represented as an offset from the top edge
 // of a child element.
 type Position struct {
@@ -91,10 +207,11 @@ func (l *List) init(gtx Context, len int) {
 		panic("unfinished child")
 	}
 	l.cs = gtx.Constraints
+	l.update(gtx)
+	l.animate(gtx, len)
 	l.maxSize = 0
 	l.children = l.children[:0]
 	l.len = len
-	l.update(gtx)
 	if l.Position.First < 0 {
 		l.Position.Offset = 0
 		l.Position.First = 0
@@ -121,6 +238,7 @@ func (l *List) Layout(gtx Context, len int, w ListElement) Dimensions {
 		dims := w(gtx, l.index())
 		call := child.Stop()
 		l.end(dims, call)
+		l.alignTarget()
 		laidOutTotalLength += l.Axis.Convert(dims.Size).X
 		numLaidOut++
 	}
@@ -130,6 +248,8 @@ func (l *List) Layout(gtx Context, len int, w ListElement) Dimensions {
 	} else {
 		l.Position.Length = 0
 	}
+	l.target.set = false
+	l.childFirst = l.Position.First
 	return l.layout(gtx.Ops, macro)
 }
 
@@ -144,6 +264,7 @@ func (l *List) Dragging() bool {
 
 func (l *List) update(gtx Context) {
 	d := l.scroll.Scroll(gtx.Metric, gtx, gtx.Now, gesture.Axis(l.Axis))
+	d = l.overscroll(gtx, d)
 	l.scrollDelta = d
 	l.Position.Offset += d
 }
@@ -351,6 +472,341 @@ func (l *List) layout(ops *op.Ops, macro op.MacroOp) Dimensions {
 	}
 	l.scroll.Add(ops, scrollRange)
 
+	if over := l.Overscrolled(); over != 0 && l.Overscroll.Effect == OverscrollStretch {
+		defer op.Offset(l.Axis.Convert(image.Pt(-over, 0))).Push(ops).Pop()
+	}
 	call.Add(ops)
 	return Dimensions{Size: dims}
 }
+
+// ScrollTo scrolls the list to the item at index, placing it at the
+// start, middle or end of the list according to align.
+func (l *List) ScrollTo(index int, align Alignment) {
+	l.scrollTo(index, align, l.ScrollDuration)
+}
+
+func (l *List) scrollTo(index int, align Alignment, d time.Duration) {
+	l.anim.active = true
+	l.anim.duration = d
+	l.anim.to = true
+	l.anim.index = index
+	l.anim.align = align
+	l.anim.start = time.Time{}
+	l.anim.done = 0
+}
+
+// ScrollBy scrolls the list by px pixels. Positive distances scroll
+// towards the end.
+func (l *List) ScrollBy(px int) {
+	if !l.anim.active || l.anim.to {
+		l.anim.dist = 0
+	}
+	l.anim.active = true
+	l.anim.duration = l.ScrollDuration
+	l.anim.to = false
+	l.anim.dist += px
+	l.anim.start = time.Time{}
+	l.anim.done = 0
+}
+
+// EnsureVisible scrolls the least distance that makes the item at index
+// fully visible, according to the Position of the last Layout.
+func (l *List) EnsureVisible(index int) {
+	p := l.Position
+	switch last := p.First + p.Count - 1; {
+	case index < p.First, index == p.First && p.Offset > 0:
+		l.ScrollTo(index, Start)
+	case index > last, index == last && p.OffsetLast < 0:
+		l.ScrollTo(index, End)
+	}
+}
+
+// Scrolling reports whether a scroll started by ScrollTo, ScrollBy or
+// EnsureVisible is in progress.
+func (l *List) Scrolling() bool {
+	return l.anim.active
+}
+
+// animate advances the programmatic scroll, if any. It must be called
+// before the children of the previous layout are discarded.
+func (l *List) animate(gtx Context, len int) {
+	if l.anim.active && (l.scrollDelta != 0 || l.scroll.State() == gesture.StateDragging) {
+		// The user took over.
+		l.anim.active = false
+	}
+	l.snapGesture()
+	if !l.anim.active {
+		return
+	}
+	if l.anim.start.IsZero() {
+		l.anim.start = gtx.Now
+	}
+	t := 1.0
+	if d := l.anim.duration; d > 0 {
+		t = float64(gtx.Now.Sub(l.anim.start)) / float64(d)
+	}
+	l.Position.BeforeEnd = true
+	if t >= 1 {
+		l.anim.active = false
+		if l.anim.to {
+			l.Position.First = clampIndex(l.anim.index, len)
+			l.Position.Offset = 0
+			l.target.set = true
+			l.target.align = l.anim.align
+		} else {
+			l.Position.Offset += l.anim.dist
+		}
+		return
+	}
+	dist := l.anim.dist
+	if l.anim.to {
+		dist = l.distanceTo(l.anim.index, l.anim.align)
+	}
+	// Ease out quadratically, and move the remaining fraction of the
+	// distance to cover this frame.
+	eased := 1 - (1-t)*(1-t)
+	step := int(math.Round(float64(dist) * (eased - l.anim.done) / (1 - l.anim.done)))
+	l.anim.done = eased
+	l.anim.dist -= step
+	l.Position.Offset += step
+	op.InvalidateOp{}.Add(gtx.Ops)
+}
+
+// snapGesture starts the settling animation at the end of a drag or
+// fling.
+func (l *List) snapGesture() {
+	dragging := l.scroll.State() == gesture.StateDragging
+	wasDragging := l.snap.dragging
+	l.snap.dragging = dragging
+	if l.Snap == SnapNone || l.len == 0 {
+		return
+	}
+	switch {
+	case dragging && !wasDragging:
+		// Discount any scrolling by the drag in this frame.
+		l.snap.from = l.nearest(-l.scrollDelta)
+	case !dragging && wasDragging:
+		fling := l.scroll.FlingDistance()
+		l.scroll.Stop()
+		index := l.nearest(fling)
+		if l.Snap == SnapPage {
+			from := l.snap.from
+			switch {
+			case fling > 0:
+				index = from + 1
+			case fling < 0:
+				index = from - 1
+			}
+			index = max(min(index, from+1), from-1)
+		}
+		d := l.ScrollDuration
+		if d == 0 {
+			d = snapDuration
+		}
+		l.scrollTo(clampIndex(index, l.len), l.SnapAlignment, d)
+	}
+}
+
+// nearest returns the index of the item closest to alignment with
+// SnapAlignment, after scrolling by dist.
+func (l *List) nearest(dist int) int {
+	if len(l.children) == 0 {
+		return l.Position.First
+	}
+	off := func(i int) int {
+		d := l.distanceTo(i, l.SnapAlignment) - dist
+		if d < 0 {
+			d = -d
+		}
+		return d
+	}
+	best := l.childFirst
+	for i := range l.children {
+		if idx := l.childFirst + i; off(idx) < off(best) {
+			best = idx
+		}
+	}
+	// Continue past the measured items, for long flings.
+	for best > 0 && off(best-1) < off(best) {
+		best--
+	}
+	for best < l.len-1 && off(best+1) < off(best) {
+		best++
+	}
+	return best
+}
+
+// alignTarget aligns the item scrolled to by ScrollTo, once it is
+// measured.
+func (l *List) alignTarget() {
+	if !l.target.set || len(l.children) != 1 {
+		return
+	}
+	l.target.set = false
+	_, vsize := l.Axis.mainConstraint(l.cs)
+	space := vsize - l.Axis.Convert(l.children[0].size).X
+	switch l.target.align {
+	case End:
+		l.Position.Offset -= space
+	case Middle:
+		l.Position.Offset -= space / 2
+	}
+}
+
+// distanceTo estimates the distance to scroll for aligning the item at
+// index according to align. Items not measured by the last Layout are
+// assumed to have the average size from Position.Length.
+func (l *List) distanceTo(index int, align Alignment) int {
+	index = clampIndex(index, l.len)
+	start, size, ok := l.itemPosition(index)
+	if !ok {
+		avg := 0
+		if l.len > 0 {
+			avg = l.Position.Length / l.len
+		}
+		size = avg
+		if n := len(l.children); index < l.childFirst {
+			start, _, _ = l.itemPosition(l.childFirst)
+			start -= (l.childFirst - index) * avg
+		} else if n > 0 {
+			last := l.childFirst + n - 1
+			var lastSize int
+			start, lastSize, _ = l.itemPosition(last)
+			start += lastSize + (index-last-1)*avg
+		}
+	}
+	_, vsize := l.Axis.mainConstraint(l.cs)
+	switch align {
+	case End:
+		start -= vsize - size
+	case Middle:
+		start -= (vsize - size) / 2
+	}
+	return start
+}
+
+// itemPosition returns the position of the item at index relative to the
+// start of the list, along with its size, if it was measured by the last
+// Layout.
+func (l *List) itemPosition(index int) (pos, size int, ok bool) {
+	rel := index - l.childFirst
+	if rel < 0 || rel >= len(l.children) {
+		return 0, 0, false
+	}
+	pos = -l.Position.Offset
+	for i := range l.children {
+		size := l.Axis.Convert(l.children[i].size).X
+		j := i + l.childFirst
+		if j < l.Position.First {
+			pos -= size
+		}
+		if j < index {
+			pos += size
+		}
+	}
+	return pos, l.Axis.Convert(l.children[rel].size).X, true
+}
+
+func clampIndex(index, len int) int {
+	if index >= len {
+		index = len - 1
+	}
+	if index < 0 {
+		index = 0
+	}
+	return index
+}
+
+// Overscrolled returns the distance the list is overscrolled, after
+// resistance. The distance is negative past the start of the list, and
+// positive past the end.
+func (l *List) Overscrolled() int {
+	raw, lim := l.over.raw, l.over.limit
+	if raw == 0 || lim == 0 {
+		return 0
+	}
+	abs := raw
+	if abs < 0 {
+		abs = -abs
+	}
+	return raw * lim / (abs + lim)
+}
+
+// Refreshed reports whether the user triggered a pull to refresh since
+// the last call to Refreshed.
+func (l *List) Refreshed() bool {
+	r := l.over.refreshed
+	l.over.refreshed = false
+	return r
+}
+
+// overscroll tracks the part of the scroll distance d past the ends of
+// the list, and returns the remaining distance.
+func (l *List) overscroll(gtx Context, d int) int {
+	o := &l.over
+	cfg := l.Overscroll
+	if cfg.Effect == OverscrollNone && cfg.RefreshDistance == 0 {
+		o.raw = 0
+		return d
+	}
+	lim := cfg.Limit
+	if lim == 0 {
+		lim = defaultOverscrollLimit
+	}
+	o.limit = gtx.Dp(lim)
+	state := l.scroll.State()
+	released := o.dragging && state != gesture.StateDragging
+	o.dragging = state == gesture.StateDragging
+	if state != gesture.StateIdle {
+		o.release = time.Time{}
+		// Scrolling back towards the list reduces the overscroll first.
+		if o.raw < 0 && d > 0 || o.raw > 0 && d < 0 {
+			r := o.raw + d
+			if r != 0 && (r < 0) != (o.raw < 0) {
+				o.raw, d = 0, r
+			} else {
+				o.raw, d = r, 0
+			}
+		}
+		// The distances to the ends are known if the items at the ends
+		// were measured by the last Layout.
+		if pos, _, ok := l.itemPosition(0); ok && d < 0 {
+			if avail := max(-pos, 0); d < -avail {
+				o.raw += d + avail
+				d = -avail
+			}
+		}
+		if pos, size, ok := l.itemPosition(l.len - 1); ok && d > 0 {
+			_, vsize := l.Axis.mainConstraint(l.cs)
+			if avail := max(pos+size-vsize, 0); d > avail {
+				o.raw += d - avail
+				d = avail
+			}
+		}
+		if l.len == 0 {
+			o.raw += d
+			d = 0
+		}
+		if state == gesture.StateFlinging && o.raw != 0 {
+			// Bounce off the end.
+			l.scroll.Stop()
+		}
+	}
+	if released && cfg.RefreshDistance > 0 && -l.Overscrolled() >= gtx.Dp(cfg.RefreshDistance) {
+		o.refreshed = true
+	}
+	if o.raw != 0 && l.scroll.State() == gesture.StateIdle {
+		if o.release.IsZero() {
+			o.release = gtx.Now
+			o.from = o.raw
+		}
+		t := float64(gtx.Now.Sub(o.release)) / float64(overscrollDuration)
+		if t >= 1 {
+			o.raw = 0
+		} else {
+			o.raw = int(math.Round(float64(o.from) * (1 - t) * (1 - t)))
+			op.InvalidateOp{}.Add(gtx.Ops)
+		}
+	}
+	return d
+}
@@ -3,8 +3,11 @@
 package layout
 
 import (
+	"fmt"
 	"image"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/xiaoshengduan/gio-fly/f32"
 	"github.com/xiaoshengduan/gio-fly/io/event"
@@ -195,3 +198,318 @@ func TestExtraChildren(t *testing.T) {
 		t.Errorf("laid out %d of %d children", count, all)
 	}
 }
+
+func TestListScrollTo(t *testing.T) {
+	l := List{Axis: Vertical}
+	gtx := Context{
+		Ops:         new(op.Ops),
+		Constraints: Exact(image.Pt(20, 50)),
+	}
+	el := func(gtx Context, idx int) Dimensions {
+		return Dimensions{Size: image.Pt(20, 10)}
+	}
+	layout := func() {
+		gtx.Ops.Reset()
+		l.Layout(gtx, 100, el)
+	}
+	check := func(first, offset int) {
+		t.Helper()
+		if p := l.Position; p.First != first || p.Offset != offset {
+			t.Errorf("got position %d+%d, want %d+%d", p.First, p.Offset, first, offset)
+		}
+	}
+	layout()
+	l.ScrollTo(20, Start)
+	layout()
+	check(20, 0)
+	l.ScrollTo(50, End)
+	layout()
+	check(46, 0)
+	l.ScrollTo(50, Middle)
+	layout()
+	check(48, 0)
+	// Already visible.
+	l.EnsureVisible(49)
+	layout()
+	check(48, 0)
+	l.EnsureVisible(60)
+	layout()
+	check(56, 0)
+	l.ScrollBy(15)
+	layout()
+	check(57, 5)
+	l.ScrollTo(1000, End)
+	layout()
+	check(95, 0)
+
+	// Animated scrolling moves towards the target over
+	// ScrollDuration.
+	l.ScrollDuration = 100 * time.Millisecond
+	gtx.Now = time.Now()
+	l.ScrollTo(10, Start)
+	layout()
+	check(95, 0)
+	prev := l.Position.First
+	for i := 0; i < 9; i++ {
+		gtx.Now = gtx.Now.Add(10 * time.Millisecond)
+		layout()
+		if !l.Scrolling() {
+			t.Fatalf("scroll animation ended early at frame %d", i)
+		}
+		if f := l.Position.First; f >= prev || f < 10 {
+			t.Errorf("frame %d: first item %d didn't move from %d towards 10", i, f, prev)
+		}
+		prev = l.Position.First
+	}
+	gtx.Now = gtx.Now.Add(10 * time.Millisecond)
+	layout()
+	check(10, 0)
+	if l.Scrolling() {
+		t.Error("scroll animation didn't end")
+	}
+}
+
+func TestListSnap(t *testing.T) {
+	for _, tc := range []struct {
+		label string
+		snap  Snap
+		align Alignment
+		// drag is the drag distance, over dt.
+		drag  float32
+		dt    time.Duration
+		first int
+		off   int
+	}{
+		{label: "item back", snap: SnapItem, drag: 4, dt: time.Second, first: 0},
+		{label: "item forward", snap: SnapItem, drag: 17, dt: time.Second, first: 2},
+		{label: "item middle", snap: SnapItem, align: Middle, drag: 14, dt: time.Second, first: 1, off: 3},
+		{label: "item fling", snap: SnapItem, drag: 20, dt: 50 * time.Millisecond, first: 11},
+		{label: "page limit", snap: SnapPage, drag: 17, dt: time.Second, first: 1},
+		{label: "page back", snap: SnapPage, drag: 4, dt: time.Second, first: 0},
+		{label: "page fling", snap: SnapPage, drag: 20, dt: 50 * time.Millisecond, first: 1},
+	} {
+		t.Run(tc.label, func(t *testing.T) {
+			r := new(router.Router)
+			gtx := Context{
+				Ops:         new(op.Ops),
+				Constraints: Exact(image.Pt(20, 25)),
+				Queue:       r,
+				Now:         time.Unix(1000, 0),
+			}
+			l := List{Axis: Vertical, Snap: tc.snap, SnapAlignment: tc.align}
+			layout := func() {
+				gtx.Ops.Reset()
+				l.Layout(gtx, 100, func(gtx Context, i int) Dimensions {
+					return Dimensions{Size: image.Pt(20, 10)}
+				})
+				r.Frame(gtx.Ops)
+			}
+			layout()
+			start := gtx.Now
+			var evts []event.Event
+			evts = append(evts, pointer.Event{
+				Source:   pointer.Touch,
+				Type:     pointer.Press,
+				Position: f32.Pt(10, 20),
+				Time:     time.Duration(start.UnixNano()),
+			})
+			const steps = 4
+			for i := 1; i <= steps; i++ {
+				evts = append(evts, pointer.Event{
+					Source:   pointer.Touch,
+					Type:     pointer.Move,
+					Position: f32.Pt(10, 20-tc.drag*float32(i)/steps),
+					Time:     time.Duration(start.UnixNano()) + tc.dt*time.Duration(i)/steps,
+				})
+			}
+			r.Queue(evts...)
+			layout()
+			gtx.Now = start.Add(tc.dt)
+			r.Queue(pointer.Event{
+				Source:   pointer.Touch,
+				Type:     pointer.Release,
+				Position: f32.Pt(10, 20-tc.drag),
+				Time:     time.Duration(start.UnixNano()) + tc.dt,
+			})
+			layout()
+			for i := 0; i < 20; i++ {
+				gtx.Now = gtx.Now.Add(50 * time.Millisecond)
+				layout()
+			}
+			if l.Scrolling() {
+				t.Error("snap animation didn't end")
+			}
+			if got, want := l.Position.First, tc.first; got != want {
+				t.Errorf("got first %d, want %d", got, want)
+			}
+			if got, want := l.Position.Offset, tc.off; got != want {
+				t.Errorf("got offset %d, want %d", got, want)
+			}
+		})
+	}
+}
+
+func TestListOverscroll(t *testing.T) {
+	r := new(router.Router)
+	gtx := Context{
+		Ops:         new(op.Ops),
+		Constraints: Exact(image.Pt(20, 25)),
+		Queue:       r,
+		Now:         time.Unix(1000, 0),
+	}
+	l := List{
+		Axis: Vertical,
+		Overscroll: Overscroll{
+			Effect:          OverscrollStretch,
+			Limit:           100,
+			RefreshDistance: 20,
+		},
+	}
+	layout := func() {
+		gtx.Ops.Reset()
+		l.Layout(gtx, 5, func(gtx Context, i int) Dimensions {
+			return Dimensions{Size: image.Pt(20, 10)}
+		})
+		r.Frame(gtx.Ops)
+	}
+	drag := func(typ pointer.Type, y float32) {
+		gtx.Now = gtx.Now.Add(time.Second)
+		r.Queue(pointer.Event{
+			Source:   pointer.Touch,
+			Type:     typ,
+			Position: f32.Pt(10, y),
+			Time:     time.Duration(gtx.Now.UnixNano()),
+		})
+		layout()
+	}
+	layout()
+	// Pull down from the start of the list.
+	drag(pointer.Press, 0)
+	drag(pointer.Move, 10)
+	drag(pointer.Move, 60)
+	if got, want := l.Overscrolled(), -37; got != want {
+		t.Errorf("pulled: got overscroll %d, want %d", got, want)
+	}
+	if l.Position.First != 0 || l.Position.Offset != 0 {
+		t.Errorf("pulled: got position %+v, want start", l.Position)
+	}
+	// Scrolling back reduces the overscroll first.
+	drag(pointer.Move, 40)
+	if got, want := l.Overscrolled(), -28; got != want {
+		t.Errorf("pushed: got overscroll %d, want %d", got, want)
+	}
+	if l.Refreshed() {
+		t.Error("refresh triggered before release")
+	}
+	drag(pointer.Release, 40)
+	if !l.Refreshed() {
+		t.Error("refresh not triggered")
+	}
+	if l.Refreshed() {
+		t.Error("refresh reported twice")
+	}
+	// The overscroll springs back.
+	gtx.Now = gtx.Now.Add(overscrollDuration / 2)
+	layout()
+	if got := l.Overscrolled(); got >= 0 || got <= -28 {
+		t.Errorf("springing back: got overscroll %d", got)
+	}
+	gtx.Now = gtx.Now.Add(overscrollDuration)
+	layout()
+	if got := l.Overscrolled(); got != 0 {
+		t.Errorf("sprung back: got overscroll %d, want 0", got)
+	}
+
+	// Push up past the end of the list.
+	drag(pointer.Press, 20)
+	drag(pointer.Move, 10)
+	drag(pointer.Move, -30)
+	drag(pointer.Move, -50)
+	if got, want := l.Overscrolled(), 31; got != want {
+		t.Errorf("pushed past end: got overscroll %d, want %d", got, want)
+	}
+	if got, want := l.Position.First+l.Position.Count, 5; got != want {
+		t.Errorf("pushed past end: got last item %d, want %d", got, want)
+	}
+	drag(pointer.Release, -50)
+	if l.Refreshed() {
+		t.Error("refresh triggered at the end of the list")
+	}
+}
+
+func TestGroupedList(t *testing.T) {
+	var g GroupedList
+	g.Axis = Vertical
+	gtx := Context{
+		Ops:         new(op.Ops),
+		Constraints: Exact(image.Pt(20, 30)),
+	}
+	sections := []int{3, 2, 4}
+	var headers []int
+	header := func(gtx Context, s int) Dimensions {
+		headers = append(headers, s)
+		return labeled(fmt.Sprintf("header %d", s), image.Pt(20, 10))(gtx)
+	}
+	el := func(gtx Context, s, i int) Dimensions {
+		if i < 0 || i >= sections[s] {
+			t.Errorf("item %d out of range for section %d", i, s)
+		}
+		return Dimensions{Size: image.Pt(20, 10)}
+	}
+	layout := func(first, offset int) {
+		t.Helper()
+		headers = headers[:0]
+		g.Position.First, g.Position.Offset = first, offset
+		gtx.Ops.Reset()
+		g.Layout(gtx, sections, header, el)
+	}
+	layout(0, 0)
+	if got, want := headers, []int{0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got headers %v, want %v", got, want)
+	}
+	// The header of the first section sticks while its items are
+	// visible.
+	layout(2, 5)
+	if got, want := headers, []int{1, 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got headers %v, want %v", got, want)
+	}
+	// A header partly scrolled out of view is laid out once, and sticks.
+	layout(0, 5)
+	if got, want := headers, []int{0, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got headers %v, want %v", got, want)
+	}
+	if !hasLabelAt(gtx.Ops, "header 0", image.Pt(0, 0)) {
+		t.Error("header 0 didn't stick")
+	}
+	// The next header pushes the stuck header out of view.
+	layout(3, 5)
+	if !hasLabelAt(gtx.Ops, "header 0", image.Pt(0, -5)) {
+		t.Error("header 0 wasn't pushed out by header 1")
+	}
+	if got, want := g.Index(1, 0), 5; got != want {
+		t.Errorf("got index %d, want %d", got, want)
+	}
+	for _, tc := range []struct{ index, section, item int }{
+		{0, 0, -1}, {3, 0, 2}, {4, 1, -1}, {5, 1, 0}, {7, 2, -1}, {11, 2, 3},
+	} {
+		if s, i := g.Locate(tc.index); s != tc.section || i != tc.item {
+			t.Errorf("Locate(%d) = %d, %d, want %d, %d", tc.index, s, i, tc.section, tc.item)
+		}
+	}
+	var empty GroupedList
+	if s, i := empty.Locate(0); s != -1 || i != -1 {
+		t.Errorf("Locate(0) = %d, %d without sections, want -1, -1", s, i)
+	}
+}
+
+// hasLabelAt reports whether ops contain a widget labeled label at pos.
+func hasLabelAt(ops *op.Ops, label string, pos image.Point) bool {
+	var r router.Router
+	r.Frame(ops)
+	for _, n := range r.AppendSemantics(nil) {
+		if n.Desc.Label == label && n.Desc.Bounds.Min == pos {
+			return true
+		}
+	}
+	return false
+}
@@ -89,16 +89,17 @@ func (s Stack) Layout(gtx Context, children ...StackChild) Dimensions {
 
 	maxSZ = gtx.Constraints.Constrain(maxSZ)
 	var baseline int
+	align := s.Alignment.Physical(gtx.Locale.Direction)
 	for _, ch := range children {
 		sz := ch.dims.Size
 		var p image.Point
-		switch s.Alignment {
+		switch align {
 		case N, S, Center:
 			p.X = (maxSZ.X - sz.X) / 2
 		case NE, SE, E:
 			p.X = maxSZ.X - sz.X
 		}
-		switch s.Alignment {
+		switch align {
 		case W, Center, E:
 			p.Y = (maxSZ.Y - sz.Y) / 2
 		case SW, S, SE:
@@ -0,0 +1,152 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package layout
+
+import (
+	"image"
+
+	"github.com/xiaoshengduan/gio-fly/io/system"
+	"github.com/xiaoshengduan/gio-fly/op"
+	"github.com/xiaoshengduan/gio-fly/unit"
+)
+
+// Wrap lays out child elements along an axis, starting a new line
+// whenever a child doesn't fit in the space left in the current line.
+// Horizontal lines run from right to left when gtx.Locale.Direction is
+// right-to-left.
+type Wrap struct {
+	// Axis is the main axis, either Horizontal or Vertical.
+	Axis Axis
+	// Spacing controls the distribution of the space left in each line,
+	// relative to the longest line or the minimum constraint.
+	Spacing Spacing
+	// Alignment is the alignment of children in the cross axis of their
+	// line.
+	Alignment Alignment
+	// MainGap is the space between children in a line.
+	MainGap unit.Dp
+	// CrossGap is the space between lines.
+	CrossGap unit.Dp
+}
+
+// wrapChild is the scratch space for a Wrap child.
+type wrapChild struct {
+	call op.CallOp
+	dims Dimensions
+}
+
+// wrapLine is a line of Wrap children.
+type wrapLine struct {
+	// start and end are the indices of the children in the line.
+	start, end int
+	// size is the main axis size of the line, including gaps.
+	size int
+	// cross is the cross axis size of the line.
+	cross int
+	// baseline is the largest distance from the top of a child to its
+	// baseline.
+	baseline int
+}
+
+// Layout len children. Every child is laid out with no minimum
+// constraints and the maximum constraints of the Wrap.
+func (w Wrap) Layout(gtx Context, len int, el ListElement) Dimensions {
+	cs := gtx.Constraints
+	mainMin, mainMax := w.Axis.mainConstraint(cs)
+	crossMin, crossMax := w.Axis.crossConstraint(cs)
+	mainGap, crossGap := gtx.Dp(w.MainGap), gtx.Dp(w.CrossGap)
+	children := make([]wrapChild, len)
+	var lines []wrapLine
+	var cur wrapLine
+	cgtx := gtx
+	cgtx.Constraints = w.Axis.constraints(0, mainMax, 0, crossMax)
+	for i := range children {
+		macro := op.Record(gtx.Ops)
+		dims := el(cgtx, i)
+		children[i] = wrapChild{call: macro.Stop(), dims: dims}
+		sz := w.Axis.Convert(dims.Size)
+		if cur.end > cur.start {
+			if cur.size+mainGap+sz.X > mainMax {
+				lines = append(lines, cur)
+				cur = wrapLine{start: i, end: i}
+			} else {
+				cur.size += mainGap
+			}
+		}
+		cur.end = i + 1
+		cur.size += sz.X
+		cur.cross = max(cur.cross, sz.Y)
+		cur.baseline = max(cur.baseline, dims.Size.Y-dims.Baseline)
+	}
+	if cur.end > cur.start {
+		lines = append(lines, cur)
+	}
+	mainSize := mainMin
+	for _, l := range lines {
+		mainSize = max(mainSize, l.size)
+	}
+	rtl := w.Axis == Horizontal && gtx.Locale.Direction.Progression() == system.TowardOrigin
+	var crossSize int
+	for i, l := range lines {
+		if i > 0 {
+			crossSize += crossGap
+		}
+		pos, between := w.Spacing.distribute(mainSize-l.size, l.end-l.start)
+		for _, c := range children[l.start:l.end] {
+			sz := w.Axis.Convert(c.dims.Size)
+			var cross int
+			switch w.Alignment {
+			case End:
+				cross = l.cross - sz.Y
+			case Middle:
+				cross = (l.cross - sz.Y) / 2
+			case Baseline:
+				if w.Axis == Horizontal {
+					cross = l.baseline - (c.dims.Size.Y - c.dims.Baseline)
+				}
+			}
+			main := pos
+			if rtl {
+				main = mainSize - pos - sz.X
+			}
+			pt := w.Axis.Convert(image.Pt(main, crossSize+cross))
+			trans := op.Offset(pt).Push(gtx.Ops)
+			c.call.Add(gtx.Ops)
+			trans.Pop()
+			pos += sz.X + mainGap + between
+		}
+		crossSize += l.cross
+	}
+	sz := w.Axis.Convert(image.Pt(mainSize, max(crossSize, crossMin)))
+	sz = cs.Constrain(sz)
+	dims := Dimensions{Size: sz}
+	if len > 0 {
+		dims.Baseline = sz.Y - lines[0].baseline
+	}
+	return dims
+}
+
+// distribute returns the space before the first of n children and
+// between children, for distributing space according to s.
+func (s Spacing) distribute(space, n int) (start, between int) {
+	if space <= 0 || n == 0 {
+		return 0, 0
+	}
+	switch s {
+	case SpaceStart:
+		start = space
+	case SpaceSides:
+		start = space / 2
+	case SpaceEvenly:
+		start = space / (1 + n)
+		between = start
+	case SpaceAround:
+		start = space / (n * 2)
+		between = space / n
+	case SpaceBetween:
+		if n > 1 {
+			between = space / (n - 1)
+		}
+	}
+	return start, between
+}
@@ -69,7 +69,11 @@ func (p Op) add(o *op.Ops) {
 	}
 	bo := binary.LittleEndian
 	if path.hasSegments {
-		data := ops.Write(&o.Internal, ops.TypePathLen)
+		var glyphs interface{}
+		if path.glyphs != nil {
+			glyphs = path.glyphs
+		}
+		data := ops.Write1(&o.Internal, ops.TypePathLen, glyphs)
 		data[0] = byte(ops.TypePath)
 		bo.PutUint64(data[1:], path.hash)
 		path.spec.Add(o)
@@ -96,7 +100,10 @@ func (p Op) add(o *op.Ops) {
 	bo.PutUint32(data[9:], uint32(bounds.Max.X))
 	bo.PutUint32(data[13:], uint32(bounds.Max.Y))
 	if p.outline {
-		data[17] = byte(1)
+		data[17] |= ops.ClipOutline
+	}
+	if path.atlas {
+		data[17] |= ops.ClipAtlas
 	}
 	data[18] = byte(path.shape)
 }
@@ -114,6 +121,22 @@ type PathSpec struct {
 	bounds      image.Rectangle
 	shape       ops.Shape
 	hash        uint64
+	// atlas marks the path for drawing from a texture atlas.
+	atlas bool
+	// glyphs are the glyphs marked by Path.Glyph, if any.
+	glyphs *[]ops.Glyph
+}
+
+// Atlas returns a copy of the path whose glyphs, as marked by Path.Glyph,
+// renderers may rasterize once into a texture atlas and draw as textured
+// quads in this and later frames, instead of rasterizing the path in every
+// frame. Every glyph outline is rasterized at its size for every subpixel
+// position, so Atlas is for small glyphs, such as those of text. Renderers
+// without an atlas, transformed paths and glyphs too large for the atlas
+// are drawn as vector paths.
+func (p PathSpec) Atlas() PathSpec {
+	p.atlas = true
+	return p
 }
 
 // Path constructs a Op clip path described by lines and
@@ -132,6 +155,13 @@ type Path struct {
 	hasSegments bool
 	bounds      f32internal.Rectangle
 	hash        maphash.Hash
+	// size is the size of the segment data.
+	size int
+	// glyphs are the glyphs of the path. glyph reports whether the last
+	// of them is incomplete, and glyphSegments whether it has segments.
+	glyphs        []ops.Glyph
+	glyph         bool
+	glyphSegments bool
 }
 
 // Pos returns the current pen position.
@@ -153,14 +183,51 @@ func (p *Path) Begin(o *op.Ops) {
 // End returns a PathSpec ready to use in clipping operations.
 func (p *Path) End() PathSpec {
 	p.gap()
+	p.endGlyph()
 	c := p.macro.Stop()
 	ops.EndMulti(p.ops)
-	return PathSpec{
+	spec := PathSpec{
 		spec:        c,
 		hasSegments: p.hasSegments,
 		bounds:      p.bounds.Round(),
 		hash:        p.hash.Sum64(),
 	}
+	if len(p.glyphs) > 0 {
+		glyphs := p.glyphs
+		spec.glyphs = &glyphs
+	}
+	return spec
+}
+
+// Glyph starts a glyph at origin. The segments up to the next call to
+// Glyph or End make up the glyph, and id identifies its outline, such as
+// by its font, glyph index and size. Renderers that draw the glyphs from
+// an atlas, as described by PathSpec.Atlas, rasterize the outline of an
+// id once for every subpixel position.
+func (p *Path) Glyph(id uint64, origin f32.Point) {
+	p.gap()
+	p.end()
+	p.start = p.pen
+	p.endGlyph()
+	p.glyphs = append(p.glyphs, ops.Glyph{ID: id, Origin: origin, Start: p.size})
+	p.glyph = true
+	p.glyphSegments = false
+}
+
+// endGlyph completes the current glyph, if any. Glyphs without segments
+// are dropped.
+func (p *Path) endGlyph() {
+	if !p.glyph {
+		return
+	}
+	p.glyph = false
+	g := &p.glyphs[len(p.glyphs)-1]
+	g.End = p.size
+	if g.Start == g.End {
+		p.glyphs = p.glyphs[:len(p.glyphs)-1]
+		return
+	}
+	g.Bounds = g.Bounds.Sub(g.Origin)
 }
 
 // Move moves the pen by the amount specified by delta.
@@ -184,10 +251,7 @@ func (p *Path) gap() {
 	if p.pen != p.start {
 		// A closed contour starts and ends in the same point.
 		// This move creates a gap in the contour, register it.
-		data := ops.WriteMulti(p.ops, scene.CommandSize+4)
-		bo := binary.LittleEndian
-		bo.PutUint32(data[0:], uint32(p.contour))
-		p.cmd(data[4:], scene.Gap(p.pen, p.start))
+		p.cmd(scene.Gap(p.pen, p.start))
 	}
 }
 
@@ -204,17 +268,19 @@ func (p *Path) Line(delta f32.Point) {
 
 // LineTo moves the pen to the absolute point specified, recording a line.
 func (p *Path) LineTo(to f32.Point) {
-	data := ops.WriteMulti(p.ops, scene.CommandSize+4)
-	bo := binary.LittleEndian
-	bo.PutUint32(data[0:], uint32(p.contour))
-	p.cmd(data[4:], scene.Line(p.pen, to))
+	p.cmd(scene.Line(p.pen, to))
 	p.pen = to
 	p.expand(to)
 }
 
-func (p *Path) cmd(data []byte, c scene.Command) {
-	ops.EncodeCommand(data, c)
-	p.hash.Write(data)
+// cmd records a segment of the current contour.
+func (p *Path) cmd(c scene.Command) {
+	data := ops.WriteMulti(p.ops, scene.CommandSize+4)
+	bo := binary.LittleEndian
+	bo.PutUint32(data[0:], uint32(p.contour))
+	ops.EncodeCommand(data[4:], c)
+	p.hash.Write(data[4:])
+	p.size += len(data)
 }
 
 func (p *Path) expand(pt f32.Point) {
@@ -222,23 +288,36 @@ func (p *Path) expand(pt f32.Point) {
 		p.hasSegments = true
 		p.bounds = f32internal.Rectangle{Min: pt, Max: pt}
 	} else {
-		b := p.bounds
-		if pt.X < b.Min.X {
-			b.Min.X = pt.X
-		}
-		if pt.Y < b.Min.Y {
-			b.Min.Y = pt.Y
-		}
-		if pt.X > b.Max.X {
-			b.Max.X = pt.X
-		}
-		if pt.Y > b.Max.Y {
-			b.Max.Y = pt.Y
+		p.bounds = expandRect(p.bounds, pt)
+	}
+	if p.glyph {
+		g := &p.glyphs[len(p.glyphs)-1]
+		if !p.glyphSegments {
+			p.glyphSegments = true
+			g.Bounds = f32internal.Rectangle{Min: pt, Max: pt}
+		} else {
+			g.Bounds = expandRect(g.Bounds, pt)
 		}
-		p.bounds = b
 	}
 }
 
+// expandRect returns the smallest rectangle that contains b and pt.
+func expandRect(b f32internal.Rectangle, pt f32.Point) f32internal.Rectangle {
+	if pt.X < b.Min.X {
+		b.Min.X = pt.X
+	}
+	if pt.Y < b.Min.Y {
+		b.Min.Y = pt.Y
+	}
+	if pt.X > b.Max.X {
+		b.Max.X = pt.X
+	}
+	if pt.Y > b.Max.Y {
+		b.Max.Y = pt.Y
+	}
+	return b
+}
+
 // Quad records a quadratic Bézier from the pen to end
 // with the control point ctrl.
 func (p *Path) Quad(ctrl, to f32.Point) {
@@ -250,10 +329,7 @@ func (p *Path) Quad(ctrl, to f32.Point) {
 // QuadTo records a quadratic Bézier from the pen to end
 // with the control point ctrl, with absolute coordinates.
 func (p *Path) QuadTo(ctrl, to f32.Point) {
-	data := ops.WriteMulti(p.ops, scene.CommandSize+4)
-	bo := binary.LittleEndian
-	bo.PutUint32(data[0:], uint32(p.contour))
-	p.cmd(data[4:], scene.Quad(p.pen, ctrl, to))
+	p.cmd(scene.Quad(p.pen, ctrl, to))
 	p.pen = to
 	p.expand(ctrl)
 	p.expand(to)
@@ -294,10 +370,7 @@ func (p *Path) CubeTo(ctrl0, ctrl1, to f32.Point) {
 	if ctrl0 == p.pen && ctrl1 == p.pen && to == p.pen {
 		return
 	}
-	data := ops.WriteMulti(p.ops, scene.CommandSize+4)
-	bo := binary.LittleEndian
-	bo.PutUint32(data[0:], uint32(p.contour))
-	p.cmd(data[4:], scene.Cubic(p.pen, ctrl0, ctrl1, to))
+	p.cmd(scene.Cubic(p.pen, ctrl0, ctrl1, to))
 	p.pen = to
 	p.expand(ctrl0)
 	p.expand(ctrl1)
@@ -0,0 +1,279 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package text
+
+import (
+	"fmt"
+	"image"
+	"sort"
+
+	"golang.org/x/image/math/fixed"
+
+	"github.com/xiaoshengduan/gio-fly/io/system"
+)
+
+// Block is a block of laid out lines, positioned the way the widgets
+// draw them: every line is aligned within Width, the baseline of the
+// first line is at its ascent, and every other line starts below the
+// descent of the previous line. Block maps between rune offsets and
+// positions for hit testing, and for drawing carets and selections.
+type Block struct {
+	Lines     []Line
+	Alignment Alignment
+	// Width is the width the lines are aligned within.
+	Width int
+}
+
+// Caret is a position between two runes of a Block.
+type Caret struct {
+	// Runes is the offset of the rune after the caret.
+	Runes int
+	// Line is the index of the line of the caret.
+	Line int
+	// X is the horizontal position of the caret.
+	X fixed.Int26_6
+	// Y is the baseline of the line of the caret.
+	Y int
+	// Ascent and Descent are the visible extents of the line above and
+	// below its baseline.
+	Ascent, Descent fixed.Int26_6
+}
+
+// Span is a horizontal extent of a line.
+type Span struct {
+	Min, Max fixed.Int26_6
+}
+
+// Align returns the position of the left edge of a line of the given
+// width and direction within maxWidth.
+func (a Alignment) Align(dir system.TextDirection, width fixed.Int26_6, maxWidth int) fixed.Int26_6 {
+	mw := fixed.I(maxWidth)
+	if dir.Progression() == system.TowardOrigin {
+		switch a {
+		case Start, Justify:
+			a = End
+		case End:
+			a = Start
+		}
+	}
+	switch a {
+	case Middle:
+		return fixed.I(((mw - width) / 2).Floor())
+	case End:
+		return fixed.I((mw - width).Floor())
+	case Start, Justify:
+		return 0
+	default:
+		panic(fmt.Errorf("unknown alignment %v", a))
+	}
+}
+
+// Rect returns the rectangle of c drawn width pixels wide.
+func (c Caret) Rect(width int) image.Rectangle {
+	x := c.X.Round()
+	return image.Rectangle{
+		Min: image.Pt(x-width/2, c.Y-c.Ascent.Ceil()),
+		Max: image.Pt(x+width-width/2, c.Y+c.Descent.Ceil()),
+	}
+}
+
+// LineX returns the position of the left edge of a line.
+func (b Block) LineX(line int) fixed.Int26_6 {
+	l := b.Lines[line]
+	return b.Alignment.Align(l.Layout.Direction, l.Width, b.Width)
+}
+
+// LineY returns the position of the baseline of a line.
+func (b Block) LineY(line int) int {
+	y := 0
+	for i, l := range b.Lines[:line+1] {
+		if i > 0 {
+			y += b.Lines[i-1].Descent.Ceil()
+		}
+		y += l.Ascent.Ceil()
+	}
+	return y
+}
+
+// LineAt returns the index of the line containing the rune at offset
+// runes. Offsets past the last line are on the last line.
+func (b Block) LineAt(runes int) int {
+	i := sort.Search(len(b.Lines), func(i int) bool {
+		r := b.Lines[i].Layout.Runes
+		return r.Offset+r.Count > runes
+	})
+	if i == len(b.Lines) {
+		i--
+	}
+	return i
+}
+
+// ClusterAt returns the indices of the line and the glyph cluster
+// containing the rune at offset runes. The cluster of the end of the
+// text is the number of clusters of the last line.
+func (b Block) ClusterAt(runes int) (line, cluster int) {
+	line = b.LineAt(runes)
+	clusters := b.Lines[line].Layout.Clusters
+	cluster = sort.Search(len(clusters), func(i int) bool {
+		r := clusters[i].Runes
+		return r.Offset+r.Count > runes
+	})
+	return line, cluster
+}
+
+// Caret returns the caret before the rune at offset runes, clamped to
+// the text.
+func (b Block) Caret(runes int) Caret {
+	if len(b.Lines) == 0 {
+		return Caret{}
+	}
+	last := b.Lines[len(b.Lines)-1].Layout.Runes
+	if end := last.Offset + last.Count; runes > end {
+		runes = end
+	}
+	if runes < 0 {
+		runes = 0
+	}
+	line := b.LineAt(runes)
+	l := b.Lines[line]
+	return Caret{
+		Runes:   runes,
+		Line:    line,
+		X:       b.LineX(line) + l.Layout.CaretX(runes),
+		Y:       b.LineY(line),
+		Ascent:  -l.Bounds.Min.Y,
+		Descent: l.Bounds.Max.Y,
+	}
+}
+
+// Hit returns the caret closest to the point (x, y). Like a click in an
+// editor, a point right of the end of a line that is not the last selects
+// the position before its last rune, which is usually a space or newline.
+func (b Block) Hit(x fixed.Int26_6, y int) Caret {
+	if len(b.Lines) == 0 {
+		return Caret{}
+	}
+	line, base := 0, 0
+	for i, l := range b.Lines {
+		if i > 0 {
+			base += b.Lines[i-1].Descent.Ceil()
+		}
+		base += l.Ascent.Ceil()
+		line = i
+		if base+l.Descent.Ceil() >= y {
+			break
+		}
+	}
+	l := b.Lines[line].Layout
+	return b.Caret(l.Hit(x-b.LineX(line), line == len(b.Lines)-1))
+}
+
+// Selection returns the rectangles covering the runes in [start, end),
+// from the ascent to the descent of their lines. Lines mixing
+// directions may have several rectangles.
+func (b Block) Selection(start, end int) []image.Rectangle {
+	if start > end {
+		start, end = end, start
+	}
+	var rects []image.Rectangle
+	y := 0
+	for i, l := range b.Lines {
+		if i > 0 {
+			y += b.Lines[i-1].Descent.Ceil()
+		}
+		y += l.Ascent.Ceil()
+		r := l.Layout.Runes
+		if r.Offset >= end {
+			break
+		}
+		if r.Offset+r.Count <= start {
+			continue
+		}
+		x := b.LineX(i)
+		for _, s := range l.Layout.Spans(start, end) {
+			rects = append(rects, image.Rectangle{
+				Min: image.Pt((x + s.Min).Round(), y-l.Ascent.Ceil()),
+				Max: image.Pt((x + s.Max).Round(), y+l.Descent.Ceil()),
+			})
+		}
+	}
+	return rects
+}
+
+// CaretX returns the position of the caret before the rune at offset
+// runes of l, from the left edge of l. Offsets at or after the end of l
+// are at the end of its last cluster.
+func (l Layout) CaretX(runes int) fixed.Int26_6 {
+	for _, c := range l.Clusters {
+		if runes >= c.Runes.Offset && runes < c.Runes.Offset+c.Runes.Count {
+			return c.X + fixed.Int26_6(runes-c.Runes.Offset)*c.RuneWidth()
+		}
+	}
+	if n := len(l.Clusters); n > 0 {
+		last := l.Clusters[n-1]
+		return last.X + last.Advance
+	}
+	return 0
+}
+
+// Hit returns the offset of the rune position of l closest to x, from
+// the left edge of l. The position after the last rune is included if
+// end is set.
+func (l Layout) Hit(x fixed.Int26_6, end bool) int {
+	best, bestDist := l.Runes.Offset, fixed.Int26_6(-1)
+	lineEnd := l.Runes.Offset + l.Runes.Count
+	for _, c := range l.Clusters {
+		for k := 0; k <= c.Runes.Count; k++ {
+			r := c.Runes.Offset + k
+			if r == lineEnd && !end {
+				continue
+			}
+			d := c.X + fixed.Int26_6(k)*c.RuneWidth() - x
+			if d < 0 {
+				d = -d
+			}
+			if bestDist < 0 || d < bestDist {
+				best, bestDist = r, d
+			}
+		}
+	}
+	return best
+}
+
+// Spans returns the extents of the runes of l in [start, end), from the
+// left edge of l, from left to right. Adjacent extents are merged.
+func (l Layout) Spans(start, end int) []Span {
+	var spans []Span
+	for _, c := range l.Clusters {
+		s, e := c.Runes.Offset, c.Runes.Offset+c.Runes.Count
+		if start > s {
+			s = start
+		}
+		if end < e {
+			e = end
+		}
+		if s >= e {
+			continue
+		}
+		x0 := c.X + fixed.Int26_6(s-c.Runes.Offset)*c.RuneWidth()
+		x1 := c.X + fixed.Int26_6(e-c.Runes.Offset)*c.RuneWidth()
+		if x0 > x1 {
+			x0, x1 = x1, x0
+		}
+		spans = append(spans, Span{Min: x0, Max: x1})
+	}
+	sort.Slice(spans, func(i, j int) bool {
+		return spans[i].Min < spans[j].Min
+	})
+	merged := spans[:0]
+	for _, s := range spans {
+		if n := len(merged); n > 0 && s.Min <= merged[n-1].Max {
+			if s.Max > merged[n-1].Max {
+				merged[n-1].Max = s.Max
+			}
+			continue
+		}
+		merged = append(merged, s)
+	}
+	return merged
+}
@@ -0,0 +1,134 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package text
+
+import (
+	"image"
+	"reflect"
+	"testing"
+
+	"golang.org/x/image/math/fixed"
+
+	"github.com/xiaoshengduan/gio-fly/io/system"
+)
+
+// testLine returns a left-to-right line starting at rune offset, with a
+// rune for every width in widths. Negative widths are right-to-left runes
+// as wide as their absolute value.
+func testLine(offset int, widths ...int) Line {
+	l := Line{
+		Ascent:  fixed.I(8),
+		Descent: fixed.I(2),
+		Bounds:  fixed.Rectangle26_6{Min: fixed.Point26_6{Y: -fixed.I(8)}, Max: fixed.Point26_6{Y: fixed.I(2)}},
+	}
+	l.Layout.Runes = Range{Offset: offset, Count: len(widths)}
+	l.Layout.Direction = system.LTR
+	var x fixed.Int26_6
+	for i, w := range widths {
+		c := GlyphCluster{
+			Advance: fixed.I(w),
+			Runes:   Range{Offset: offset + i, Count: 1},
+			Glyphs:  Range{Offset: i, Count: 1},
+			X:       x,
+		}
+		if w < 0 {
+			c.X -= c.Advance
+		}
+		l.Layout.Clusters = append(l.Layout.Clusters, c)
+		l.Width += fixed.I(abs(w))
+		x += fixed.I(abs(w))
+	}
+	return l
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func TestBlock(t *testing.T) {
+	b := Block{
+		Lines:     []Line{testLine(0, 10, 10, 10), testLine(3, 10, 10)},
+		Alignment: Start,
+		Width:     100,
+	}
+	if got := b.LineY(1); got != 18 {
+		t.Errorf("LineY(1) = %d, expected 18", got)
+	}
+	c := b.Caret(4)
+	if c.Line != 1 || c.X != fixed.I(10) || c.Y != 18 {
+		t.Errorf("Caret(4) = %+v", c)
+	}
+	if got, exp := c.Rect(2), image.Rect(9, 10, 11, 20); got != exp {
+		t.Errorf("caret rectangle %v, expected %v", got, exp)
+	}
+	if c := b.Caret(100); c.Runes != 5 || c.X != fixed.I(20) {
+		t.Errorf("Caret(100) = %+v", c)
+	}
+	if line, cluster := b.ClusterAt(4); line != 1 || cluster != 1 {
+		t.Errorf("ClusterAt(4) = %d, %d", line, cluster)
+	}
+	if line, cluster := b.ClusterAt(5); line != 1 || cluster != 2 {
+		t.Errorf("ClusterAt(5) = %d, %d", line, cluster)
+	}
+	hits := []struct {
+		x, y  int
+		runes int
+	}{
+		{0, 0, 0},
+		{14, 5, 1},
+		{16, 5, 2},
+		// Right of a line before the last.
+		{90, 5, 2},
+		{90, 15, 5},
+		{90, 100, 5},
+	}
+	for _, h := range hits {
+		if c := b.Hit(fixed.I(h.x), h.y); c.Runes != h.runes {
+			t.Errorf("Hit(%d, %d) = %d, expected %d", h.x, h.y, c.Runes, h.runes)
+		}
+	}
+	exp := []image.Rectangle{image.Rect(10, 0, 30, 10), image.Rect(0, 10, 10, 20)}
+	if got := b.Selection(1, 4); !reflect.DeepEqual(got, exp) {
+		t.Errorf("Selection(1, 4) = %v, expected %v", got, exp)
+	}
+
+	// Alignment moves the lines.
+	b.Alignment = End
+	if c := b.Caret(0); c.X != fixed.I(70) {
+		t.Errorf("end aligned caret at %v, expected 70", c.X)
+	}
+}
+
+func TestBlockMixed(t *testing.T) {
+	// Two left-to-right runes, two right-to-left runes and another
+	// left-to-right rune.
+	l := testLine(0, 10, 10, -10, -10, 10)
+	l.Layout.Clusters[2].X, l.Layout.Clusters[3].X = fixed.I(40), fixed.I(30)
+	b := Block{Lines: []Line{l}, Width: 100}
+	if !l.Layout.Mixed() {
+		t.Fatal("line doesn't mix directions")
+	}
+	if got, exp := l.Layout.VisualOrder(), []int{0, 1, 3, 2, 4}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("visual order %v, expected %v", got, exp)
+	}
+	if got, exp := l.Layout.LogicalOrder(), []int{0, 1, 3, 2, 4}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("logical order %v, expected %v", got, exp)
+	}
+	if c := b.Caret(2); c.X != fixed.I(40) {
+		t.Errorf("caret of rune 2 at %v, expected 40", c.X)
+	}
+	if c := b.Hit(fixed.I(29), 0); c.Runes != 3 {
+		t.Errorf("hit at 29 found rune %d, expected 3", c.Runes)
+	}
+	if c := b.Hit(fixed.I(39), 0); c.Runes != 2 {
+		t.Errorf("hit at 39 found rune %d, expected 2", c.Runes)
+	}
+	// Selecting across the direction boundary covers two separate spans.
+	exp := []Span{{Min: fixed.I(10), Max: fixed.I(20)}, {Min: fixed.I(30), Max: fixed.I(40)}}
+	if got := l.Layout.Spans(1, 3); !reflect.DeepEqual(got, exp) {
+		t.Errorf("Spans(1, 3) = %v, expected %v", got, exp)
+	}
+}
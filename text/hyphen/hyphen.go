@@ -0,0 +1,178 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+// Package hyphen implements Liang's hyphenation algorithm with TeX
+// hyphenation patterns, such as the hyph-*.tex files of the hyph-utf8
+// project.
+package hyphen
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// Patterns hyphenates words with a set of hyphenation patterns and
+// exceptions. Patterns implements text.Hyphenator.
+type Patterns struct {
+	// LeftMin and RightMin are the minimum number of runes before and
+	// after a hyphen. Zero means 2 and 3, the values of TeX for English.
+	LeftMin, RightMin int
+
+	// patterns maps the letters of patterns to their values between
+	// and around the letters.
+	patterns map[string][]uint8
+	// exceptions maps words to their hyphen positions.
+	exceptions map[string][]int
+	// maxLen is the length in runes of the longest pattern.
+	maxLen int
+}
+
+// Parse reads patterns and exceptions in the TeX format. Patterns are
+// listed in a \patterns{...} group and exceptions in a \hyphenation{...}
+// group. A file without groups is read as a list of patterns. Comments
+// start with %.
+func Parse(r io.Reader) (*Patterns, error) {
+	p := &Patterns{
+		patterns:   make(map[string][]uint8),
+		exceptions: make(map[string][]int),
+	}
+	const (
+		none = iota
+		patterns
+		exceptions
+	)
+	group := none
+	grouped := false
+	s := bufio.NewScanner(r)
+	line := 0
+	for s.Scan() {
+		line++
+		l := s.Text()
+		if i := strings.IndexByte(l, '%'); i >= 0 {
+			l = l[:i]
+		}
+		for _, f := range strings.Fields(l) {
+			switch {
+			case strings.HasPrefix(f, `\patterns{`):
+				group, grouped = patterns, true
+				f = strings.TrimPrefix(f, `\patterns{`)
+			case strings.HasPrefix(f, `\hyphenation{`):
+				group, grouped = exceptions, true
+				f = strings.TrimPrefix(f, `\hyphenation{`)
+			case strings.HasPrefix(f, `\`):
+				// Skip other commands.
+				continue
+			}
+			end := strings.HasSuffix(f, "}")
+			f = strings.TrimSuffix(f, "}")
+			if f != "" {
+				switch {
+				case group == exceptions:
+					p.addException(f)
+				case group == patterns || !grouped:
+					if err := p.addPattern(f); err != nil {
+						return nil, fmt.Errorf("hyphen: line %d: %v", line, err)
+					}
+				}
+			}
+			if end {
+				group = none
+			}
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// addPattern adds a pattern such as "hy3ph" or ".ach4".
+func (p *Patterns) addPattern(pat string) error {
+	var letters []rune
+	values := []uint8{0}
+	for _, r := range pat {
+		if '0' <= r && r <= '9' {
+			values[len(values)-1] = uint8(r - '0')
+			continue
+		}
+		letters = append(letters, unicode.ToLower(r))
+		values = append(values, 0)
+	}
+	if len(letters) == 0 {
+		return fmt.Errorf("pattern %q has no letters", pat)
+	}
+	p.patterns[string(letters)] = values
+	if len(letters) > p.maxLen {
+		p.maxLen = len(letters)
+	}
+	return nil
+}
+
+// addException adds a hyphenated word such as "ta-ble".
+func (p *Patterns) addException(word string) {
+	var letters []rune
+	var hyphens []int
+	for _, r := range word {
+		if r == '-' {
+			hyphens = append(hyphens, len(letters))
+			continue
+		}
+		letters = append(letters, unicode.ToLower(r))
+	}
+	p.exceptions[string(letters)] = hyphens
+}
+
+// Hyphenate returns the increasing indices of the runes of word that may
+// be preceded by a hyphen.
+func (p *Patterns) Hyphenate(word []rune) []int {
+	leftMin, rightMin := p.LeftMin, p.RightMin
+	if leftMin == 0 {
+		leftMin = 2
+	}
+	if rightMin == 0 {
+		rightMin = 3
+	}
+	if len(word) < leftMin+rightMin {
+		return nil
+	}
+	lower := make([]rune, len(word)+2)
+	lower[0], lower[len(lower)-1] = '.', '.'
+	for i, r := range word {
+		lower[i+1] = unicode.ToLower(r)
+	}
+	if exc, ok := p.exceptions[string(lower[1:len(lower)-1])]; ok {
+		// Copy the hyphens allowed by LeftMin and RightMin.
+		var hyphens []int
+		for _, i := range exc {
+			if i >= leftMin && i <= len(word)-rightMin {
+				hyphens = append(hyphens, i)
+			}
+		}
+		return hyphens
+	}
+	// values[i] is the value of the gap before lower[i].
+	values := make([]uint8, len(lower)+1)
+	for i := range lower {
+		for j := i + 1; j <= len(lower) && j-i <= p.maxLen; j++ {
+			pat, ok := p.patterns[string(lower[i:j])]
+			if !ok {
+				continue
+			}
+			for k, v := range pat {
+				if v > values[i+k] {
+					values[i+k] = v
+				}
+			}
+		}
+	}
+	var hyphens []int
+	for i := leftMin; i <= len(word)-rightMin; i++ {
+		// The gap before word[i] is the gap before lower[i+1].
+		if values[i+1]%2 == 1 {
+			hyphens = append(hyphens, i)
+		}
+	}
+	return hyphens
+}
@@ -0,0 +1,62 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package hyphen
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// liang are the patterns from Liang's thesis for hyphenating
+// "hyphenation", in a TeX pattern file.
+const liang = `% Patterns for testing.
+\patterns{
+hy3ph he2n hena4 hen5at
+1na n2at 1tio 2io o2n
+}
+\hyphenation{
+ta-ble o-ver-flow-ing
+}
+`
+
+func TestHyphenate(t *testing.T) {
+	p, err := Parse(strings.NewReader(liang))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		word string
+		exp  []int
+	}{
+		{"hyphenation", []int{2, 6}},
+		{"Hyphenation", []int{2, 6}},
+		{"table", []int{2}},
+		{"overflowing", []int{4, 8}},
+		{"hyp", nil},
+	}
+	for _, test := range tests {
+		got := p.Hyphenate([]rune(test.word))
+		if !reflect.DeepEqual(got, test.exp) {
+			t.Errorf("Hyphenate(%q) = %v, expected %v", test.word, got, test.exp)
+		}
+	}
+	// Exceptions are copied.
+	p.Hyphenate([]rune("table"))[0] = 0
+	if got := p.Hyphenate([]rune("table")); !reflect.DeepEqual(got, []int{2}) {
+		t.Errorf("Hyphenate(%q) = %v after modifying the result", "table", got)
+	}
+}
+
+func TestParsePatternList(t *testing.T) {
+	p, err := Parse(strings.NewReader("hy3ph he2n hena4 hen5at\n1na n2at 1tio 2io o2n\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, exp := p.Hyphenate([]rune("hyphenation")), []int{2, 6}; !reflect.DeepEqual(got, exp) {
+		t.Errorf("got %v, expected %v", got, exp)
+	}
+	if _, err := Parse(strings.NewReader("12")); err == nil {
+		t.Error("parsed a pattern without letters")
+	}
+}
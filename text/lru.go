@@ -3,7 +3,10 @@
 package text
 
 import (
+	"image/color"
+
 	"github.com/xiaoshengduan/gio-fly/io/system"
+	"github.com/xiaoshengduan/gio-fly/op"
 	"github.com/xiaoshengduan/gio-fly/op/clip"
 	"github.com/benoitkugler/textlayout/fonts"
 	"golang.org/x/image/math/fixed"
@@ -19,17 +22,30 @@ type pathCache struct {
 	head, tail *path
 }
 
+type instanceCache struct {
+	m          map[Font]*instanceElem
+	head, tail *instanceElem
+}
+
 type layoutElem struct {
 	next, prev *layoutElem
 	key        layoutKey
 	layout     []Line
 }
 
+type instanceElem struct {
+	next, prev *instanceElem
+	key        Font
+	face       *faceCache
+}
+
 type path struct {
 	next, prev *path
 	key        pathKey
 	val        clip.PathSpec
+	call       op.CallOp
 	gids       []fonts.GID
+	faces      []Face
 }
 
 type layoutKey struct {
@@ -37,11 +53,16 @@ type layoutKey struct {
 	maxWidth int
 	str      string
 	locale   system.Locale
+	params   Parameters
 }
 
 type pathKey struct {
 	ppem    fixed.Int26_6
 	gidHash uint64
+	// color distinguishes the color glyphs of ShapeColor, and fg is
+	// their text color.
+	color bool
+	fg    color.NRGBA
 }
 
 const maxSize = 1000
@@ -85,6 +106,45 @@ func (l *layoutCache) insert(lt *layoutElem) {
 	lt.next.prev = lt
 }
 
+func (c *instanceCache) Get(k Font) (*faceCache, bool) {
+	if v, ok := c.m[k]; ok {
+		c.remove(v)
+		c.insert(v)
+		return v.face, true
+	}
+	return nil, false
+}
+
+func (c *instanceCache) Put(k Font, f *faceCache) {
+	if c.m == nil {
+		c.m = make(map[Font]*instanceElem)
+		c.head = new(instanceElem)
+		c.tail = new(instanceElem)
+		c.head.prev = c.tail
+		c.tail.next = c.head
+	}
+	val := &instanceElem{key: k, face: f}
+	c.m[k] = val
+	c.insert(val)
+	if len(c.m) > maxSize {
+		oldest := c.tail.next
+		c.remove(oldest)
+		delete(c.m, oldest.key)
+	}
+}
+
+func (c *instanceCache) remove(v *instanceElem) {
+	v.next.prev = v.prev
+	v.prev.next = v.next
+}
+
+func (c *instanceCache) insert(v *instanceElem) {
+	v.next = c.head
+	v.prev = c.head.prev
+	v.prev.next = v
+	v.next.prev = v
+}
+
 func gidsMatch(gids []fonts.GID, l Layout) bool {
 	if len(gids) != len(l.Glyphs) {
 		return false
@@ -97,16 +157,47 @@ func gidsMatch(gids []fonts.GID, l Layout) bool {
 	return true
 }
 
+func facesMatch(faces []Face, l Layout) bool {
+	for i := range faces {
+		if faces[i] != l.Glyphs[i].Face {
+			return false
+		}
+	}
+	return true
+}
+
 func (c *pathCache) Get(k pathKey, l Layout) (clip.PathSpec, bool) {
-	if v, ok := c.m[k]; ok && gidsMatch(v.gids, l) {
-		c.remove(v)
-		c.insert(v)
+	if v, ok := c.get(k, l); ok {
 		return v.val, true
 	}
 	return clip.PathSpec{}, false
 }
 
+func (c *pathCache) GetColor(k pathKey, l Layout) (op.CallOp, bool) {
+	if v, ok := c.get(k, l); ok {
+		return v.call, true
+	}
+	return op.CallOp{}, false
+}
+
+func (c *pathCache) get(k pathKey, l Layout) (*path, bool) {
+	if v, ok := c.m[k]; ok && gidsMatch(v.gids, l) && facesMatch(v.faces, l) {
+		c.remove(v)
+		c.insert(v)
+		return v, true
+	}
+	return nil, false
+}
+
 func (c *pathCache) Put(k pathKey, l Layout, v clip.PathSpec) {
+	c.put(&path{key: k, val: v}, l)
+}
+
+func (c *pathCache) PutColor(k pathKey, l Layout, call op.CallOp) {
+	c.put(&path{key: k, call: call}, l)
+}
+
+func (c *pathCache) put(val *path, l Layout) {
 	if c.m == nil {
 		c.m = make(map[pathKey]*path)
 		c.head = new(path)
@@ -115,11 +206,13 @@ func (c *pathCache) Put(k pathKey, l Layout, v clip.PathSpec) {
 		c.tail.next = c.head
 	}
 	gids := make([]fonts.GID, len(l.Glyphs))
+	faces := make([]Face, len(l.Glyphs))
 	for i := range l.Glyphs {
 		gids[i] = l.Glyphs[i].ID
+		faces[i] = l.Glyphs[i].Face
 	}
-	val := &path{key: k, val: v, gids: gids}
-	c.m[k] = val
+	val.gids, val.faces = gids, faces
+	c.m[val.key] = val
 	c.insert(val)
 	if len(c.m) > maxSize {
 		oldest := c.tail.next
@@ -21,6 +21,18 @@ func TestLayoutLRU(t *testing.T) {
 	testLRU(t, put, get)
 }
 
+func TestInstanceLRU(t *testing.T) {
+	c := new(instanceCache)
+	put := func(i int) {
+		c.Put(Font{Variations: strconv.Itoa(i)}, nil)
+	}
+	get := func(i int) bool {
+		_, ok := c.Get(Font{Variations: strconv.Itoa(i)})
+		return ok
+	}
+	testLRU(t, put, get)
+}
+
 func TestPathLRU(t *testing.T) {
 	c := new(pathCache)
 	put := func(i int) {
@@ -4,24 +4,35 @@ package text
 
 import (
 	"encoding/binary"
+	"fmt"
 	"hash/maphash"
+	"image/color"
 	"io"
+	"sort"
 	"strings"
 
 	"golang.org/x/image/math/fixed"
 
 	"github.com/xiaoshengduan/gio-fly/io/system"
+	"github.com/xiaoshengduan/gio-fly/op"
 	"github.com/xiaoshengduan/gio-fly/op/clip"
 )
 
 // Shaper implements layout and shaping of text.
 type Shaper interface {
 	// Layout a text according to a set of options.
-	Layout(font Font, size fixed.Int26_6, maxWidth int, lc system.Locale, txt io.RuneReader) ([]Line, error)
+	Layout(font Font, size fixed.Int26_6, maxWidth int, lc system.Locale, params Parameters, txt io.RuneReader) ([]Line, error)
 	// LayoutString is Layout for strings.
-	LayoutString(font Font, size fixed.Int26_6, maxWidth int, lc system.Locale, str string) []Line
+	LayoutString(font Font, size fixed.Int26_6, maxWidth int, lc system.Locale, params Parameters, str string) []Line
 	// Shape a line of text and return a clipping operation for its outline.
 	Shape(font Font, size fixed.Int26_6, layout Layout) clip.PathSpec
+	// ShapeColor returns the operations for painting the color glyphs of
+	// a line of text, as described by ColorFace.ShapeColor. The color
+	// glyphs are left out of the outline returned by Shape.
+	ShapeColor(font Font, size fixed.Int26_6, layout Layout, fg color.NRGBA) op.CallOp
+	// DecorationMetrics returns the positions and thicknesses of the
+	// text decorations of a font.
+	DecorationMetrics(font Font, size fixed.Int26_6) DecorationMetrics
 }
 
 // A FontFace is a Font and a matching Face.
@@ -36,38 +47,139 @@ type FontFace struct {
 // If a font matches no registered shape, Cache falls back to the
 // first registered face.
 //
+// Runes missing from the face of a font are laid out with the first
+// registered face that has glyphs for them, in the order of registration,
+// if the face implements FallbackFace.
+//
+// Color glyphs are painted by ShapeColor if the face implements
+// ColorFace.
+//
+// Fonts with Variations or Features, and fonts whose weight is only
+// matched by a variable font, are laid out and shaped with an instance
+// of the matching face if it implements VariableFace. Malformed
+// variations and features are ignored.
+//
 // The LayoutString and ShapeString results are cached and re-used if
 // possible.
 type Cache struct {
+	// AtlasSize is the largest text size whose shapes are marked for
+	// drawing from a glyph atlas, as described by clip.PathSpec.Atlas.
+	// Larger text is drawn as vector paths. Zero disables the atlas.
+	AtlasSize fixed.Int26_6
+
 	def   Typeface
 	faces map[Font]*faceCache
+	// instances are the faces of recently requested fonts, keyed by
+	// instanceKey. They are the matching faces, or their instances for
+	// variable fonts and fonts with features.
+	instances instanceCache
 }
 
 type faceCache struct {
-	face        Face
+	face Face
+	// fallbacks are the registered faces, in order of registration.
+	fallbacks   []Face
 	layoutCache layoutCache
 	pathCache   pathCache
 	seed        maphash.Seed
 }
 
 func (c *Cache) lookup(font Font) *faceCache {
-	f := c.faceForStyle(font)
-	if f == nil {
-		font.Typeface = c.def
-		f = c.faceForStyle(font)
+	key := instanceKey(font)
+	if f, ok := c.instances.Get(key); ok {
+		return f
+	}
+	static := font
+	static.Variations, static.Features = "", ""
+	match, ok := c.faceForStyle(static)
+	if !ok {
+		static.Typeface = c.def
+		match, ok = c.faceForStyle(static)
+	}
+	if !ok {
+		return nil
+	}
+	f := c.faces[match]
+	vf, ok := f.face.(VariableFace)
+	if !ok {
+		c.instances.Put(key, f)
+		return f
+	}
+	wght := font.Weight != match.Weight && hasAxis(vf.Axes(), "wght")
+	if font.Variations == "" && font.Features == "" && !wght {
+		c.instances.Put(key, f)
+		return f
+	}
+	vars, _ := ParseVariations(font.Variations)
+	if wght && !hasVariation(vars, "wght") {
+		vars = append(vars, Variation{Tag: "wght", Value: float32(font.Weight - Normal + 400)})
+	}
+	feats, _ := ParseFeatures(font.Features)
+	inst := &faceCache{face: vf.Instance(vars, feats)}
+	// Replace the face with its instance among the fallbacks.
+	inst.fallbacks = make([]Face, len(f.fallbacks))
+	for i, fb := range f.fallbacks {
+		if fb == f.face {
+			fb = inst.face
+		}
+		inst.fallbacks[i] = fb
 	}
-	return f
+	c.instances.Put(key, inst)
+	return inst
 }
 
-func (c *Cache) faceForStyle(font Font) *faceCache {
+// instanceKey returns font with its variations and features parsed and
+// sorted by tag, so that equivalent settings share an instance.
+func instanceKey(font Font) Font {
+	if font.Variations != "" {
+		vars, _ := ParseVariations(font.Variations)
+		sort.SliceStable(vars, func(i, j int) bool {
+			return vars[i].Tag < vars[j].Tag
+		})
+		var b strings.Builder
+		for _, v := range vars {
+			fmt.Fprintf(&b, "%s=%g,", v.Tag, v.Value)
+		}
+		font.Variations = b.String()
+	}
+	if font.Features != "" {
+		feats, _ := ParseFeatures(font.Features)
+		sort.SliceStable(feats, func(i, j int) bool {
+			return feats[i].Tag < feats[j].Tag
+		})
+		var b strings.Builder
+		for _, f := range feats {
+			fmt.Fprintf(&b, "%s=%d,", f.Tag, f.Value)
+		}
+		font.Features = b.String()
+	}
+	return font
+}
+
+func (c *Cache) faceForStyle(font Font) (Font, bool) {
 	if closest, ok := c.closestFont(font); ok {
-		return c.faces[closest]
+		return closest, true
 	}
 	font.Style = Regular
-	if closest, ok := c.closestFont(font); ok {
-		return c.faces[closest]
+	return c.closestFont(font)
+}
+
+func hasAxis(axes []Axis, tag string) bool {
+	for _, a := range axes {
+		if a.Tag == tag {
+			return true
+		}
 	}
-	return nil
+	return false
+}
+
+func hasVariation(vars []Variation, tag string) bool {
+	for _, v := range vars {
+		if v.Tag == tag {
+			return true
+		}
+	}
+	return false
 }
 
 // closestFont returns the closest Font by weight, in case of equality the
@@ -102,35 +214,61 @@ func NewCache(collection []FontFace) *Cache {
 	c := &Cache{
 		faces: make(map[Font]*faceCache),
 	}
+	fallbacks := make([]Face, len(collection))
 	for i, ff := range collection {
 		if i == 0 {
 			c.def = ff.Font.Typeface
 		}
-		c.faces[ff.Font] = &faceCache{face: ff.Face}
+		c.faces[ff.Font] = &faceCache{face: ff.Face, fallbacks: fallbacks}
+		fallbacks[i] = ff.Face
 	}
 	return c
 }
 
 // Layout implements the Shaper interface.
-func (c *Cache) Layout(font Font, size fixed.Int26_6, maxWidth int, lc system.Locale, txt io.RuneReader) ([]Line, error) {
+func (c *Cache) Layout(font Font, size fixed.Int26_6, maxWidth int, lc system.Locale, params Parameters, txt io.RuneReader) ([]Line, error) {
 	cache := c.lookup(font)
-	return cache.face.Layout(size, maxWidth, lc, txt)
+	return cache.layoutText(size, maxWidth, lc, params, txt)
 }
 
 // LayoutString is a caching implementation of the Shaper interface.
-func (c *Cache) LayoutString(font Font, size fixed.Int26_6, maxWidth int, lc system.Locale, str string) []Line {
+func (c *Cache) LayoutString(font Font, size fixed.Int26_6, maxWidth int, lc system.Locale, params Parameters, str string) []Line {
 	cache := c.lookup(font)
-	return cache.layout(size, maxWidth, lc, str)
+	return cache.layout(size, maxWidth, lc, params, str)
 }
 
 // Shape is a caching implementation of the Shaper interface. Shape assumes that the layout
 // argument is unchanged from a call to Layout or LayoutString.
 func (c *Cache) Shape(font Font, size fixed.Int26_6, layout Layout) clip.PathSpec {
 	cache := c.lookup(font)
-	return cache.shape(size, layout)
+	path := cache.shape(size, layout)
+	if size <= c.AtlasSize {
+		path = path.Atlas()
+	}
+	return path
+}
+
+// ShapeColor is a caching implementation of the Shaper interface. Like
+// Shape, it assumes that the layout argument is unchanged from a call to
+// Layout or LayoutString.
+func (c *Cache) ShapeColor(font Font, size fixed.Int26_6, layout Layout, fg color.NRGBA) op.CallOp {
+	cache := c.lookup(font)
+	return cache.shapeColor(size, layout, fg)
 }
 
-func (f *faceCache) layout(ppem fixed.Int26_6, maxWidth int, lc system.Locale, str string) []Line {
+// DecorationMetrics implements the Shaper interface. Faces that are not
+// DecorationFaces have the DefaultDecorationMetrics.
+func (c *Cache) DecorationMetrics(font Font, size fixed.Int26_6) DecorationMetrics {
+	cache := c.lookup(font)
+	if cache != nil {
+		if df, ok := cache.face.(DecorationFace); ok {
+			return df.DecorationMetrics(size)
+		}
+	}
+	return DefaultDecorationMetrics(size)
+}
+
+func (f *faceCache) layout(ppem fixed.Int26_6, maxWidth int, lc system.Locale, params Parameters, str string) []Line {
 	if f == nil {
 		return nil
 	}
@@ -139,15 +277,25 @@ func (f *faceCache) layout(ppem fixed.Int26_6, maxWidth int, lc system.Locale, s
 		maxWidth: maxWidth,
 		str:      str,
 		locale:   lc,
+		params:   params,
 	}
 	if l, ok := f.layoutCache.Get(lk); ok {
 		return l
 	}
-	l, _ := f.face.Layout(ppem, maxWidth, lc, strings.NewReader(str))
+	l, _ := f.layoutText(ppem, maxWidth, lc, params, strings.NewReader(str))
 	f.layoutCache.Put(lk, l)
 	return l
 }
 
+// layoutText lays out txt with the face, falling back to the other
+// registered faces if the face supports it.
+func (f *faceCache) layoutText(ppem fixed.Int26_6, maxWidth int, lc system.Locale, params Parameters, txt io.RuneReader) ([]Line, error) {
+	if ff, ok := f.face.(FallbackFace); ok && len(f.fallbacks) > 1 {
+		return ff.LayoutFallback(ppem, maxWidth, lc, params, txt, f.fallbacks)
+	}
+	return f.face.Layout(ppem, maxWidth, lc, params, txt)
+}
+
 // hashGIDs returns a 64-bit hash value of the font GIDs contained
 // within the provided layout.
 func (f *faceCache) hashGIDs(layout Layout) uint64 {
@@ -179,3 +327,25 @@ func (f *faceCache) shape(ppem fixed.Int26_6, layout Layout) clip.PathSpec {
 	f.pathCache.Put(pk, layout, clip)
 	return clip
 }
+
+func (f *faceCache) shapeColor(ppem fixed.Int26_6, layout Layout, fg color.NRGBA) op.CallOp {
+	if f == nil {
+		return op.CallOp{}
+	}
+	cf, ok := f.face.(ColorFace)
+	if !ok {
+		return op.CallOp{}
+	}
+	pk := pathKey{
+		ppem:    ppem,
+		gidHash: f.hashGIDs(layout),
+		color:   true,
+		fg:      fg,
+	}
+	if call, ok := f.pathCache.GetColor(pk, layout); ok {
+		return call
+	}
+	call := cf.ShapeColor(ppem, layout, fg)
+	f.pathCache.PutColor(pk, layout, call)
+	return call
+}
@@ -3,9 +3,12 @@
 package text
 
 import (
+	"image/color"
 	"io"
+	"sort"
 
 	"github.com/xiaoshengduan/gio-fly/io/system"
+	"github.com/xiaoshengduan/gio-fly/op"
 	"github.com/xiaoshengduan/gio-fly/op/clip"
 	"github.com/go-text/typesetting/font"
 	"golang.org/x/image/math/fixed"
@@ -23,6 +26,10 @@ type Line struct {
 	Descent fixed.Int26_6
 	// Bounds is the visible bounds of the line.
 	Bounds fixed.Rectangle26_6
+	// Truncated is the number of runes of the line replaced by the
+	// truncator of the layout Parameters. The truncator glyphs form a
+	// single cluster covering the replaced runes.
+	Truncated int
 }
 
 // Range describes the position and quantity of a range of text elements
@@ -57,6 +64,10 @@ type Glyph struct {
 	// XOffset and YOffset describe offsets from the dot that should be
 	// applied when rendering the glyph.
 	XOffset, YOffset fixed.Int26_6
+	// Face is the face the glyph was shaped with, which is a fallback
+	// face for runes missing from the face that laid out the text. A nil
+	// Face means the face that laid out the text.
+	Face Face
 }
 
 // GlyphCluster provides metadata about a sequence of indivisible shaped
@@ -70,6 +81,11 @@ type GlyphCluster struct {
 	// Glyphs indicates the position and quantity of the glyphs within this
 	// cluster in a Layout's Glyphs slice.
 	Glyphs Range
+	// X is the position of the start of the first rune of the cluster,
+	// from the left edge of its line. It is the left edge of
+	// left-to-right clusters and the right edge of right-to-left
+	// clusters, whose Advance is negative.
+	X fixed.Int26_6
 }
 
 // RuneWidth returns the effective width of one rune for this cluster.
@@ -95,14 +111,61 @@ type Layout struct {
 	// Runes describes the position of the text data this layout represents
 	// within the overall body of text being shaped.
 	Runes Range
-	// Direction is the layout direction of the text.
+	// Direction is the layout direction of the text. Lines of paragraphs
+	// mixing directions may contain clusters of both directions, ordered
+	// visually by the Unicode Bidirectional Algorithm.
 	Direction system.TextDirection
 }
 
+// Mixed reports whether l contains clusters of the direction opposite
+// to its Direction.
+func (l Layout) Mixed() bool {
+	rtl := l.Direction.Progression() == system.TowardOrigin
+	for _, c := range l.Clusters {
+		if c.Advance != 0 && (c.Advance < 0) != rtl {
+			return true
+		}
+	}
+	return false
+}
+
+// VisualOrder returns the indices of the clusters of l in visual order,
+// from left to right.
+func (l Layout) VisualOrder() []int {
+	order := make([]int, len(l.Clusters))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return l.Clusters[order[i]].left() < l.Clusters[order[j]].left()
+	})
+	return order
+}
+
+// LogicalOrder returns the visual index of every cluster of l, the
+// inverse of VisualOrder.
+func (l Layout) LogicalOrder() []int {
+	order := l.VisualOrder()
+	logical := make([]int, len(order))
+	for v, c := range order {
+		logical[c] = v
+	}
+	return logical
+}
+
+// left returns the left edge of c.
+func (c GlyphCluster) left() fixed.Int26_6 {
+	if c.Advance < 0 {
+		return c.X + c.Advance
+	}
+	return c.X
+}
+
 // Slice returns a layout starting at the glyph cluster index start
 // and running through the glyph cluster index end. The Offsets field
 // of the returned layout is adjusted to reflect the new rune range
 // covered by the layout. The returned layout will have no Clusters.
+// The layout must not be Mixed.
 func (l Layout) Slice(start, end int) Layout {
 	if start == end || end == 0 || start == len(l.Clusters) {
 		return Layout{}
@@ -140,17 +203,127 @@ type Font struct {
 	Typeface Typeface
 	Variant  Variant
 	Style    Style
-	// Weight is the text weight. If zero, Normal is used instead.
+	// Weight is the text weight. If zero, Normal is used instead. The
+	// weight of a variable font without a face of that weight sets its
+	// "wght" axis, unless Variations does.
 	Weight Weight
+	// Variations sets the axes of a variable font, as described by
+	// ParseVariations. For example, "wdth 75, slnt -10".
+	Variations string
+	// Features sets the OpenType features of the text, as described by
+	// ParseFeatures. For example, "tnum, -liga".
+	Features string
 }
 
 // Face implements text layout and shaping for a particular font. All
 // methods must be safe for concurrent use.
 type Face interface {
-	Layout(ppem fixed.Int26_6, maxWidth int, lc system.Locale, txt io.RuneReader) ([]Line, error)
+	Layout(ppem fixed.Int26_6, maxWidth int, lc system.Locale, params Parameters, txt io.RuneReader) ([]Line, error)
 	Shape(ppem fixed.Int26_6, str Layout) clip.PathSpec
 }
 
+// FallbackFace is a Face that can lay out runes it has no glyphs for
+// with other faces.
+type FallbackFace interface {
+	Face
+	// LayoutFallback is like Layout, except that runes the face has no
+	// glyphs for are laid out with the first of fallbacks that has. The
+	// fallbacks may include the face itself. The face of every glyph is
+	// recorded in its Face field, for Shape.
+	LayoutFallback(ppem fixed.Int26_6, maxWidth int, lc system.Locale, params Parameters, txt io.RuneReader, fallbacks []Face) ([]Line, error)
+}
+
+// DecorationFace is a Face that knows the positions and thicknesses of
+// its text decorations.
+type DecorationFace interface {
+	Face
+	DecorationMetrics(ppem fixed.Int26_6) DecorationMetrics
+}
+
+// Parameters are the options for laying out text, in addition to its
+// font, size, maximum width and locale. The zero value lays out text with
+// the spacing of its font.
+type Parameters struct {
+	// LetterSpacing is the extra space after every glyph cluster. It may
+	// be negative.
+	LetterSpacing fixed.Int26_6
+	// LineHeight is the height of every line, from the top of its ascent
+	// to the bottom of its descent. Zero means the height of the font,
+	// scaled by LineHeightScale.
+	LineHeight fixed.Int26_6
+	// LineHeightScale scales the height of the font for the height of
+	// lines, unless LineHeight is set. Zero means 1.
+	LineHeightScale float32
+	// MaxLines limits the number of lines. Zero means no limit. The text
+	// that doesn't fit is replaced by Truncator.
+	MaxLines int
+	// Truncation selects the part of the text replaced by Truncator.
+	Truncation Truncation
+	// Truncator is the text shown in place of truncated text. The empty
+	// string means "…".
+	Truncator string
+	// Justify stretches the word gaps of every line but the last of each
+	// paragraph to fill the maximum width.
+	Justify bool
+	// Hyphenator, if not nil, adds line breaks inside words. A hyphen is
+	// shown at the end of lines broken inside words. The Hyphenator must
+	// be comparable, such as a pointer, and is compared by identity.
+	Hyphenator Hyphenator
+}
+
+// Hyphenator finds the places where words may be hyphenated.
+type Hyphenator interface {
+	// Hyphenate returns the increasing indices of the runes of word that
+	// may be preceded by a hyphen.
+	Hyphenate(word []rune) []int
+}
+
+// Truncation is the part of the text dropped when it doesn't fit in the
+// maximum number of lines.
+type Truncation uint8
+
+const (
+	// TruncateEnd drops the end of the text.
+	TruncateEnd Truncation = iota
+	// TruncateStart drops the start of the text.
+	TruncateStart
+	// TruncateMiddle drops the middle of the text.
+	TruncateMiddle
+)
+
+// Decoration is a set of lines drawn along text.
+type Decoration uint8
+
+const (
+	// Underline is a line below the baseline.
+	Underline Decoration = 1 << iota
+	// Strikethrough is a line through the middle of lowercase letters.
+	Strikethrough
+	// Overline is a line along the ascent of the font.
+	Overline
+)
+
+// DecorationMetrics are the positions and thicknesses of text
+// decorations. Positions are the offsets from the baseline to the top
+// edges of the lines, downward.
+type DecorationMetrics struct {
+	Underline, UnderlineThickness         fixed.Int26_6
+	Strikethrough, StrikethroughThickness fixed.Int26_6
+	Overline, OverlineThickness           fixed.Int26_6
+}
+
+// ColorFace is a Face with color glyphs, such as emoji. The color glyphs
+// are left out of the outline returned by Shape and painted by
+// ShapeColor instead.
+type ColorFace interface {
+	Face
+	// ShapeColor returns the operations for painting the color glyphs of
+	// a line of text, or the zero CallOp if it has none. Parts of glyphs
+	// in the text color are painted in fg. The operations replace the
+	// paint material.
+	ShapeColor(ppem fixed.Int26_6, str Layout, fg color.NRGBA) op.CallOp
+}
+
 // Typeface identifies a particular typeface design. The empty
 // string denotes the default typeface.
 type Typeface string
@@ -164,6 +337,10 @@ const (
 	Start Alignment = iota
 	End
 	Middle
+	// Justify stretches the word gaps of lines to fill the maximum width,
+	// except for the last line of every paragraph which is aligned to
+	// Start. Justified text must be laid out with Parameters.Justify.
+	Justify
 )
 
 const (
@@ -190,6 +367,39 @@ const (
 	UltraBlack Weight = ExtraBlack
 )
 
+// DefaultDecorationMetrics returns decoration metrics for text of size
+// ppem, for faces that don't provide their own.
+func DefaultDecorationMetrics(ppem fixed.Int26_6) DecorationMetrics {
+	thickness := ppem / 14
+	return DecorationMetrics{
+		Underline:              ppem / 10,
+		UnderlineThickness:     thickness,
+		Strikethrough:          -ppem * 3 / 10,
+		StrikethroughThickness: thickness,
+		Overline:               -ppem * 9 / 10,
+		OverlineThickness:      thickness,
+	}
+}
+
+// AdjustLines adjusts the ascents and descents of lines laid out by a
+// Face to the line height of the parameters. The difference from the
+// height of the font is shared evenly above and below the text.
+func (p Parameters) AdjustLines(lines []Line) {
+	if p.LineHeight == 0 && (p.LineHeightScale == 0 || p.LineHeightScale == 1) {
+		return
+	}
+	for i := range lines {
+		l := &lines[i]
+		height := p.LineHeight
+		if height == 0 {
+			height = fixed.Int26_6(float32(l.Ascent+l.Descent) * p.LineHeightScale)
+		}
+		extra := height - (l.Ascent + l.Descent)
+		l.Ascent += extra / 2
+		l.Descent += extra - extra/2
+	}
+}
+
 func (a Alignment) String() string {
 	switch a {
 	case Start:
@@ -198,6 +408,8 @@ func (a Alignment) String() string {
 		return "End"
 	case Middle:
 		return "Middle"
+	case Justify:
+		return "Justify"
 	default:
 		panic("invalid Alignment")
 	}
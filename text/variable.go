@@ -0,0 +1,140 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package text
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Variation is the value of an axis of a variable font.
+type Variation struct {
+	// Tag identifies the axis, such as "wght", "wdth" or "slnt".
+	Tag string
+	// Value is the position on the axis, in design units.
+	Value float32
+}
+
+// Feature is the setting of an OpenType feature.
+type Feature struct {
+	// Tag identifies the feature, such as "liga", "tnum" or "smcp".
+	Tag string
+	// Value is 0 to disable the feature and 1 to enable it. Features
+	// selecting alternate glyphs, such as "salt", use the one-based
+	// index of the alternate.
+	Value uint32
+}
+
+// Axis is a variation axis of a variable font.
+type Axis struct {
+	Tag string
+	// Min, Default and Max are the range and default position of the
+	// axis, in design units.
+	Min, Default, Max float32
+}
+
+// VariableFace is a Face that can be varied along the axes of a
+// variable font, and whose OpenType features can be set.
+type VariableFace interface {
+	Face
+	// Axes returns the variation axes of the face, or none for static
+	// fonts.
+	Axes() []Axis
+	// Instance returns the face with the given axis values and
+	// features. Axes missing from the face keep their default values
+	// and features missing from the face are ignored.
+	Instance(variations []Variation, features []Feature) Face
+}
+
+// ParseVariations parses a comma separated list of axis tags and
+// values, such as "wght 650, wdth 75" or "slnt=-10". Tags may be
+// quoted as in CSS. It returns the valid variations and the first
+// error.
+func ParseVariations(s string) ([]Variation, error) {
+	var vars []Variation
+	var first error
+	for _, item := range strings.Split(s, ",") {
+		tag, value, ok := splitSetting(item)
+		if tag == "" && value == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(value, 32)
+		if err == nil && !ok {
+			err = fmt.Errorf("invalid tag %q", tag)
+		}
+		if err != nil {
+			if first == nil {
+				first = fmt.Errorf("text: invalid variation %q: %v", strings.TrimSpace(item), err)
+			}
+			continue
+		}
+		vars = append(vars, Variation{Tag: tag, Value: float32(v)})
+	}
+	return vars, first
+}
+
+// ParseFeatures parses a comma separated list of feature tags, such as
+// "tnum, -liga, salt=2". A tag alone or prefixed by '+' enables the
+// feature, a tag prefixed by '-' disables it, and a tag followed by a
+// value sets it. Tags may be quoted as in CSS. It returns the valid
+// features and the first error.
+func ParseFeatures(s string) ([]Feature, error) {
+	var feats []Feature
+	var first error
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		f := Feature{Value: 1}
+		switch item[0] {
+		case '-':
+			f.Value = 0
+			item = item[1:]
+		case '+':
+			item = item[1:]
+		}
+		tag, value, ok := splitSetting(item)
+		var err error
+		if !ok {
+			err = fmt.Errorf("invalid tag %q", tag)
+		} else if value != "" {
+			var v uint64
+			v, err = strconv.ParseUint(value, 10, 32)
+			f.Value = uint32(v)
+		}
+		if err != nil {
+			if first == nil {
+				first = fmt.Errorf("text: invalid feature %q: %v", item, err)
+			}
+			continue
+		}
+		f.Tag = tag
+		feats = append(feats, f)
+	}
+	return feats, first
+}
+
+// splitSetting splits a setting such as "wght 650", "wght=650" or
+// `"wght" 650` into its tag and value. The tag is padded with spaces to
+// 4 characters, and ok reports whether it is valid.
+func splitSetting(s string) (tag, value string, ok bool) {
+	s = strings.TrimSpace(s)
+	tag, value = s, ""
+	if i := strings.IndexAny(s, " \t="); i >= 0 {
+		tag, value = s[:i], strings.TrimSpace(strings.TrimLeft(s[i:], " \t="))
+	}
+	if len(tag) >= 2 && (tag[0] == '"' || tag[0] == '\'') && tag[len(tag)-1] == tag[0] {
+		tag = tag[1 : len(tag)-1]
+	}
+	if tag == "" || len(tag) > 4 {
+		return tag, value, false
+	}
+	for _, r := range tag {
+		if r < 0x20 || r > 0x7e {
+			return tag, value, false
+		}
+	}
+	return tag + strings.Repeat(" ", 4-len(tag)), value, true
+}
@@ -0,0 +1,142 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package text
+
+import (
+	"io"
+	"reflect"
+	"testing"
+
+	"golang.org/x/image/math/fixed"
+
+	"github.com/xiaoshengduan/gio-fly/io/system"
+	"github.com/xiaoshengduan/gio-fly/op/clip"
+)
+
+func TestParseVariations(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []Variation
+		err  bool
+	}{
+		{in: "", want: nil},
+		{in: "wght 650", want: []Variation{{Tag: "wght", Value: 650}}},
+		{in: " wght 650 , wdth 75", want: []Variation{{Tag: "wght", Value: 650}, {Tag: "wdth", Value: 75}}},
+		{in: "slnt=-10", want: []Variation{{Tag: "slnt", Value: -10}}},
+		{in: `"opsz" 12.5`, want: []Variation{{Tag: "opsz", Value: 12.5}}},
+		{in: "ab 1", want: []Variation{{Tag: "ab  ", Value: 1}}},
+		{in: "weight 650, wdth 75", want: []Variation{{Tag: "wdth", Value: 75}}, err: true},
+		{in: "wght, wdth 75", want: []Variation{{Tag: "wdth", Value: 75}}, err: true},
+	}
+	for _, test := range tests {
+		got, err := ParseVariations(test.in)
+		if (err != nil) != test.err {
+			t.Errorf("ParseVariations(%q) error: %v", test.in, err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParseVariations(%q) = %v, want %v", test.in, got, test.want)
+		}
+	}
+}
+
+func TestParseFeatures(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []Feature
+		err  bool
+	}{
+		{in: "", want: nil},
+		{in: "tnum", want: []Feature{{Tag: "tnum", Value: 1}}},
+		{in: "tnum, -liga, +smcp", want: []Feature{{Tag: "tnum", Value: 1}, {Tag: "liga", Value: 0}, {Tag: "smcp", Value: 1}}},
+		{in: "salt=2, 'calt' 0", want: []Feature{{Tag: "salt", Value: 2}, {Tag: "calt", Value: 0}}},
+		{in: "ligatures, tnum", want: []Feature{{Tag: "tnum", Value: 1}}, err: true},
+		{in: "salt=-1", want: nil, err: true},
+	}
+	for _, test := range tests {
+		got, err := ParseFeatures(test.in)
+		if (err != nil) != test.err {
+			t.Errorf("ParseFeatures(%q) error: %v", test.in, err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParseFeatures(%q) = %v, want %v", test.in, got, test.want)
+		}
+	}
+}
+
+func TestCacheInstances(t *testing.T) {
+	vf := &variableFace{axes: []Axis{{Tag: "wght", Min: 100, Default: 400, Max: 900}}}
+	c := NewCache([]FontFace{{Font: Font{Weight: Normal}, Face: vf}})
+
+	if got := c.lookup(Font{}); got.face != vf {
+		t.Errorf("default font looked up %v, want the face itself", got.face)
+	}
+	bold := c.lookup(Font{Weight: Bold})
+	inst, ok := bold.face.(*variableFace)
+	if !ok || inst == vf {
+		t.Fatalf("bold font looked up %v, want an instance", bold.face)
+	}
+	if want := []Variation{{Tag: "wght", Value: 700}}; !reflect.DeepEqual(inst.vars, want) {
+		t.Errorf("bold instance has variations %v, want %v", inst.vars, want)
+	}
+	if c.lookup(Font{Weight: Bold}) != bold {
+		t.Error("instance not cached")
+	}
+	for _, fb := range bold.fallbacks {
+		if fb == Face(vf) {
+			t.Error("instance falls back to its variable face")
+		}
+	}
+
+	f := c.lookup(Font{Variations: "wght 550, wdth 75", Features: "-liga"})
+	inst = f.face.(*variableFace)
+	wantVars := []Variation{{Tag: "wght", Value: 550}, {Tag: "wdth", Value: 75}}
+	if !reflect.DeepEqual(inst.vars, wantVars) {
+		t.Errorf("instance has variations %v, want %v", inst.vars, wantVars)
+	}
+	if want := []Feature{{Tag: "liga", Value: 0}}; !reflect.DeepEqual(inst.feats, want) {
+		t.Errorf("instance has features %v, want %v", inst.feats, want)
+	}
+	// Equivalent settings share the instance.
+	if c.lookup(Font{Variations: "wdth=75,wght=550", Features: " -liga "}) != f {
+		t.Error("reordered settings looked up a new instance")
+	}
+
+	// Static faces are cached too, and their axes queried once.
+	static := &variableFace{}
+	c = NewCache([]FontFace{{Font: Font{Weight: Normal}, Face: static}})
+	for i := 0; i < 2; i++ {
+		if got := c.lookup(Font{Weight: Bold}); got.face != static {
+			t.Errorf("bold font looked up %v, want the static face", got.face)
+		}
+	}
+	if static.queries != 1 {
+		t.Errorf("static face axes queried %d times, want 1", static.queries)
+	}
+}
+
+// variableFace is a VariableFace that records the settings of its
+// instances.
+type variableFace struct {
+	axes  []Axis
+	vars  []Variation
+	feats []Feature
+	// queries counts the calls to Axes.
+	queries int
+}
+
+func (f *variableFace) Layout(ppem fixed.Int26_6, maxWidth int, lc system.Locale, params Parameters, txt io.RuneReader) ([]Line, error) {
+	return nil, nil
+}
+
+func (f *variableFace) Shape(ppem fixed.Int26_6, str Layout) clip.PathSpec {
+	return clip.PathSpec{}
+}
+
+func (f *variableFace) Axes() []Axis {
+	f.queries++
+	return f.axes
+}
+
+func (f *variableFace) Instance(variations []Variation, features []Feature) Face {
+	return &variableFace{axes: f.axes, vars: variations, feats: features}
+}
@@ -4,8 +4,11 @@ package widget
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"image"
+	"image/color"
 	"io"
 	"math"
 	"sort"
@@ -21,6 +24,7 @@ import (
 	"github.com/xiaoshengduan/gio-fly/io/key"
 	"github.com/xiaoshengduan/gio-fly/io/pointer"
 	"github.com/xiaoshengduan/gio-fly/io/system"
+	"github.com/xiaoshengduan/gio-fly/io/transfer"
 	"github.com/xiaoshengduan/gio-fly/layout"
 	"github.com/xiaoshengduan/gio-fly/op"
 	"github.com/xiaoshengduan/gio-fly/op/clip"
@@ -52,11 +56,54 @@ type Editor struct {
 	// Filter is the list of characters allowed in the Editor. If Filter is empty,
 	// all characters are allowed.
 	Filter string
+	// MaxHistory limits the number of undo steps kept in the history. Zero
+	// means no limit.
+	MaxHistory int
+	// ReadOnly prevents the user from modifying the contents. Text can
+	// still be selected and copied, and modified programmatically.
+	ReadOnly bool
+	// Formatter, if not nil, validates and formats modifications made by
+	// the user.
+	Formatter InputFormatter
+	// SpellChecker, if not nil, checks the spelling of the text. The text
+	// is checked again when it changes.
+	SpellChecker SpellChecker
+	// SpellColor is the color of the wavy lines under misspelled words.
+	// If zero, the lines are red.
+	SpellColor color.NRGBA
+	// TextColor is the color of the parts of color glyphs, such as emoji,
+	// that are drawn in the color of the text.
+	TextColor color.NRGBA
+	// MaxLines limits the number of lines shown. Zero means no limit. The
+	// text that doesn't fit is replaced by Truncator, which the caret
+	// moves over as a single character. MaxLines is ignored for styled
+	// text.
+	MaxLines int
+	// Truncation selects the part of the text replaced by Truncator.
+	Truncation text.Truncation
+	// Truncator is the text shown in place of truncated text. The empty
+	// string means "…".
+	Truncator string
+	// Hyphenator, if not nil, hyphenates words at the ends of lines. It
+	// must be comparable, such as a pointer.
+	Hyphenator text.Hyphenator
+	// Decoration is the set of lines drawn along the text.
+	Decoration text.Decoration
+	// LetterSpacing is the extra space after every character. It may be
+	// negative.
+	LetterSpacing unit.Sp
+	// LineHeight is the height of every line. Zero means the height of
+	// the font, scaled by LineHeightScale.
+	LineHeight unit.Sp
+	// LineHeightScale scales the height of the font for the height of
+	// lines, unless LineHeight is set. Zero means 1.
+	LineHeightScale float32
 
 	eventKey     int
 	font         text.Font
 	shaper       text.Shaper
 	textSize     fixed.Int26_6
+	params       text.Parameters
 	blinkStart   time.Time
 	focused      bool
 	rr           editBuffer
@@ -102,6 +149,16 @@ type Editor struct {
 	scrollOff image.Point
 
 	clicker gesture.Click
+	// drag tracks dragging the selection to transfer targets.
+	drag struct {
+		// pressed is set while the pointer is pressed inside the
+		// selection.
+		pressed bool
+		// transferred is set if the press started a transfer.
+		transferred bool
+		// at is the position of the press.
+		at combinedPos
+	}
 
 	// events is the list of events not yet processed.
 	events []EditorEvent
@@ -110,12 +167,50 @@ type Editor struct {
 
 	locale system.Locale
 
+	// inputErr is the result of the latest Formatter validation.
+	inputErr error
+
+	// spell tracks the misspelled words of the text.
+	spell struct {
+		valid      bool
+		misspelled []TextRange
+	}
+
+	// styles are the style runs of the text, sorted by offset.
+	styles []StyleRun
+	// spans are the styled glyph runs of every line, if the text is
+	// styled.
+	spans [][]styleSpan
+	// pending is the style of text typed at the caret, after toggling a
+	// style without a selection.
+	pending struct {
+		set   bool
+		style TextStyle
+		at    int
+	}
+
 	// history contains undo history.
-	history []modification
+	history []historyGroup
 	// nextHistoryIdx is the index within the history of the next modification. This
 	// is only not len(history) immediately after undo operations occur. It is framed as the "next" value
 	// to make the zero value consistent.
 	nextHistoryIdx int
+	// groupDepth is the nesting depth of BeginGroup calls.
+	groupDepth int
+	// groupOpen reports whether the last history group accepts
+	// modifications from the current BeginGroup.
+	groupOpen bool
+	// typing tracks modifications made by the user, for coalescing them
+	// into undo steps.
+	typing struct {
+		// active is set while processing key events.
+		active bool
+		// now is the time of the key events being processed.
+		now time.Time
+		// last is the time of the last coalescable modification, or
+		// zero if the next modification starts a new undo step.
+		last time.Time
+	}
 }
 
 type offEntry struct {
@@ -232,14 +327,61 @@ func (e *Editor) processEvents(gtx layout.Context) {
 		return
 	}
 	oldStart, oldLen := min(e.caret.start, e.caret.end), e.SelectionLen()
+	e.processDrag(gtx)
 	e.processPointer(gtx)
+	e.processSpelling(gtx)
 	e.processKey(gtx)
+	e.validate()
 	// Queue a SelectEvent if the selection changed, including if it went away.
 	if newStart, newLen := min(e.caret.start, e.caret.end), e.SelectionLen(); oldStart != newStart || oldLen != newLen {
 		e.events = append(e.events, SelectEvent{})
 	}
 }
 
+// processDrag offers the selection to the target it was dragged to. It
+// runs before processPointer, to know whether a pointer release ends a
+// transfer.
+func (e *Editor) processDrag(gtx layout.Context) {
+	for _, ev := range gtx.Events(&e.drag) {
+		switch ev := ev.(type) {
+		case transfer.RequestEvent:
+			e.drag.transferred = true
+			data, err := MarshalRichText(e.SelectedRichText())
+			if err != nil {
+				break
+			}
+			transfer.OfferOp{
+				Tag:  &e.drag,
+				Type: ev.Type,
+				Data: io.NopCloser(bytes.NewReader(data)),
+			}.Add(gtx.Ops)
+		case transfer.CancelEvent:
+			e.drag.transferred = true
+		}
+	}
+}
+
+// inSelection reports whether the point pos is inside the selection.
+func (e *Editor) inSelection(pos image.Point) bool {
+	pt := pos.Add(e.scrollOff)
+	for _, r := range e.selectionRects() {
+		if pt.In(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectionRects returns the rectangles covering the selection, in text
+// coordinates.
+func (e *Editor) selectionRects() []image.Rectangle {
+	if e.caret.start == e.caret.end {
+		return nil
+	}
+	b := text.Block{Lines: e.lines, Alignment: e.Alignment, Width: e.viewSize.X}
+	return b.Selection(e.caret.start, e.caret.end)
+}
+
 func (e *Editor) makeValid() {
 	if e.valid {
 		return
@@ -271,7 +413,19 @@ func (e *Editor) processPointer(gtx layout.Context) {
 	for _, evt := range e.clickDragEvents(gtx) {
 		switch evt := evt.(type) {
 		case gesture.ClickEvent:
+			if evt.Type == gesture.TypeClick && evt.NumClicks == 1 && (e.ReadOnly || evt.Modifiers.Contain(key.ModShortcut)) {
+				if link := e.linkAt(evt.Position); link != "" {
+					e.events = append(e.events, LinkEvent{Link: link})
+				}
+			}
 			switch {
+			case evt.Type == gesture.TypePress && evt.Source == pointer.Mouse &&
+				evt.NumClicks == 1 && evt.Modifiers == 0 && e.inSelection(evt.Position):
+				// Keep the selection for dragging it, and place the
+				// caret on release instead.
+				e.drag.pressed = true
+				e.drag.at = e.hit(evt.Position)
+				e.requestFocus = true
 			case evt.Type == gesture.TypePress && evt.Source == pointer.Mouse,
 				evt.Type == gesture.TypeClick && evt.Source != pointer.Mouse:
 				prevCaretPos := e.caret.start
@@ -296,11 +450,17 @@ func (e *Editor) processPointer(gtx layout.Context) {
 				}
 				e.dragging = true
 
-				// Process a double-click.
-				if evt.NumClicks == 2 {
+				switch evt.NumClicks {
+				case 2:
+					// Select the word under the pointer.
 					e.moveWord(-1, selectionClear)
 					e.moveWord(1, selectionExtend)
 					e.dragging = false
+				case 3:
+					// Select the line under the pointer.
+					e.moveStart(selectionClear)
+					e.moveEnd(selectionExtend)
+					e.dragging = false
 				}
 			}
 		case pointer.Event:
@@ -310,6 +470,20 @@ func (e *Editor) processPointer(gtx layout.Context) {
 				release = true
 				fallthrough
 			case evt.Type == pointer.Drag && evt.Source == pointer.Mouse:
+				if release && e.drag.pressed && !e.drag.transferred {
+					// Without a transfer, select from the press to the
+					// release like a plain press.
+					e.blinkStart = gtx.Now
+					e.caret.end = e.drag.at.runes
+					e.moveCoord(image.Point{
+						X: int(math.Round(float64(evt.Position.X))),
+						Y: int(math.Round(float64(evt.Position.Y))),
+					})
+				}
+				if release {
+					e.drag.pressed = false
+					e.drag.transferred = false
+				}
 				if e.dragging {
 					e.blinkStart = gtx.Now
 					e.moveCoord(image.Point{
@@ -346,8 +520,15 @@ func (e *Editor) processKey(gtx layout.Context) {
 	if e.rr.Changed() {
 		e.events = append(e.events, ChangeEvent{})
 	}
+	// Coalesce user modifications into undo steps.
+	e.typing.active = true
+	e.typing.now = gtx.Now
+	defer func() { e.typing.active = false }()
 	// adjust keeps track of runes dropped because of MaxLen.
 	var adjust int
+	// pasted is the rich text inserted by a paste, whose plain text
+	// follows in a clipboard.Event.
+	var pasted *RichText
 	for _, ke := range gtx.Events(&e.eventKey) {
 		e.blinkStart = gtx.Now
 		switch ke := ke.(type) {
@@ -359,6 +540,9 @@ func (e *Editor) processKey(gtx layout.Context) {
 			if !e.focused || ke.State != key.Press {
 				break
 			}
+			if e.ReadOnly && isEditKey(ke) {
+				break
+			}
 			if e.Submit && (ke.Name == key.NameReturn || ke.Name == key.NameEnter) {
 				if !ke.Modifiers.Contain(key.ModShift) {
 					e.events = append(e.events, SubmitEvent{
@@ -373,6 +557,9 @@ func (e *Editor) processKey(gtx layout.Context) {
 		case key.SnippetEvent:
 			e.updateSnippet(gtx, ke.Start, ke.End)
 		case key.EditEvent:
+			if e.ReadOnly {
+				break
+			}
 			e.caret.scroll = true
 			e.scroller.Stop()
 			s := ke.Text
@@ -401,9 +588,45 @@ func (e *Editor) processKey(gtx layout.Context) {
 			}
 		// Complete a paste event, initiated by Shortcut-V in Editor.command().
 		case clipboard.Event:
+			if e.ReadOnly {
+				break
+			}
+			if pasted != nil && pasted.Text == ke.Text {
+				// Already pasted with its styles.
+				pasted = nil
+				break
+			}
 			e.caret.scroll = true
 			e.scroller.Stop()
 			e.append(ke.Text)
+		case transfer.DataEvent:
+			if e.ReadOnly || ke.Type != RichTextMIME {
+				break
+			}
+			r := ke.Open()
+			data, err := io.ReadAll(r)
+			r.Close()
+			if err != nil {
+				break
+			}
+			rt, err := UnmarshalRichText(data)
+			if err != nil {
+				break
+			}
+			pasted = &rt
+			if rt.equal(e.SelectedRichText()) {
+				// Pasting or dropping the selection onto itself changes
+				// nothing.
+				break
+			}
+			e.caret.scroll = true
+			e.scroller.Stop()
+			if e.Formatter != nil {
+				// Formatters work on plain text.
+				e.append(rt.Text)
+			} else {
+				e.InsertRichText(rt)
+			}
 		case key.SelectionEvent:
 			e.caret.scroll = true
 			e.scroller.Stop()
@@ -419,17 +642,58 @@ func (e *Editor) processKey(gtx layout.Context) {
 	}
 }
 
+// isEditKey reports whether k modifies the editor contents.
+func isEditKey(k key.Event) bool {
+	switch k.Name {
+	case key.NameReturn, key.NameEnter, key.NameDeleteBackward, key.NameDeleteForward, "V", "X", "Z", "B", "I":
+		return true
+	}
+	return false
+}
+
 func (e *Editor) moveLines(distance int, selAct selectionAction) {
 	caretStart := e.closestPosition(combinedPos{runes: e.caret.start})
 	x := caretStart.x + e.caret.xoff
 	// Seek to line.
 	pos := e.closestPosition(combinedPos{lineCol: screenPos{Y: caretStart.lineCol.Y + distance}})
-	pos = e.closestPosition(combinedPos{x: x, y: pos.y})
+	pos = e.hitPosition(x, pos.y)
 	e.caret.start = pos.runes
 	e.caret.xoff = x - pos.x
 	e.updateSelection(selAct)
 }
 
+// moveVisual moves the caret one position to the left if dist is
+// negative, or to the right. On lines mixing directions the caret moves
+// to the closest rune position in that direction, and to the
+// neighbouring line if there is none.
+func (e *Editor) moveVisual(dist int, selAct selectionAction) {
+	caret := e.closestPosition(combinedPos{runes: e.caret.start})
+	line := e.lines[caret.lineCol.Y]
+	direction := 1
+	if line.Layout.Direction.Progression() == system.TowardOrigin {
+		direction = -1
+	}
+	delta := dist * direction
+	if line.Layout.Mixed() {
+		x := line.Layout.CaretX(caret.runes)
+		cols := line.Layout.Runes.Count
+		if caret.lineCol.Y == len(e.lines)-1 {
+			cols++
+		}
+		found := false
+		var best fixed.Int26_6
+		for col := 0; col < cols; col++ {
+			d := (line.Layout.CaretX(line.Layout.Runes.Offset+col) - x) * fixed.Int26_6(dist)
+			if d > 0 && (!found || d < best) {
+				found, best = true, d
+				delta = col - caret.lineCol.X
+			}
+		}
+	}
+	e.MoveCaret(delta, delta*int(selAct))
+	e.skipLiterals(sign(delta), selAct)
+}
+
 func (e *Editor) command(gtx layout.Context, k key.Event) {
 	direction := 1
 	if e.locale.Direction.Progression() == system.TowardOrigin {
@@ -466,7 +730,7 @@ func (e *Editor) command(gtx layout.Context, k key.Event) {
 			if selAct == selectionClear {
 				e.ClearSelection()
 			}
-			e.MoveCaret(-1*direction, -1*direction*int(selAct))
+			e.moveVisual(-1, selAct)
 		}
 	case key.NameRightArrow:
 		if moveByWord {
@@ -475,7 +739,7 @@ func (e *Editor) command(gtx layout.Context, k key.Event) {
 			if selAct == selectionClear {
 				e.ClearSelection()
 			}
-			e.MoveCaret(1*direction, int(selAct)*direction)
+			e.moveVisual(+1, selAct)
 		}
 	case key.NamePageUp:
 		e.movePages(-1, selAct)
@@ -491,21 +755,29 @@ func (e *Editor) command(gtx layout.Context, k key.Event) {
 		clipboard.ReadOp{Tag: &e.eventKey}.Add(gtx.Ops)
 	// Copy or Cut selection -- ignored if nothing selected.
 	case "C", "X":
-		if text := e.SelectedText(); text != "" {
-			clipboard.WriteOp{Text: text}.Add(gtx.Ops)
+		if rt := e.SelectedRichText(); rt.Text != "" {
+			w := clipboard.WriteOp{Text: rt.Text}
+			if data, err := MarshalRichText(rt); err == nil && len(rt.Styles) > 0 {
+				w.Type, w.Data = RichTextMIME, data
+			}
+			w.Add(gtx.Ops)
 			if k.Name == "X" {
 				e.Delete(1)
 			}
 		}
+	case "B":
+		e.ToggleBold()
+	case "I":
+		e.ToggleItalic()
 	// Select all
 	case "A":
 		e.caret.end = 0
 		e.caret.start = e.Len()
 	case "Z":
 		if k.Modifiers.Contain(key.ModShift) {
-			e.redo()
+			e.Redo()
 		} else {
-			e.undo()
+			e.Undo()
 		}
 	}
 }
@@ -543,6 +815,16 @@ func (e *Editor) Layout(gtx layout.Context, sh text.Shaper, font text.Font, size
 		e.font = font
 		e.textSize = textSize
 	}
+	params := textParams(gtx, e.LetterSpacing, e.LineHeight, e.LineHeightScale)
+	params.MaxLines = e.MaxLines
+	params.Truncation = e.Truncation
+	params.Truncator = e.Truncator
+	params.Justify = e.Alignment == text.Justify
+	params.Hyphenator = e.Hyphenator
+	if params != e.params {
+		e.params = params
+		e.invalidate()
+	}
 	maxWidth := gtx.Constraints.Max.X
 	if e.SingleLine {
 		maxWidth = inf
@@ -559,6 +841,7 @@ func (e *Editor) Layout(gtx layout.Context, sh text.Shaper, font text.Font, size
 		e.lastMask = e.Mask
 		e.invalidate()
 	}
+	e.measureObjects(gtx)
 
 	e.makeValid()
 	e.processEvents(gtx)
@@ -569,6 +852,7 @@ func (e *Editor) Layout(gtx layout.Context, sh text.Shaper, font text.Font, size
 		e.invalidate()
 	}
 	e.makeValid()
+	e.checkSpelling()
 
 	dims := e.layout(gtx, content)
 
@@ -653,24 +937,34 @@ func (e *Editor) layout(gtx layout.Context, content layout.Widget) layout.Dimens
 
 	defer clip.Rect(image.Rectangle{Max: e.viewSize}).Push(gtx.Ops).Pop()
 	pointer.CursorText.Add(gtx.Ops)
-	const keyFilterNoLeftUp = "(ShortAlt)-(Shift)-[→,↓]|(Shift)-[⏎,⌤]|(ShortAlt)-(Shift)-[⌫,⌦]|(Shift)-[⇞,⇟,⇱,⇲]|Short-[C,V,X,A]|Short-(Shift)-Z"
-	const keyFilterNoRightDown = "(ShortAlt)-(Shift)-[←,↑]|(Shift)-[⏎,⌤]|(ShortAlt)-(Shift)-[⌫,⌦]|(Shift)-[⇞,⇟,⇱,⇲]|Short-[C,V,X,A]|Short-(Shift)-Z"
-	const keyFilterNoArrows = "(Shift)-[⏎,⌤]|(ShortAlt)-(Shift)-[⌫,⌦]|(Shift)-[⇞,⇟,⇱,⇲]|Short-[C,V,X,A]|Short-(Shift)-Z"
-	const keyFilterAllArrows = "(ShortAlt)-(Shift)-[←,→,↑,↓]|(Shift)-[⏎,⌤]|(ShortAlt)-(Shift)-[⌫,⌦]|(Shift)-[⇞,⇟,⇱,⇲]|Short-[C,V,X,A]|Short-(Shift)-Z"
+	const keyFilterNoLeftUp = "(ShortAlt)-(Shift)-[→,↓]|"
+	const keyFilterNoRightDown = "(ShortAlt)-(Shift)-[←,↑]|"
+	const keyFilterAllArrows = "(ShortAlt)-(Shift)-[←,→,↑,↓]|"
+	const keyFilterNav = "(Shift)-[⇞,⇟,⇱,⇲]|Short-[C,A]"
+	const keyFilterEdit = "|(Shift)-[⏎,⌤]|(ShortAlt)-(Shift)-[⌫,⌦]|Short-[V,X,B,I]|Short-(Shift)-Z"
+	keys := key.Set(keyFilterNav)
+	if !e.ReadOnly {
+		keys += keyFilterEdit
+	}
 	caret := e.closestPosition(combinedPos{runes: e.caret.start})
 	switch {
 	case caret.runes == 0 && caret.runes == e.Len():
-		key.InputOp{Tag: &e.eventKey, Hint: e.InputHint, Keys: keyFilterNoArrows}.Add(gtx.Ops)
 	case caret.runes == 0:
-		key.InputOp{Tag: &e.eventKey, Hint: e.InputHint, Keys: keyFilterNoLeftUp}.Add(gtx.Ops)
+		keys = keyFilterNoLeftUp + keys
 	case caret.runes == e.Len():
-		key.InputOp{Tag: &e.eventKey, Hint: e.InputHint, Keys: keyFilterNoRightDown}.Add(gtx.Ops)
+		keys = keyFilterNoRightDown + keys
 	default:
-		key.InputOp{Tag: &e.eventKey, Hint: e.InputHint, Keys: keyFilterAllArrows}.Add(gtx.Ops)
+		keys = keyFilterAllArrows + keys
+	}
+	key.InputOp{Tag: &e.eventKey, Hint: e.InputHint, Keys: keys}.Add(gtx.Ops)
+	if !e.ReadOnly {
+		transfer.TargetOp{Tag: &e.eventKey, Type: RichTextMIME}.Add(gtx.Ops)
 	}
 	if e.requestFocus {
 		key.FocusOp{Tag: &e.eventKey}.Add(gtx.Ops)
-		key.SoftKeyboardOp{Show: true}.Add(gtx.Ops)
+		if !e.ReadOnly {
+			key.SoftKeyboardOp{Show: true}.Add(gtx.Ops)
+		}
 	}
 	e.requestFocus = false
 
@@ -686,8 +980,20 @@ func (e *Editor) layout(gtx layout.Context, content layout.Widget) layout.Dimens
 
 	e.clicker.Add(gtx.Ops)
 	e.dragger.Add(gtx.Ops)
+	if e.SpellChecker != nil {
+		pointer.InputOp{Tag: &e.spell, Types: pointer.Press}.Add(gtx.Ops)
+	}
+	if e.Mask == 0 && !e.dragging {
+		// Offer the selection for dragging, above the
+		// other handlers.
+		for _, r := range e.selectionRects() {
+			st := clip.Rect(r.Sub(e.scrollOff)).Push(gtx.Ops)
+			transfer.SourceOp{Tag: &e.drag, Type: RichTextMIME}.Add(gtx.Ops)
+			st.Pop()
+		}
+	}
 	e.caret.on = false
-	if e.focused {
+	if e.focused && !e.ReadOnly {
 		now := gtx.Now
 		dt := now.Sub(e.blinkStart)
 		blinking := dt < maxBlinkDuration
@@ -739,6 +1045,18 @@ func (e *Editor) PaintSelection(gtx layout.Context) {
 			return
 		}
 		line := e.lines[leftmost.lineCol.Y]
+		if line.Layout.Mixed() {
+			// The selected runes of lines mixing directions may not be
+			// adjacent, so paint them cluster by cluster.
+			t := op.Offset(scroll.Mul(-1)).Push(gtx.Ops)
+			paintClusterSelection(gtx.Ops, line, leftmost, selStart, selEnd)
+			t.Pop()
+			if pos.lineCol.Y == len(e.lines)-1 {
+				break
+			}
+			pos = e.closestPosition(combinedPos{lineCol: screenPos{Y: pos.lineCol.Y + 1}})
+			continue
+		}
 		flip := line.Layout.Direction.Progression() == system.TowardOrigin
 		// Clamp start, end to selection.
 		if !flip {
@@ -776,6 +1094,21 @@ func (e *Editor) PaintSelection(gtx layout.Context) {
 	}
 }
 
+// paintClusterSelection paints the selection of the runes in
+// [selStart, selEnd) of line, whose left edge is at lineStart.
+func paintClusterSelection(ops *op.Ops, line text.Line, lineStart combinedPos, selStart, selEnd int) {
+	for _, s := range line.Layout.Spans(selStart, selEnd) {
+		r := image.Rect((lineStart.x + s.Min).Round(), lineStart.y-line.Ascent.Ceil(), (lineStart.x + s.Max).Round(), lineStart.y+line.Descent.Ceil())
+		cl := clip.Rect(r).Push(ops)
+		paint.PaintOp{}.Add(ops)
+		cl.Pop()
+	}
+}
+
+// PaintText paints the text glyphs and inline objects, and underlines
+// misspelled words. Color glyphs, such as emoji, are painted after the
+// text, and leave their colors as the current paint material. Text with a
+// TextStyle color and the underlines are painted last.
 func (e *Editor) PaintText(gtx layout.Context) {
 	cl := textPadding(e.lines)
 	cl.Max = cl.Max.Add(e.viewSize)
@@ -786,6 +1119,16 @@ func (e *Editor) PaintText(gtx layout.Context) {
 	}
 	cl = cl.Add(scroll)
 	pos := e.seekFirstVisibleLine(cl.Min.Y)
+	if e.spans != nil {
+		e.paintStyledText(gtx, pos, cl, scroll)
+		e.paintSpelling(gtx, cl)
+		return
+	}
+	var decorations text.DecorationMetrics
+	if e.Decoration != 0 {
+		decorations = e.shaper.DecorationMetrics(e.font, e.textSize)
+	}
+	var colors colorGlyphs
 	for !posIsBelow(e.lines, pos, cl.Max.Y) {
 		start, end := clipLine(e.lines, e.Alignment, e.viewSize.X, cl, pos)
 		line := e.lines[start.lineCol.Y]
@@ -799,13 +1142,17 @@ func (e *Editor) PaintText(gtx layout.Context) {
 		op := clip.Outline{Path: e.shaper.Shape(e.font, e.textSize, l)}.Op().Push(gtx.Ops)
 		paint.PaintOp{}.Add(gtx.Ops)
 		op.Pop()
+		paintDecorations(gtx.Ops, e.Decoration, decorations, l)
 		t.Pop()
+		colors.add(off, e.shaper.ShapeColor(e.font, e.textSize, l, e.TextColor))
 
 		if pos.lineCol.Y == len(e.lines)-1 {
 			break
 		}
 		pos = e.closestPosition(combinedPos{lineCol: screenPos{Y: pos.lineCol.Y + 1}})
 	}
+	colors.paint(gtx.Ops)
+	e.paintSpelling(gtx, cl)
 }
 
 // caretWidth returns the width occupied by the caret for the current
@@ -879,6 +1226,12 @@ const (
 	maxInt  = 1<<(intSize-1) - 1
 )
 
+// Truncated reports whether the last layout of the editor truncated its
+// text to MaxLines.
+func (e *Editor) Truncated() bool {
+	return truncated(e.lines)
+}
+
 // Len is the length of the editor contents, in runes.
 func (e *Editor) Len() int {
 	end := e.closestPosition(combinedPos{runes: maxInt})
@@ -893,6 +1246,8 @@ func (e *Editor) Text() string {
 // SetText replaces the contents of the editor, clearing any selection first.
 func (e *Editor) SetText(s string) {
 	e.rr = editBuffer{}
+	e.styles = nil
+	e.pending.set = false
 	e.caret.start = 0
 	e.caret.end = 0
 	if e.SingleLine {
@@ -940,10 +1295,34 @@ func (e *Editor) scrollAbs(x, y int) {
 	}
 }
 
-func (e *Editor) moveCoord(pos image.Point) {
+// hit returns the position closest to the point pos.
+func (e *Editor) hit(pos image.Point) combinedPos {
 	x := fixed.I(pos.X + e.scrollOff.X)
 	y := pos.Y + e.scrollOff.Y
-	e.caret.start = e.closestPosition(combinedPos{x: x, y: y}).runes
+	return e.hitPosition(x, y)
+}
+
+// hitPosition returns the position closest to the text coordinates
+// (x, y). Unlike closestPosition, it hit tests the clusters of lines
+// mixing directions, whose rune positions are not ordered by x.
+func (e *Editor) hitPosition(x fixed.Int26_6, y int) combinedPos {
+	closest := e.closestPosition(combinedPos{x: x, y: y})
+	l := e.lines[closest.lineCol.Y]
+	if !l.Layout.Mixed() {
+		return closest
+	}
+	lineX := x - align(e.Alignment, l.Layout.Direction, l.Width, e.viewSize.X)
+	col := l.Layout.Hit(lineX, closest.lineCol.Y == len(e.lines)-1) - l.Layout.Runes.Offset
+	lineStart := closest
+	lineStart.lineCol.X = 0
+	lineStart.runes -= closest.lineCol.X
+	lineStart.clusterIndex = 0
+	closest, _ = seekPosition(e.lines, e.Alignment, e.viewSize.X, lineStart, combinedPos{lineCol: screenPos{Y: closest.lineCol.Y, X: col}}, 0)
+	return closest
+}
+
+func (e *Editor) moveCoord(pos image.Point) {
+	e.caret.start = e.hit(pos).runes
 	e.caret.xoff = 0
 }
 
@@ -955,8 +1334,11 @@ func (e *Editor) layoutText(s text.Shaper) ([]text.Line, layout.Dimensions) {
 		r = &e.maskReader
 	}
 	var lines []text.Line
-	if s != nil {
-		lines, _ = s.Layout(e.font, e.textSize, e.maxWidth, e.locale, r)
+	e.spans = nil
+	if s != nil && e.styled() {
+		lines, e.spans = e.layoutStyled(s)
+	} else if s != nil {
+		lines, _ = s.Layout(e.font, e.textSize, e.maxWidth, e.locale, e.params, r)
 		if len(lines) == 0 {
 			// The editor does not tolerate a zero-length list of lines being returned from the shaper.
 			lines = append(lines, text.Line{})
@@ -1084,6 +1466,7 @@ func (e *Editor) closestPosition(pos combinedPos) combinedPos {
 // If limit is non-zero, seekPosition stops seeks after limit runes and returns false.
 func seekPosition(lines []text.Line, alignment text.Alignment, width int, start, needle combinedPos, limit int) (combinedPos, bool) {
 	l := lines[start.lineCol.Y]
+	lineX := align(alignment, l.Layout.Direction, l.Width, width)
 	count := 0
 	// Advance next and prev until next is greater than or equal to pos.
 	for {
@@ -1094,6 +1477,9 @@ func seekPosition(lines []text.Line, alignment text.Alignment, width int, start,
 				start.clusterIndex++
 				cluster = l.Layout.Clusters[start.clusterIndex]
 			}
+			// Clusters of lines mixing directions are not adjacent in
+			// logical order, so position every rune from its cluster.
+			start.x = lineX + cluster.X + fixed.Int26_6(start.runes-cluster.Runes.Offset)*cluster.RuneWidth()
 			if limit != 0 && count == limit {
 				return start, false
 			}
@@ -1101,12 +1487,14 @@ func seekPosition(lines []text.Line, alignment text.Alignment, width int, start,
 			if positionGreaterOrEqual(lines, start, needle) {
 				return start, true
 			}
-
-			start.x += cluster.RuneWidth()
 			start.runes++
 		}
 		if start.lineCol.Y == len(lines)-1 {
 			// End of file.
+			if n := len(l.Layout.Clusters); n > 0 {
+				last := l.Layout.Clusters[n-1]
+				start.x = lineX + last.X + last.Advance
+			}
 			return start, true
 		}
 
@@ -1115,7 +1503,8 @@ func seekPosition(lines []text.Line, alignment text.Alignment, width int, start,
 		start.lineCol.X = 0
 		start.clusterIndex = 0
 		l = lines[start.lineCol.Y]
-		start.x = align(alignment, l.Layout.Direction, l.Width, width)
+		lineX = align(alignment, l.Layout.Direction, l.Width, width)
+		start.x = lineX
 		if l.Layout.Direction.Progression() == system.TowardOrigin {
 			start.x += l.Width
 		}
@@ -1161,6 +1550,7 @@ func (e *Editor) invalidate() {
 	e.index = e.index[:0]
 	e.offIndex = e.offIndex[:0]
 	e.valid = false
+	e.spell.valid = false
 }
 
 // Delete runes from the caret position. The sign of runes specifies the
@@ -1198,12 +1588,9 @@ func (e *Editor) append(s string) {
 	if e.SingleLine {
 		s = strings.ReplaceAll(s, "\n", " ")
 	}
+	start := min(e.caret.start, e.caret.end)
 	moves := e.replace(e.caret.start, e.caret.end, s, true)
 	e.caret.xoff = 0
-	start := e.caret.start
-	if end := e.caret.end; end < start {
-		start = end
-	}
 	e.caret.start = start + moves
 	e.caret.end = e.caret.start
 }
@@ -1221,41 +1608,312 @@ type modification struct {
 	// ReverseContent is the data inserted at StartRune to
 	// apply this operation. It overwrites len([]rune(ApplyContent)) runes.
 	ReverseContent string
+	// ApplyStyles and ReverseStyles are the style runs of ApplyContent
+	// and ReverseContent, relative to StartRune.
+	ApplyStyles   []StyleRun `json:",omitempty"`
+	ReverseStyles []StyleRun `json:",omitempty"`
+}
+
+// historyGroup is a sequence of modifications that are undone and
+// redone as a single step.
+type historyGroup struct {
+	Mods []modification
 }
 
-// undo applies the modification at e.history[e.historyIdx] and decrements
-// e.historyIdx.
-func (e *Editor) undo() {
-	if len(e.history) < 1 || e.nextHistoryIdx == 0 {
+// historyState is the serialized form of the undo history.
+type historyState struct {
+	Groups []historyGroup
+	// Next is the index of the group applied by the next redo.
+	Next int
+}
+
+// undoCoalesceInterval is the maximum duration between typed
+// modifications that are merged into a single undo step.
+const undoCoalesceInterval = time.Second
+
+// coalesce attempts to merge next into m, and reports whether it
+// succeeded. Only single rune insertions and deletions adjacent to m
+// are merged, and insertions are split at word boundaries.
+func (m *modification) coalesce(next modification) bool {
+	switch {
+	case m.ReverseContent == "" && next.ReverseContent == "":
+		r, n := utf8.DecodeRuneInString(next.ApplyContent)
+		if n == 0 || n != len(next.ApplyContent) || r == '\n' {
+			return false
+		}
+		if next.StartRune != m.StartRune+utf8.RuneCountInString(m.ApplyContent) {
+			return false
+		}
+		last, _ := utf8.DecodeLastRuneInString(m.ApplyContent)
+		if last == '\n' || unicode.IsSpace(last) && !unicode.IsSpace(r) {
+			// Start a new step for every word.
+			return false
+		}
+		m.ApplyStyles = concatRuns(m.ApplyStyles, next.ApplyStyles, utf8.RuneCountInString(m.ApplyContent))
+		m.ApplyContent += next.ApplyContent
+		return true
+	case m.ApplyContent == "" && next.ApplyContent == "":
+		if utf8.RuneCountInString(next.ReverseContent) != 1 {
+			return false
+		}
+		switch next.StartRune {
+		case m.StartRune - 1:
+			// Backward deletion.
+			m.StartRune = next.StartRune
+			m.ReverseStyles = concatRuns(next.ReverseStyles, m.ReverseStyles, 1)
+			m.ReverseContent = next.ReverseContent + m.ReverseContent
+			return true
+		case m.StartRune:
+			// Forward deletion.
+			m.ReverseStyles = concatRuns(m.ReverseStyles, next.ReverseStyles, utf8.RuneCountInString(m.ReverseContent))
+			m.ReverseContent += next.ReverseContent
+			return true
+		}
+	}
+	return false
+}
+
+// record adds a modification to the undo history, discarding any
+// modifications available for redo.
+func (e *Editor) record(mod modification) {
+	if e.nextHistoryIdx < len(e.history) {
+		e.history = e.history[:e.nextHistoryIdx]
+	}
+	typed := e.typing.active
+	if e.typing.active {
+		last := e.typing.last
+		e.typing.last = e.typing.now
+		if e.groupDepth == 0 && len(e.history) > 0 && !last.IsZero() && e.typing.now.Sub(last) < undoCoalesceInterval {
+			g := &e.history[len(e.history)-1]
+			if g.Mods[len(g.Mods)-1].coalesce(mod) {
+				return
+			}
+		}
+	}
+	if !typed {
+		e.typing.last = time.Time{}
+	}
+	if e.groupDepth > 0 && e.groupOpen {
+		g := &e.history[len(e.history)-1]
+		g.Mods = append(g.Mods, mod)
+		return
+	}
+	e.history = append(e.history, historyGroup{Mods: []modification{mod}})
+	e.groupOpen = e.groupDepth > 0
+	if n := len(e.history) - e.MaxHistory; e.MaxHistory > 0 && n > 0 {
+		e.history = append(e.history[:0], e.history[n:]...)
+	}
+	e.nextHistoryIdx = len(e.history)
+}
+
+// Undo reverts the most recent modification to the editor contents,
+// or group of modifications. Undo selects the restored text.
+func (e *Editor) Undo() {
+	if !e.CanUndo() {
 		return
 	}
-	mod := e.history[e.nextHistoryIdx-1]
-	replaceEnd := mod.StartRune + utf8.RuneCountInString(mod.ApplyContent)
-	e.replace(mod.StartRune, replaceEnd, mod.ReverseContent, false)
+	e.sealHistory()
+	e.nextHistoryIdx--
+	mods := e.history[e.nextHistoryIdx].Mods
+	for i := len(mods) - 1; i >= 0; i-- {
+		mod := mods[i]
+		replaceEnd := mod.StartRune + utf8.RuneCountInString(mod.ApplyContent)
+		e.replaceStyled(mod.StartRune, replaceEnd, mod.ReverseContent, mod.ReverseStyles, false)
+	}
+	mod := mods[0]
 	caretEnd := mod.StartRune + utf8.RuneCountInString(mod.ReverseContent)
 	e.SetCaret(caretEnd, mod.StartRune)
-	e.nextHistoryIdx--
 }
 
-// redo applies the modification at e.history[e.historyIdx] and increments
-// e.historyIdx.
-func (e *Editor) redo() {
-	if len(e.history) < 1 || e.nextHistoryIdx == len(e.history) {
+// Redo re-applies the most recently undone modification, or group of
+// modifications. Redo selects the re-applied text.
+func (e *Editor) Redo() {
+	if !e.CanRedo() {
 		return
 	}
-	mod := e.history[e.nextHistoryIdx]
-	end := mod.StartRune + utf8.RuneCountInString(mod.ReverseContent)
-	e.replace(mod.StartRune, end, mod.ApplyContent, false)
+	e.sealHistory()
+	mods := e.history[e.nextHistoryIdx].Mods
+	for _, mod := range mods {
+		end := mod.StartRune + utf8.RuneCountInString(mod.ReverseContent)
+		e.replaceStyled(mod.StartRune, end, mod.ApplyContent, mod.ApplyStyles, false)
+	}
+	mod := mods[len(mods)-1]
 	caretEnd := mod.StartRune + utf8.RuneCountInString(mod.ApplyContent)
 	e.SetCaret(caretEnd, mod.StartRune)
 	e.nextHistoryIdx++
 }
 
+// CanUndo reports whether there is a modification to Undo.
+func (e *Editor) CanUndo() bool {
+	return e.nextHistoryIdx > 0
+}
+
+// CanRedo reports whether there is a modification to Redo.
+func (e *Editor) CanRedo() bool {
+	return e.nextHistoryIdx < len(e.history)
+}
+
+// BeginGroup starts a group of modifications that are undone and redone
+// as a single step. Every call to BeginGroup must be matched by a call to
+// EndGroup. Groups may be nested, in which case the outermost group
+// determines the undo step.
+func (e *Editor) BeginGroup() {
+	if e.groupDepth == 0 {
+		e.sealHistory()
+	}
+	e.groupDepth++
+}
+
+// EndGroup ends a group started by BeginGroup.
+func (e *Editor) EndGroup() {
+	if e.groupDepth == 0 {
+		return
+	}
+	e.groupDepth--
+	if e.groupDepth == 0 {
+		e.sealHistory()
+	}
+}
+
+// sealHistory ensures that the next modification starts a new undo step.
+func (e *Editor) sealHistory() {
+	e.groupOpen = false
+	e.typing.last = time.Time{}
+}
+
+// ClearHistory discards the undo and redo history.
+func (e *Editor) ClearHistory() {
+	e.history = nil
+	e.nextHistoryIdx = 0
+	e.sealHistory()
+}
+
+// MarshalHistory returns a serialized form of the undo and redo history,
+// for restoring with UnmarshalHistory. The history is only meaningful
+// together with the editor contents at the time of serialization.
+func (e *Editor) MarshalHistory() ([]byte, error) {
+	return json.Marshal(historyState{
+		Groups: e.history,
+		Next:   e.nextHistoryIdx,
+	})
+}
+
+// UnmarshalHistory replaces the undo and redo history with history
+// serialized by MarshalHistory.
+func (e *Editor) UnmarshalHistory(data []byte) error {
+	var h historyState
+	if err := json.Unmarshal(data, &h); err != nil {
+		return err
+	}
+	if h.Next < 0 || h.Next > len(h.Groups) {
+		return fmt.Errorf("widget: history index %d out of range [0,%d]", h.Next, len(h.Groups))
+	}
+	for _, g := range h.Groups {
+		if len(g.Mods) == 0 {
+			return errors.New("widget: empty history group")
+		}
+		for _, m := range g.Mods {
+			if err := checkRuns(m.ApplyStyles, utf8.RuneCountInString(m.ApplyContent)); err != nil {
+				return err
+			}
+			if err := checkRuns(m.ReverseStyles, utf8.RuneCountInString(m.ReverseContent)); err != nil {
+				return err
+			}
+		}
+	}
+	// Replay the lengths of the text through the history to check that
+	// every modification is within the text it applies to: backwards from
+	// the current text for undo, and forwards for redo.
+	n := e.Len()
+	for i := h.Next - 1; i >= 0; i-- {
+		mods := h.Groups[i].Mods
+		for j := len(mods) - 1; j >= 0; j-- {
+			m := mods[j]
+			var err error
+			if n, err = checkModification(m.StartRune, m.ApplyContent, m.ReverseContent, n); err != nil {
+				return err
+			}
+		}
+	}
+	n = e.Len()
+	for _, g := range h.Groups[h.Next:] {
+		for _, m := range g.Mods {
+			var err error
+			if n, err = checkModification(m.StartRune, m.ReverseContent, m.ApplyContent, n); err != nil {
+				return err
+			}
+		}
+	}
+	e.history = h.Groups
+	e.nextHistoryIdx = h.Next
+	e.sealHistory()
+	return nil
+}
+
+// checkModification checks that replacing the text old at start of a
+// text of n runes with new is in range, and returns the new length.
+func checkModification(start int, old, new string, n int) (int, error) {
+	end := start + utf8.RuneCountInString(old)
+	if start < 0 || end > n {
+		return 0, fmt.Errorf("widget: history range [%d,%d) out of range [0,%d]", start, end, n)
+	}
+	return n - (end - start) + utf8.RuneCountInString(new), nil
+}
+
+// checkRuns checks that the style runs are within a text of n runes.
+func checkRuns(runs []StyleRun, n int) error {
+	for _, r := range runs {
+		if r.Start < 0 || r.End > n || r.Start > r.End {
+			return fmt.Errorf("widget: style run [%d,%d) out of range [0,%d]", r.Start, r.End, n)
+		}
+	}
+	return nil
+}
+
 // replace the text between start and end with s. Indices are in runes.
 // It returns the number of runes inserted.
 // addHistory controls whether this modification is recorded in the undo
-// history.
+// history. The inserted text takes the style of the text it replaces, or
+// of the text before it.
 func (e *Editor) replace(start, end int, s string, addHistory bool) int {
+	if e.Formatter != nil && e.typing.active {
+		return e.formatReplace(start, end, s)
+	}
+	return e.replaceInherit(start, end, s, addHistory)
+}
+
+// replaceInherit is like replaceStyled, but styles the inserted text like
+// the text it replaces, or like the text before it.
+func (e *Editor) replaceInherit(start, end int, s string, addHistory bool) int {
+	var runs []StyleRun
+	if len(e.styles) > 0 || e.pending.set {
+		var st TextStyle
+		if a, b := min(start, end), max(start, end); a < b {
+			st = e.StyleAt(a)
+			st.Object = nil
+		} else {
+			st = e.insertStyle(a)
+		}
+		runs = []StyleRun{{Start: 0, End: maxInt, Style: st}}
+	}
+	return e.replaceStyled(start, end, s, runs, addHistory)
+}
+
+// appendStyled is like append, but styles the inserted text with runs.
+func (e *Editor) appendStyled(s string, runs []StyleRun) {
+	if e.SingleLine {
+		s = strings.ReplaceAll(s, "\n", " ")
+	}
+	start := min(e.caret.start, e.caret.end)
+	moves := e.replaceStyled(e.caret.start, e.caret.end, s, runs, true)
+	e.caret.xoff = 0
+	e.caret.start = start + moves
+	e.caret.end = e.caret.start
+}
+
+// replaceStyled is like replace, but styles the inserted text with runs
+// whose offsets are relative to the start of s.
+func (e *Editor) replaceStyled(start, end int, s string, runs []StyleRun, addHistory bool) int {
 	if start > end {
 		start, end = end, start
 	}
@@ -1266,6 +1924,8 @@ func (e *Editor) replace(start, end int, s string, addHistory bool) int {
 	el := e.Len()
 	var sc int
 	idx := 0
+	// dropped tracks the offsets of runes removed by Filter.
+	var dropped []int
 	for idx < len(s) {
 		if e.MaxLen > 0 && el-replaceSize+sc >= e.MaxLen {
 			s = s[:idx]
@@ -1274,6 +1934,7 @@ func (e *Editor) replace(start, end int, s string, addHistory bool) int {
 		_, n := utf8.DecodeRuneInString(s[idx:])
 		if e.Filter != "" && !strings.Contains(e.Filter, s[idx:idx+n]) {
 			s = s[:idx] + s[idx+n:]
+			dropped = append(dropped, sc+len(dropped))
 			continue
 		}
 		idx += n
@@ -1281,6 +1942,7 @@ func (e *Editor) replace(start, end int, s string, addHistory bool) int {
 	}
 	newEnd := startPos.runes + sc
 
+	var mod modification
 	if addHistory {
 		e.rr.Seek(int64(startOff), 0)
 		deleted := make([]rune, 0, replaceSize)
@@ -1288,19 +1950,25 @@ func (e *Editor) replace(start, end int, s string, addHistory bool) int {
 			ru, _, _ := e.rr.ReadRune()
 			deleted = append(deleted, ru)
 		}
-		if e.nextHistoryIdx < len(e.history) {
-			e.history = e.history[:e.nextHistoryIdx]
-		}
-		e.history = append(e.history, modification{
+		mod = modification{
 			StartRune:      startPos.runes,
 			ApplyContent:   s,
 			ReverseContent: string(deleted),
-		})
-		e.nextHistoryIdx++
+			ReverseStyles:  stylesIn(e.styles, startPos.runes, endPos.runes),
+		}
 	}
 
 	e.rr.deleteRunes(startOff, replaceSize)
 	e.rr.prepend(startOff, s)
+	if len(e.styles) > 0 || len(runs) > 0 {
+		e.styles = removeRuns(e.styles, startPos.runes, endPos.runes, true)
+		e.styles = insertRuns(e.styles, startPos.runes, sc, remapRuns(runs, dropped))
+	}
+	e.pending.set = false
+	if addHistory {
+		mod.ApplyStyles = stylesIn(e.styles, startPos.runes, newEnd)
+		e.record(mod)
+	}
 	adjust := func(pos int) int {
 		switch {
 		case newEnd < pos && pos <= endPos.runes:
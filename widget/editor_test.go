@@ -4,6 +4,7 @@ package widget
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"image"
 	"io"
@@ -12,6 +13,7 @@ import (
 	"strings"
 	"testing"
 	"testing/quick"
+	"time"
 	"unicode"
 	"unicode/utf8"
 
@@ -47,10 +49,10 @@ func TestEditorHistory(t *testing.T) {
 	e.Insert("")
 	assertContents(t, e, "", 0, 0)
 	// Ensure that undoing the overwrite succeeds.
-	e.undo()
+	e.Undo()
 	assertContents(t, e, "안П你 hello 안П你", 13, 0)
 	// Ensure that redoing the overwrite succeeds.
-	e.redo()
+	e.Redo()
 	assertContents(t, e, "", 0, 0)
 	// Insert some smaller text.
 	e.Insert("안П你 hello")
@@ -64,21 +66,355 @@ func TestEditorHistory(t *testing.T) {
 	e.Insert("П")
 	assertContents(t, e, "안ПeПlo", 4, 4)
 	// Ensure both operations undo successfully.
-	e.undo()
+	e.Undo()
 	assertContents(t, e, "안Пello", 4, 3)
-	e.undo()
+	e.Undo()
 	assertContents(t, e, "안П你 hello", 5, 1)
 	// Make a new modification.
 	e.Insert("Something New")
 	// Ensure that redo history is discarded now that
 	// we've diverged from the linear editing history.
-	// This redo() call should do nothing.
+	// This Redo call should do nothing.
 	text := e.Text()
 	start, end := e.Selection()
-	e.redo()
+	e.Redo()
 	assertContents(t, e, text, start, end)
 }
 
+// TestTruncation ensures that labels and editors report truncated text,
+// and that the caret moves over the truncator.
+func TestTruncation(t *testing.T) {
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(100, 100)),
+		Locale:      english,
+	}
+	cache := text.NewCache(gofont.Collection())
+	font := text.Font{}
+	fontSize := unit.Sp(10)
+	const txt = "The quick brown fox jumps over the lazy dog"
+
+	l := Label{MaxLines: 1}
+	if !l.Truncated(gtx, cache, font, fontSize, txt) {
+		t.Error("long label not truncated")
+	}
+	if l.Truncated(gtx, cache, font, fontSize, "fox") {
+		t.Error("short label truncated")
+	}
+
+	e := &Editor{MaxLines: 1}
+	e.SetText(txt)
+	e.Layout(gtx, cache, font, fontSize, nil)
+	if !e.Truncated() {
+		t.Fatal("long editor text not truncated")
+	}
+	if n := len(e.lines); n != 1 {
+		t.Fatalf("got %d lines, expected 1", n)
+	}
+	e.SetCaret(e.Len(), e.Len())
+	if line, col := e.CaretPos(); line != 0 || col != e.Len() {
+		t.Errorf("caret at line %d, column %d, expected line 0, column %d", line, col, e.Len())
+	}
+	end := e.CaretCoords().X
+	e.MoveCaret(-1, -1)
+	if x := e.CaretCoords().X; x >= end {
+		t.Errorf("caret didn't move over the truncator: %v >= %v", x, end)
+	}
+	e.MaxLines = 0
+	e.Layout(gtx, cache, font, fontSize, nil)
+	if e.Truncated() {
+		t.Error("editor text truncated without MaxLines")
+	}
+}
+
+func TestTextParamsLetterSpacing(t *testing.T) {
+	gtx := layout.Context{Metric: unit.Metric{PxPerSp: 1.5}}
+	// Fractions of pixels are kept.
+	if got, want := textParams(gtx, 0.5, 0, 0).LetterSpacing, fixed.Int26_6(48); got != want {
+		t.Errorf("letter spacing %v, want %v", got, want)
+	}
+}
+
+// countHyphenator hyphenates words in the middle and counts the words.
+type countHyphenator struct {
+	words int
+}
+
+func (h *countHyphenator) Hyphenate(word []rune) []int {
+	h.words++
+	return []int{len(word) / 2}
+}
+
+func TestEditorHyphenatorCache(t *testing.T) {
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(60, 100)),
+		Locale:      english,
+	}
+	cache := text.NewCache(gofont.Collection())
+	hyph := new(countHyphenator)
+	const txt = "Hyphenation hyphenation"
+	e := &Editor{Hyphenator: hyph}
+	e.SetText(txt)
+	l := Label{Hyphenator: hyph}
+	e.Layout(gtx, cache, text.Font{}, unit.Sp(10), nil)
+	l.Layout(gtx, cache, text.Font{}, unit.Sp(10), txt)
+	if n := len(e.lines); n < 2 {
+		t.Errorf("got %d lines, expected the text to wrap", n)
+	}
+	// Pointer Hyphenators don't invalidate the layouts.
+	words := hyph.words
+	if words == 0 {
+		t.Fatal("no words hyphenated")
+	}
+	e.Layout(gtx, cache, text.Font{}, unit.Sp(10), nil)
+	l.Layout(gtx, cache, text.Font{}, unit.Sp(10), txt)
+	if hyph.words != words {
+		t.Errorf("hyphenated %d words again, expected cached layouts", hyph.words-words)
+	}
+}
+
+// TestEditorHistoryCoalesce ensures that typed runes are merged into
+// undo steps by time and word boundaries.
+func TestEditorHistoryCoalesce(t *testing.T) {
+	e := new(Editor)
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(100, 100)),
+		Locale:      english,
+	}
+	cache := text.NewCache(gofont.Collection())
+	font := text.Font{}
+	fontSize := unit.Sp(10)
+	e.Layout(gtx, cache, font, fontSize, nil)
+	e.focused = true
+	now := time.Now()
+	typ := func(s string) {
+		for _, r := range s {
+			n := e.Len()
+			gtx.Now = now
+			gtx.Queue = newQueue(key.EditEvent{Range: key.Range{Start: n, End: n}, Text: string(r)})
+			e.Layout(gtx, cache, font, fontSize, nil)
+			now = now.Add(100 * time.Millisecond)
+		}
+	}
+	typ("hello world")
+	e.Undo()
+	assertContents(t, e, "hello ", 6, 6)
+	e.Undo()
+	assertContents(t, e, "", 0, 0)
+	if e.CanUndo() {
+		t.Error("CanUndo reported true with empty history")
+	}
+	e.Redo()
+	e.Redo()
+	assertContents(t, e, "hello world", 11, 6)
+
+	// A pause starts a new undo step.
+	typ("ab")
+	now = now.Add(2 * undoCoalesceInterval)
+	typ("cd")
+	e.Undo()
+	assertContents(t, e, "hello worldab", 13, 13)
+
+	// Consecutive deletions are merged.
+	e.ClearSelection()
+	for i := 0; i < 3; i++ {
+		gtx.Now = now
+		gtx.Queue = newQueue(key.Event{Name: key.NameDeleteBackward, State: key.Press})
+		e.Layout(gtx, cache, font, fontSize, nil)
+	}
+	assertContents(t, e, "hello worl", 10, 10)
+	e.Undo()
+	assertContents(t, e, "hello worldab", 13, 10)
+}
+
+func TestEditorHistoryGroup(t *testing.T) {
+	e := new(Editor)
+	e.SetText("one two")
+	e.ClearHistory()
+	if e.CanUndo() || e.CanRedo() {
+		t.Fatal("history not cleared")
+	}
+	e.BeginGroup()
+	e.SetCaret(0, 3)
+	e.Insert("1")
+	e.BeginGroup()
+	e.SetCaret(2, 5)
+	e.Insert("2")
+	e.EndGroup()
+	e.EndGroup()
+	assertContents(t, e, "1 2", 3, 3)
+	e.Undo()
+	assertContents(t, e, "one two", 3, 0)
+	if e.CanUndo() {
+		t.Error("group was not undone as a single step")
+	}
+	e.Redo()
+	assertContents(t, e, "1 2", 3, 2)
+}
+
+func TestEditorMaxHistory(t *testing.T) {
+	e := new(Editor)
+	e.MaxHistory = 2
+	for _, s := range []string{"a", "b", "c"} {
+		e.Insert(s)
+	}
+	e.Undo()
+	e.Undo()
+	e.Undo()
+	assertContents(t, e, "a", 1, 1)
+}
+
+func TestEditorPatternMask(t *testing.T) {
+	e := &Editor{Formatter: PatternMask{Pattern: "9999-99-99"}}
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(100, 100)),
+		Locale:      english,
+	}
+	cache := text.NewCache(gofont.Collection())
+	font := text.Font{}
+	fontSize := unit.Sp(10)
+	e.Layout(gtx, cache, font, fontSize, nil)
+	e.focused = true
+	typ := func(s string) {
+		for _, r := range s {
+			c := e.caret.start
+			gtx.Queue = newQueue(key.EditEvent{Range: key.Range{Start: c, End: c}, Text: string(r)})
+			e.Layout(gtx, cache, font, fontSize, nil)
+		}
+	}
+	press := func(name string) {
+		gtx.Queue = newQueue(key.Event{Name: name, State: key.Press})
+		e.Layout(gtx, cache, font, fontSize, nil)
+	}
+	validation := func() (error, bool) {
+		var err error
+		found := false
+		for _, evt := range e.Events() {
+			if v, ok := evt.(ValidationEvent); ok {
+				err, found = v.Err, true
+			}
+		}
+		return err, found
+	}
+
+	typ("2")
+	if err, ok := validation(); !ok || err != ErrIncompleteInput {
+		t.Errorf("got validation %v, %v, want %v", err, ok, ErrIncompleteInput)
+	}
+	// Literals are inserted automatically, and letters rejected.
+	typ("023x01")
+	assertContents(t, e, "2023-01", 7, 7)
+	// Typed literals are skipped.
+	typ("-15")
+	assertContents(t, e, "2023-01-15", 10, 10)
+	if err, ok := validation(); !ok || err != nil {
+		t.Errorf("got validation %v, %v, want nil", err, ok)
+	}
+	if err := e.InputErr(); err != nil {
+		t.Errorf("InputErr returned %v for valid text", err)
+	}
+	// The caret skips literals.
+	press(key.NameLeftArrow)
+	press(key.NameLeftArrow)
+	press(key.NameLeftArrow)
+	assertContents(t, e, "2023-01-15", 6, 6)
+	press(key.NameRightArrow)
+	assertContents(t, e, "2023-01-15", 8, 8)
+	// Deleting a literal deletes the digit before it, and the following
+	// digits shift into place.
+	press(key.NameDeleteBackward)
+	assertContents(t, e, "2023-01-5", 6, 6)
+	if err, ok := validation(); !ok || err != ErrIncompleteInput {
+		t.Errorf("got validation %v, %v, want %v", err, ok, ErrIncompleteInput)
+	}
+	// A rejected modification leaves the text alone.
+	e.SetCaret(0, 0)
+	typ("a")
+	assertContents(t, e, "2023-01-5", 0, 0)
+	// Programmatic modifications are not formatted.
+	e.SetText("abc")
+	e.Layout(gtx, cache, font, fontSize, nil)
+	if e.Text() != "abc" || e.InputErr() != ErrIncompleteInput {
+		t.Errorf("SetText: got %q, %v", e.Text(), e.InputErr())
+	}
+}
+
+func TestEditorValidateFunc(t *testing.T) {
+	errEmpty := errors.New("empty")
+	e := &Editor{Formatter: ValidateFunc(func(s string) error {
+		if s == "" {
+			return errEmpty
+		}
+		return nil
+	})}
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(100, 100)),
+		Locale:      english,
+	}
+	cache := text.NewCache(gofont.Collection())
+	e.Layout(gtx, cache, text.Font{}, unit.Sp(10), nil)
+	if e.InputErr() != errEmpty {
+		t.Errorf("got error %v, want %v", e.InputErr(), errEmpty)
+	}
+	e.focused = true
+	gtx.Queue = newQueue(key.EditEvent{Text: "ab"})
+	e.Layout(gtx, cache, text.Font{}, unit.Sp(10), nil)
+	assertContents(t, e, "ab", 2, 2)
+	if e.InputErr() != nil {
+		t.Errorf("got error %v, want nil", e.InputErr())
+	}
+}
+
+func TestEditorHistoryMarshal(t *testing.T) {
+	e := new(Editor)
+	e.Insert("hello")
+	e.Insert(" world")
+	e.Undo()
+	data, err := e.MarshalHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	e2 := new(Editor)
+	e2.SetText(e.Text())
+	if err := e2.UnmarshalHistory(data); err != nil {
+		t.Fatal(err)
+	}
+	e2.Redo()
+	assertContents(t, e2, "hello world", 11, 5)
+	e2.Undo()
+	e2.Undo()
+	assertContents(t, e2, "", 0, 0)
+	if err := e2.UnmarshalHistory([]byte(`{"Groups":[],"Next":1}`)); err == nil {
+		t.Error("UnmarshalHistory accepted an out of range index")
+	}
+	// The history applies to "hello world", not to the empty text.
+	if err := e2.UnmarshalHistory(data); err == nil {
+		t.Error("UnmarshalHistory accepted offsets past the end of the text")
+	}
+	e3 := new(Editor)
+	e3.SetText("hello")
+	if err := e3.UnmarshalHistory([]byte(`{"Groups":[{"Mods":[{"StartRune":9,"ApplyContent":"x"}]}],"Next":0}`)); err == nil {
+		t.Error("UnmarshalHistory accepted a redo offset past the end of the text")
+	}
+	styles := []string{
+		`{"Start":0,"End":2,"Style":{"Bold":true}}`,
+		`{"Start":-1,"End":1,"Style":{"Bold":true}}`,
+		`{"Start":1,"End":0,"Style":{"Bold":true}}`,
+	}
+	for _, s := range styles {
+		if err := e3.UnmarshalHistory([]byte(`{"Groups":[{"Mods":[{"StartRune":0,"ApplyContent":"x","ApplyStyles":[` + s + `]}]}],"Next":0}`)); err == nil {
+			t.Errorf("UnmarshalHistory accepted the style run %s of a single rune", s)
+		}
+	}
+	if err := e3.UnmarshalHistory([]byte(`{"Groups":[{"Mods":[{"StartRune":0,"ReverseContent":"h","ReverseStyles":[` + styles[0] + `]}]}],"Next":1}`)); err == nil {
+		t.Error("UnmarshalHistory accepted a reverse style run past its text")
+	}
+}
+
 func assertContents(t *testing.T, e *Editor, contents string, selectionStart, selectionEnd int) {
 	t.Helper()
 	actualContents := e.Text()
@@ -321,6 +657,60 @@ func TestEditorRTL(t *testing.T) {
 	assertCaret(t, e, 1, 5, len("الحب سماء لا\nتمط غ"))
 }
 
+func TestEditorBidi(t *testing.T) {
+	e := new(Editor)
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(200, 100)),
+		Locale:      english,
+	}
+	cache := text.NewCache(append(gofont.Collection(), arabicCollection...))
+	fontSize := unit.Sp(10)
+	font := text.Font{}
+
+	// The Arabic word is laid out from right to left inside the
+	// left-to-right line.
+	e.SetText("abc الحب def")
+	e.Layout(gtx, cache, font, fontSize, nil)
+	if !e.lines[0].Layout.Mixed() {
+		t.Fatal("line doesn't mix directions")
+	}
+	caretX := func(r int) float32 {
+		e.SetCaret(r, r)
+		return e.CaretCoords().X
+	}
+	if first, last := caretX(4), caretX(7); first <= last {
+		t.Errorf("caret at the first Arabic letter at %v, left of the last at %v", first, last)
+	}
+	if a, b := caretX(5), caretX(6); a <= b {
+		t.Errorf("caret at rune 5 at %v, left of rune 6 at %v", a, b)
+	}
+	if a, b := caretX(1), caretX(2); a >= b {
+		t.Errorf("caret at rune 1 at %v, right of rune 2 at %v", a, b)
+	}
+
+	// Hit testing finds the rune under the pointer.
+	for _, r := range []int{1, 5, 6, 10} {
+		e.SetCaret(r, r)
+		pos := e.CaretCoords()
+		e.SetCaret(0, 0)
+		e.moveCoord(image.Pt(int(pos.X+.5), int(pos.Y)))
+		if got, _ := e.Selection(); got != r {
+			t.Errorf("hit test at the caret of rune %d found rune %d", r, got)
+		}
+	}
+
+	// Arrow keys move visually.
+	e.SetCaret(5, 5)
+	e.moveVisual(-1, selectionClear)
+	assertCaret(t, e, 0, 6, len("abc ال"))
+	e.moveVisual(+1, selectionClear)
+	assertCaret(t, e, 0, 5, len("abc ا"))
+	e.SetCaret(1, 1)
+	e.moveVisual(+1, selectionClear)
+	assertCaret(t, e, 0, 2, len("ab"))
+}
+
 func TestEditorLigature(t *testing.T) {
 	e := new(Editor)
 	gtx := layout.Context{
@@ -849,7 +1239,11 @@ g 2 4 6 8 g
 	font := text.Font{}
 	fontSize := unit.Sp(10)
 
+	// now advances the event time beyond the double-click duration for
+	// every selection.
+	var now time.Duration
 	selected := func(start, end int) string {
+		now += time.Second
 		// Layout once with no events; populate e.lines.
 		gtx.Queue = nil
 		e.Layout(gtx, cache, font, fontSize, nil)
@@ -864,11 +1258,13 @@ g 2 4 6 8 g
 					Buttons:  pointer.ButtonPrimary,
 					Type:     pointer.Press,
 					Source:   pointer.Mouse,
+					Time:     now,
 					Position: f32.Pt(textWidth(e, startPos.lineCol.Y, 0, startPos.lineCol.X), textHeight(e, startPos.lineCol.Y)),
 				},
 				pointer.Event{
 					Type:     pointer.Release,
 					Source:   pointer.Mouse,
+					Time:     now,
 					Position: f32.Pt(textWidth(e, endPos.lineCol.Y, 0, endPos.lineCol.X), textHeight(e, endPos.lineCol.Y)),
 				},
 			},
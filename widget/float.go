@@ -7,11 +7,13 @@ import (
 
 	"github.com/xiaoshengduan/gio-fly/gesture"
 	"github.com/xiaoshengduan/gio-fly/io/pointer"
+	"github.com/xiaoshengduan/gio-fly/io/system"
 	"github.com/xiaoshengduan/gio-fly/layout"
 	"github.com/xiaoshengduan/gio-fly/op/clip"
 )
 
-// Float is for selecting a value in a range.
+// Float is for selecting a value in a range. Horizontal values increase
+// from right to left when gtx.Locale.Direction is right-to-left.
 type Float struct {
 	Value float32
 	Axis  layout.Axis
@@ -20,6 +22,8 @@ type Float struct {
 	pos     float32 // position normalized to [0, 1]
 	length  float32
 	changed bool
+	// mirrored reports whether the value increases from right to left.
+	mirrored bool
 }
 
 // Dragging returns whether the value is being interacted with.
@@ -31,6 +35,7 @@ func (f *Float) Dragging() bool { return f.drag.Dragging() }
 func (f *Float) Layout(gtx layout.Context, pointerMargin int, min, max float32) layout.Dimensions {
 	size := gtx.Constraints.Min
 	f.length = float32(f.Axis.Convert(size).X)
+	f.mirrored = f.Axis == layout.Horizontal && gtx.Locale.Direction.Progression() == system.TowardOrigin
 
 	var de *pointer.Event
 	for _, e := range f.drag.Events(gtx.Metric, gtx, gesture.Axis(f.Axis)) {
@@ -46,6 +51,9 @@ func (f *Float) Layout(gtx layout.Context, pointerMargin int, min, max float32)
 			xy = de.Position.Y
 		}
 		f.pos = xy / f.length
+		if f.mirrored {
+			f.pos = 1 - f.pos
+		}
 		value = min + (max-min)*f.pos
 	} else if min != max {
 		f.pos = (value - min) / (max - min)
@@ -85,11 +93,20 @@ func (f *Float) setValue(value, min, max float32) {
 	}
 }
 
-// Pos reports the selected position.
+// Pos reports the selected position, from the top or left.
 func (f *Float) Pos() float32 {
+	if f.mirrored {
+		return (1 - f.pos) * f.length
+	}
 	return f.pos * f.length
 }
 
+// Mirrored reports whether the value increases from right to left, as
+// determined by the last Layout.
+func (f *Float) Mirrored() bool {
+	return f.mirrored
+}
+
 // Changed reports whether the value has changed since
 // the last call to Changed.
 func (f *Float) Changed() bool {
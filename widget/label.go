@@ -3,8 +3,9 @@
 package widget
 
 import (
-	"fmt"
 	"image"
+	"image/color"
+	"math"
 
 	"github.com/xiaoshengduan/gio-fly/io/semantic"
 	"github.com/xiaoshengduan/gio-fly/io/system"
@@ -22,8 +23,32 @@ import (
 type Label struct {
 	// Alignment specify the text alignment.
 	Alignment text.Alignment
-	// MaxLines limits the number of lines. Zero means no limit.
+	// MaxLines limits the number of lines. Zero means no limit. The text
+	// that doesn't fit is replaced by Truncator.
 	MaxLines int
+	// Truncation selects the part of the text replaced by Truncator.
+	Truncation text.Truncation
+	// Truncator is the text shown in place of truncated text. The empty
+	// string means "…".
+	Truncator string
+	// Hyphenator, if not nil, hyphenates words at the ends of lines. It
+	// must be comparable, such as a pointer.
+	Hyphenator text.Hyphenator
+	// Decoration is the set of lines drawn along the text.
+	Decoration text.Decoration
+	// LetterSpacing is the extra space after every character. It may be
+	// negative.
+	LetterSpacing unit.Sp
+	// LineHeight is the height of every line. Zero means the height of
+	// the font, scaled by LineHeightScale.
+	LineHeight unit.Sp
+	// LineHeightScale scales the height of the font for the height of
+	// lines, unless LineHeight is set. Zero means 1.
+	LineHeightScale float32
+	// TextColor is the color of the parts of color glyphs, such as emoji,
+	// that are drawn in the color of the text. The other glyphs are
+	// painted with the current paint material.
+	TextColor color.NRGBA
 }
 
 // screenPos describes a character position (in text line and column numbers,
@@ -46,6 +71,12 @@ func clipLine(lines []text.Line, alignment text.Alignment, width int, clip image
 	// Seek to first (potentially) visible column.
 	lineIdx := linePos.lineCol.Y
 	line := lines[lineIdx]
+	if line.Layout.Mixed() {
+		// Lines mixing directions are drawn whole, from their left edge.
+		start = linePos
+		start.x = align(alignment, line.Layout.Direction, line.Width, width)
+		return start, start
+	}
 	// runeWidth is the width of the widest rune in line.
 	runeWidth := (line.Bounds.Max.X - line.Width).Ceil()
 	lineStart := fixed.I(clip.Min.X - runeWidth)
@@ -71,6 +102,11 @@ func subLayout(line text.Line, start, end combinedPos) text.Layout {
 	if start.lineCol.X == line.Layout.Runes.Count {
 		return text.Layout{}
 	}
+	if line.Layout.Mixed() {
+		l := line.Layout
+		l.Clusters = nil
+		return l
+	}
 
 	startCluster := clusterIndexFor(line, start.lineCol.X, start.clusterIndex)
 	endCluster := clusterIndexFor(line, end.lineCol.X, end.clusterIndex)
@@ -86,7 +122,10 @@ func firstPos(line text.Line, alignment text.Alignment, width int) combinedPos {
 		y: line.Ascent.Ceil(),
 	}
 
-	if line.Layout.Direction.Progression() == system.TowardOrigin {
+	switch {
+	case len(line.Layout.Clusters) > 0:
+		p.x += line.Layout.Clusters[0].X
+	case line.Layout.Direction.Progression() == system.TowardOrigin:
 		p.x += line.Width
 	}
 	return p
@@ -99,7 +138,7 @@ func (p1 screenPos) Less(p2 screenPos) bool {
 func (l Label) Layout(gtx layout.Context, s text.Shaper, font text.Font, size unit.Sp, txt string) layout.Dimensions {
 	cs := gtx.Constraints
 	textSize := fixed.I(gtx.Sp(size))
-	lines := s.LayoutString(font, textSize, cs.Max.X, gtx.Locale, txt)
+	lines := s.LayoutString(font, textSize, cs.Max.X, gtx.Locale, l.params(gtx), txt)
 	if max := l.MaxLines; max > 0 && len(lines) > max {
 		lines = lines[:max]
 	}
@@ -113,6 +152,11 @@ func (l Label) Layout(gtx layout.Context, s text.Shaper, font text.Font, size un
 	defer clip.Rect(cl).Push(gtx.Ops).Pop()
 	semantic.LabelOp(txt).Add(gtx.Ops)
 	pos := firstPos(lines[0], l.Alignment, dims.Size.X)
+	var decorations text.DecorationMetrics
+	if l.Decoration != 0 {
+		decorations = s.DecorationMetrics(font, textSize)
+	}
+	var colors colorGlyphs
 	for !posIsBelow(lines, pos, cl.Max.Y) {
 		start, end := clipLine(lines, l.Alignment, dims.Size.X, cl, pos)
 		line := lines[start.lineCol.Y]
@@ -123,16 +167,123 @@ func (l Label) Layout(gtx layout.Context, s text.Shaper, font text.Font, size un
 		op := clip.Outline{Path: s.Shape(font, textSize, lt)}.Op().Push(gtx.Ops)
 		paint.PaintOp{}.Add(gtx.Ops)
 		op.Pop()
+		paintDecorations(gtx.Ops, l.Decoration, decorations, lt)
 		t.Pop()
+		colors.add(off, s.ShapeColor(font, textSize, lt, l.TextColor))
 
 		if pos.lineCol.Y == len(lines)-1 {
 			break
 		}
 		pos, _ = seekPosition(lines, l.Alignment, dims.Size.X, pos, combinedPos{lineCol: screenPos{Y: pos.lineCol.Y + 1}}, 0)
 	}
+	colors.paint(gtx.Ops)
 	return dims
 }
 
+// Truncated reports whether Layout truncates txt to MaxLines.
+func (l Label) Truncated(gtx layout.Context, s text.Shaper, font text.Font, size unit.Sp, txt string) bool {
+	if l.MaxLines <= 0 {
+		return false
+	}
+	textSize := fixed.I(gtx.Sp(size))
+	lines := s.LayoutString(font, textSize, gtx.Constraints.Max.X, gtx.Locale, l.params(gtx), txt)
+	return truncated(lines)
+}
+
+func (l Label) params(gtx layout.Context) text.Parameters {
+	params := textParams(gtx, l.LetterSpacing, l.LineHeight, l.LineHeightScale)
+	params.MaxLines = l.MaxLines
+	params.Truncation = l.Truncation
+	params.Truncator = l.Truncator
+	params.Justify = l.Alignment == text.Justify
+	params.Hyphenator = l.Hyphenator
+	return params
+}
+
+// truncated reports whether any of lines is truncated.
+func truncated(lines []text.Line) bool {
+	for _, l := range lines {
+		if l.Truncated > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// textParams returns the layout parameters for letter spacing and line
+// height.
+func textParams(gtx layout.Context, letterSpacing, lineHeight unit.Sp, lineHeightScale float32) text.Parameters {
+	pxPerSp := gtx.Metric.PxPerSp
+	if pxPerSp == 0 {
+		pxPerSp = 1
+	}
+	return text.Parameters{
+		// The spacing is added after every cluster, so don't round it
+		// to whole pixels.
+		LetterSpacing:   fixed.Int26_6(math.Round(float64(pxPerSp) * float64(letterSpacing) * 64)),
+		LineHeight:      fixed.I(gtx.Sp(lineHeight)),
+		LineHeightScale: lineHeightScale,
+	}
+}
+
+// paintDecorations paints the decorations of a line of text with the
+// current paint material. The line starts at the origin, on the baseline.
+func paintDecorations(ops *op.Ops, d text.Decoration, m text.DecorationMetrics, l text.Layout) {
+	if d == 0 {
+		return
+	}
+	var width fixed.Int26_6
+	for _, g := range l.Glyphs {
+		width += g.XAdvance
+	}
+	line := func(pos, thickness fixed.Int26_6) {
+		y := pos.Round()
+		h := thickness.Round()
+		if h < 1 {
+			h = 1
+		}
+		r := image.Rect(0, y, width.Ceil(), y+h)
+		cl := clip.Rect(r).Push(ops)
+		paint.PaintOp{}.Add(ops)
+		cl.Pop()
+	}
+	if d&text.Underline != 0 {
+		line(m.Underline, m.UnderlineThickness)
+	}
+	if d&text.Strikethrough != 0 {
+		line(m.Strikethrough, m.StrikethroughThickness)
+	}
+	if d&text.Overline != 0 {
+		line(m.Overline, m.OverlineThickness)
+	}
+}
+
+// colorGlyphs collects the color glyphs of lines of text, to be painted
+// after the lines. Color glyphs replace the paint material, which must
+// not affect the glyphs painted in the text color.
+type colorGlyphs []colorGlyph
+
+type colorGlyph struct {
+	off  image.Point
+	call op.CallOp
+}
+
+// add the color glyphs of a line at off, if any.
+func (c *colorGlyphs) add(off image.Point, call op.CallOp) {
+	if call != (op.CallOp{}) {
+		*c = append(*c, colorGlyph{off: off, call: call})
+	}
+}
+
+// paint the color glyphs.
+func (c colorGlyphs) paint(ops *op.Ops) {
+	for _, g := range c {
+		t := op.Offset(g.off).Push(ops)
+		g.call.Add(ops)
+		t.Pop()
+	}
+}
+
 func textPadding(lines []text.Line) (padding image.Rectangle) {
 	if len(lines) == 0 {
 		return
@@ -184,23 +335,5 @@ func linesDimens(lines []text.Line) layout.Dimensions {
 // appears correctly aligned within a space of size maxWidth and with the primary
 // text direction dir.
 func align(align text.Alignment, dir system.TextDirection, width fixed.Int26_6, maxWidth int) fixed.Int26_6 {
-	mw := fixed.I(maxWidth)
-	if dir.Progression() == system.TowardOrigin {
-		switch align {
-		case text.Start:
-			align = text.End
-		case text.End:
-			align = text.Start
-		}
-	}
-	switch align {
-	case text.Middle:
-		return fixed.I(((mw - width) / 2).Floor())
-	case text.End:
-		return fixed.I((mw - width).Floor())
-	case text.Start:
-		return 0
-	default:
-		panic(fmt.Errorf("unknown alignment %v", align))
-	}
+	return align.Align(dir, width, maxWidth)
 }
@@ -0,0 +1,299 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"errors"
+	"unicode"
+)
+
+// InputFormatter validates and formats the text entered into an Editor.
+type InputFormatter interface {
+	// Edit is called for every modification made by the user, where the
+	// runes of text between start and end are replaced with insert. Edit
+	// returns the resulting text and the rune offset of the caret, or
+	// false to reject the modification.
+	Edit(text string, start, end int, insert string) (result string, caret int, ok bool)
+	// Literal reports whether the rune at offset pos of text is a literal
+	// separator. The caret skips over literal runes.
+	Literal(text string, pos int) bool
+	// Validate returns a non-nil error if text is not valid.
+	Validate(text string) error
+}
+
+// A ValidationEvent is generated when the validity of the Editor text,
+// as reported by its Formatter, changes.
+type ValidationEvent struct {
+	// Err is the validation error, or nil if the text is valid.
+	Err error
+}
+
+// ErrIncompleteInput is returned by PatternMask.Validate when the text
+// doesn't fill all the placeholders of the pattern.
+var ErrIncompleteInput = errors.New("incomplete input")
+
+// PatternMask is an InputFormatter that formats text according to a
+// fixed pattern. In the pattern, '9' is a placeholder for a digit, 'a' a
+// placeholder for a letter, and '*' a placeholder for any rune. Other
+// runes are literals, inserted automatically as the user types. For
+// example, the pattern "9999-99-99" formats dates and "(999) 999-9999"
+// formats phone numbers.
+//
+// Empty text is valid; otherwise every placeholder must be filled.
+type PatternMask struct {
+	Pattern string
+	// Check, if not nil, further validates text that fills the pattern.
+	Check func(text string) error
+}
+
+// ValidateFunc is an InputFormatter that accepts every modification and
+// validates the text with the function.
+type ValidateFunc func(text string) error
+
+// Edit implements InputFormatter. It fills the placeholders with the
+// inserted text and skips over literals.
+func (m PatternMask) Edit(text string, start, end int, insert string) (string, int, bool) {
+	pat := []rune(m.Pattern)
+	raw, rs, re := m.raw(text, start, end)
+	if insert == "" && start < end && rs == re && rs > 0 {
+		// Deleting only literals deletes the placeholder before them.
+		rs--
+	}
+	var ins []rune
+	slot := rs
+	for _, r := range insert {
+		pi := m.slotIndex(pat, slot)
+		switch {
+		case pi < len(pat) && matchPlaceholder(pat[pi], r):
+			ins = append(ins, r)
+			slot++
+		case isLiteral(pat, r):
+			// Ignore literals typed or pasted by the user.
+		default:
+			return "", 0, false
+		}
+	}
+	res := make([]rune, 0, len(raw)+len(ins))
+	res = append(res, raw[:rs]...)
+	res = append(res, ins...)
+	res = append(res, raw[re:]...)
+	for i, r := range res {
+		pi := m.slotIndex(pat, i)
+		if pi == len(pat) || !matchPlaceholder(pat[pi], r) {
+			return "", 0, false
+		}
+	}
+	formatted := m.format(pat, res)
+	caret := m.slotIndex(pat, slot)
+	if n := len([]rune(formatted)); caret > n {
+		caret = n
+	}
+	return formatted, caret, true
+}
+
+// Literal implements InputFormatter.
+func (m PatternMask) Literal(text string, pos int) bool {
+	pat := []rune(m.Pattern)
+	return pos >= 0 && pos < len(pat) && !isPlaceholder(pat[pos])
+}
+
+// Validate implements InputFormatter.
+func (m PatternMask) Validate(text string) error {
+	if text == "" {
+		return nil
+	}
+	pat := []rune(m.Pattern)
+	txt := []rune(text)
+	if len(txt) != len(pat) {
+		return ErrIncompleteInput
+	}
+	for i, r := range txt {
+		if p := pat[i]; isPlaceholder(p) && !matchPlaceholder(p, r) || !isPlaceholder(p) && p != r {
+			return ErrIncompleteInput
+		}
+	}
+	if m.Check != nil {
+		return m.Check(text)
+	}
+	return nil
+}
+
+// raw returns the runes of text that fill placeholders, along with the
+// rune offsets start and end converted to indices into them.
+func (m PatternMask) raw(text string, start, end int) ([]rune, int, int) {
+	pat := []rune(m.Pattern)
+	var raw []rune
+	rs, re := 0, 0
+	i := 0
+	for _, r := range text {
+		if i >= len(pat) || isPlaceholder(pat[i]) {
+			raw = append(raw, r)
+			if i < start {
+				rs++
+			}
+			if i < end {
+				re++
+			}
+		}
+		i++
+	}
+	return raw, rs, re
+}
+
+// slotIndex returns the pattern index of placeholder n, or len(pat) if
+// there is no such placeholder.
+func (m PatternMask) slotIndex(pat []rune, n int) int {
+	for i, p := range pat {
+		if !isPlaceholder(p) {
+			continue
+		}
+		if n == 0 {
+			return i
+		}
+		n--
+	}
+	return len(pat)
+}
+
+// format interleaves raw with the literals of the pattern. Literals are
+// only added before placeholders that are filled.
+func (m PatternMask) format(pat, raw []rune) string {
+	var res []rune
+	var lits []rune
+	for _, p := range pat {
+		if len(raw) == 0 {
+			break
+		}
+		if !isPlaceholder(p) {
+			lits = append(lits, p)
+			continue
+		}
+		res = append(res, lits...)
+		lits = lits[:0]
+		res = append(res, raw[0])
+		raw = raw[1:]
+	}
+	return string(res)
+}
+
+func isPlaceholder(p rune) bool {
+	return p == '9' || p == 'a' || p == '*'
+}
+
+func matchPlaceholder(p, r rune) bool {
+	switch p {
+	case '9':
+		return unicode.IsDigit(r)
+	case 'a':
+		return unicode.IsLetter(r)
+	default:
+		return true
+	}
+}
+
+func isLiteral(pat []rune, r rune) bool {
+	for _, p := range pat {
+		if !isPlaceholder(p) && p == r {
+			return true
+		}
+	}
+	return false
+}
+
+// Edit implements InputFormatter. It accepts every modification.
+func (f ValidateFunc) Edit(text string, start, end int, insert string) (string, int, bool) {
+	txt := []rune(text)
+	res := string(txt[:start]) + insert + string(txt[end:])
+	return res, start + len([]rune(insert)), true
+}
+
+// Literal implements InputFormatter. The text has no literals.
+func (f ValidateFunc) Literal(text string, pos int) bool {
+	return false
+}
+
+// Validate implements InputFormatter by calling f.
+func (f ValidateFunc) Validate(text string) error {
+	return f(text)
+}
+
+// InputErr returns the error reported by the Formatter for the text, or
+// nil if the text is valid.
+func (e *Editor) InputErr() error {
+	return e.inputErr
+}
+
+// formatReplace is like replace, but filters the modification through
+// the Formatter. It leaves the caret where the Formatter puts it, and
+// returns its distance from start.
+func (e *Editor) formatReplace(start, end int, s string) int {
+	if start > end {
+		start, end = end, start
+	}
+	start = e.closestPosition(combinedPos{runes: start}).runes
+	end = e.closestPosition(combinedPos{runes: end}).runes
+	old := []rune(e.Text())
+	res, caret, ok := e.Formatter.Edit(string(old), start, end, s)
+	if !ok {
+		return 0
+	}
+	// Replace only the runes that differ, to keep the undo history and
+	// styles of the unchanged text.
+	txt := []rune(res)
+	p := 0
+	for p < len(old) && p < len(txt) && old[p] == txt[p] {
+		p++
+	}
+	q := 0
+	for q < len(old)-p && q < len(txt)-p && old[len(old)-1-q] == txt[len(txt)-1-q] {
+		q++
+	}
+	if p < len(old)-q || p < len(txt)-q {
+		e.replaceInherit(p, len(old)-q, string(txt[p:len(txt)-q]), true)
+	}
+	caret = e.closestPosition(combinedPos{runes: caret}).runes
+	e.caret.start, e.caret.end = caret, caret
+	return caret - start
+}
+
+// skipLiterals moves the caret over the Formatter literals in the
+// direction of dir.
+func (e *Editor) skipLiterals(dir int, selAct selectionAction) {
+	if e.Formatter == nil {
+		return
+	}
+	txt := e.Text()
+	n := e.Len()
+	for {
+		pos := e.caret.start
+		if dir < 0 {
+			pos--
+		}
+		if pos < 0 || pos >= n || !e.Formatter.Literal(txt, pos) {
+			break
+		}
+		e.MoveCaret(dir, dir*int(selAct))
+	}
+}
+
+// validate updates the validation state from the Formatter, and queues a
+// ValidationEvent if it changed.
+func (e *Editor) validate() {
+	var err error
+	if e.Formatter != nil {
+		err = e.Formatter.Validate(e.Text())
+	}
+	if errString(err) != errString(e.inputErr) {
+		e.events = append(e.events, ValidationEvent{Err: err})
+	}
+	e.inputErr = err
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func (ValidationEvent) isEditorEvent() {}
@@ -7,6 +7,7 @@ import (
 	"image/color"
 	"math"
 
+	"github.com/xiaoshengduan/gio-fly/animation"
 	"github.com/xiaoshengduan/gio-fly/internal/f32color"
 	"github.com/xiaoshengduan/gio-fly/io/semantic"
 	"github.com/xiaoshengduan/gio-fly/layout"
@@ -118,7 +119,7 @@ func (b ButtonStyle) Layout(gtx layout.Context) layout.Dimensions {
 	}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
 		return b.Inset.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
 			paint.ColorOp{Color: b.Color}.Add(gtx.Ops)
-			return widget.Label{Alignment: text.Middle}.Layout(gtx, b.shaper, b.Font, b.TextSize, b.Text)
+			return widget.Label{Alignment: text.Middle, TextColor: b.Color}.Layout(gtx, b.shaper, b.Font, b.TextSize, b.Text)
 		})
 	})
 }
@@ -272,8 +273,8 @@ func drawInk(gtx layout.Context, c widget.Press) {
 	// Twice the speed to attain fully faded in at 0.5.
 	t2 := alphat * 2
 	// Beziér ease-in curve.
-	alphaBezier := t2 * t2 * (3.0 - 2.0*t2)
-	sizeBezier := sizet * sizet * (3.0 - 2.0*sizet)
+	alphaBezier := animation.SmoothStep(t2)
+	sizeBezier := animation.SmoothStep(sizet)
 	size := gtx.Constraints.Min.X
 	if h := gtx.Constraints.Min.Y; h > size {
 		size = h
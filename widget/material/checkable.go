@@ -35,7 +35,7 @@ func (c *checkable) layout(gtx layout.Context, checked, hovered bool) layout.Dim
 		icon = c.uncheckedStateIcon
 	}
 
-	dims := layout.Flex{Alignment: layout.Middle}.Layout(gtx,
+	dims := layout.Flex{Alignment: layout.Middle, Mirror: true}.Layout(gtx,
 		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
 			return layout.Stack{Alignment: layout.Center}.Layout(gtx,
 				layout.Stacked(func(gtx layout.Context) layout.Dimensions {
@@ -74,7 +74,7 @@ func (c *checkable) layout(gtx layout.Context, checked, hovered bool) layout.Dim
 		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
 			return layout.UniformInset(2).Layout(gtx, func(gtx layout.Context) layout.Dimensions {
 				paint.ColorOp{Color: c.Color}.Add(gtx.Ops)
-				return widget.Label{}.Layout(gtx, c.shaper, c.Font, c.TextSize, c.Label)
+				return widget.Label{TextColor: c.Color}.Layout(gtx, c.shaper, c.Font, c.TextSize, c.Label)
 			})
 		}),
 	)
@@ -26,7 +26,10 @@ type EditorStyle struct {
 	HintColor color.NRGBA
 	// SelectionColor is the color of the background for selected text.
 	SelectionColor color.NRGBA
-	Editor         *widget.Editor
+	// ErrorColor is the text color used when the Editor Formatter reports
+	// the text as invalid.
+	ErrorColor color.NRGBA
+	Editor     *widget.Editor
 
 	shaper text.Shaper
 }
@@ -40,6 +43,7 @@ func Editor(th *Theme, editor *widget.Editor, hint string) EditorStyle {
 		Hint:           hint,
 		HintColor:      f32color.MulAlpha(th.Palette.Fg, 0xbb),
 		SelectionColor: f32color.MulAlpha(th.Palette.ContrastBg, 0x60),
+		ErrorColor:     color.NRGBA{R: 0xb0, G: 0x00, B: 0x20, A: 0xff},
 	}
 }
 
@@ -50,7 +54,7 @@ func (e EditorStyle) Layout(gtx layout.Context) layout.Dimensions {
 	if e.Editor.SingleLine {
 		maxlines = 1
 	}
-	tl := widget.Label{Alignment: e.Editor.Alignment, MaxLines: maxlines}
+	tl := widget.Label{Alignment: e.Editor.Alignment, MaxLines: maxlines, TextColor: e.HintColor}
 	dims := tl.Layout(gtx, e.shaper, e.Font, e.TextSize, e.Hint)
 	call := macro.Stop()
 	if w := dims.Size.X; gtx.Constraints.Min.X < w {
@@ -65,7 +69,13 @@ func (e EditorStyle) Layout(gtx layout.Context) layout.Dimensions {
 		if e.Editor.Len() > 0 {
 			paint.ColorOp{Color: blendDisabledColor(disabled, e.SelectionColor)}.Add(gtx.Ops)
 			e.Editor.PaintSelection(gtx)
-			paint.ColorOp{Color: blendDisabledColor(disabled, e.Color)}.Add(gtx.Ops)
+			textColor := e.Color
+			if e.Editor.InputErr() != nil {
+				textColor = e.ErrorColor
+			}
+			textColor = blendDisabledColor(disabled, textColor)
+			paint.ColorOp{Color: textColor}.Add(gtx.Ops)
+			e.Editor.TextColor = textColor
 			e.Editor.PaintText(gtx)
 		} else {
 			call.Add(gtx.Ops)
@@ -19,10 +19,28 @@ type LabelStyle struct {
 	Color color.NRGBA
 	// Alignment specify the text alignment.
 	Alignment text.Alignment
-	// MaxLines limits the number of lines. Zero means no limit.
+	// MaxLines limits the number of lines. Zero means no limit. The text
+	// that doesn't fit is replaced by Truncator.
 	MaxLines int
-	Text     string
-	TextSize unit.Sp
+	// Truncation selects the part of the text replaced by Truncator.
+	Truncation text.Truncation
+	// Truncator is the text shown in place of truncated text. The empty
+	// string means "…".
+	Truncator string
+	// Hyphenator, if not nil, hyphenates words at the ends of lines.
+	Hyphenator text.Hyphenator
+	// Decoration is the set of lines drawn along the text.
+	Decoration text.Decoration
+	// LetterSpacing is the extra space after every character.
+	LetterSpacing unit.Sp
+	// LineHeight is the height of every line. Zero means the height of
+	// the font, scaled by LineHeightScale.
+	LineHeight unit.Sp
+	// LineHeightScale scales the height of the font for the height of
+	// lines, unless LineHeight is set. Zero means 1.
+	LineHeightScale float32
+	Text            string
+	TextSize        unit.Sp
 
 	shaper text.Shaper
 }
@@ -94,6 +112,26 @@ func Label(th *Theme, size unit.Sp, txt string) LabelStyle {
 
 func (l LabelStyle) Layout(gtx layout.Context) layout.Dimensions {
 	paint.ColorOp{Color: l.Color}.Add(gtx.Ops)
-	tl := widget.Label{Alignment: l.Alignment, MaxLines: l.MaxLines}
-	return tl.Layout(gtx, l.shaper, l.Font, l.TextSize, l.Text)
+	return l.label().Layout(gtx, l.shaper, l.Font, l.TextSize, l.Text)
+}
+
+// Truncated reports whether Layout truncates the text to MaxLines, for
+// example to show the full text in a tooltip.
+func (l LabelStyle) Truncated(gtx layout.Context) bool {
+	return l.label().Truncated(gtx, l.shaper, l.Font, l.TextSize, l.Text)
+}
+
+func (l LabelStyle) label() widget.Label {
+	return widget.Label{
+		Alignment:       l.Alignment,
+		MaxLines:        l.MaxLines,
+		Truncation:      l.Truncation,
+		Truncator:       l.Truncator,
+		Hyphenator:      l.Hyphenator,
+		Decoration:      l.Decoration,
+		LetterSpacing:   l.LetterSpacing,
+		LineHeight:      l.LineHeight,
+		LineHeightScale: l.LineHeightScale,
+		TextColor:       l.Color,
+	}
 }
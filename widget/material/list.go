@@ -7,7 +7,9 @@ import (
 	"image/color"
 	"math"
 
+	"github.com/xiaoshengduan/gio-fly/f32"
 	"github.com/xiaoshengduan/gio-fly/io/pointer"
+	"github.com/xiaoshengduan/gio-fly/io/system"
 	"github.com/xiaoshengduan/gio-fly/layout"
 	"github.com/xiaoshengduan/gio-fly/op"
 	"github.com/xiaoshengduan/gio-fly/op/clip"
@@ -232,13 +234,19 @@ type ListStyle struct {
 	state *widget.List
 	ScrollbarStyle
 	AnchorStrategy
+	// GlowColor is the color of the glow drawn at the overscrolled end
+	// of a list with the layout.OverscrollGlow effect.
+	GlowColor color.NRGBA
 }
 
 // List constructs a ListStyle using the provided theme and state.
 func List(th *Theme, state *widget.List) ListStyle {
+	glow := th.Palette.ContrastBg
+	glow.A = 0x60
 	return ListStyle{
 		state:          state,
 		ScrollbarStyle: Scrollbar(th, &state.Scrollbar),
+		GlowColor:      glow,
 	}
 }
 
@@ -266,11 +274,24 @@ func (l ListStyle) Layout(gtx layout.Context, length int, w layout.ListElement)
 		gtx.Constraints.Min = l.state.Axis.Convert(min)
 	}
 
+	// The vertical scrollbar is at the start of the lines of text, which is
+	// on the left for right-to-left locales.
+	var barStart image.Point
+	if l.AnchorStrategy == Occupy && l.state.Axis == layout.Vertical && gtx.Locale.Direction.Progression() == system.TowardOrigin {
+		barStart.X = barWidth
+	}
+	trans := op.Offset(barStart).Push(gtx.Ops)
 	listDims := l.state.List.Layout(gtx, length, w)
+	trans.Pop()
 	gtx.Constraints = originalConstraints
+	if l.state.Overscroll.Effect == layout.OverscrollGlow {
+		trans := op.Offset(barStart).Push(gtx.Ops)
+		l.layoutGlow(gtx, listDims.Size)
+		trans.Pop()
+	}
 
 	// Draw the scrollbar.
-	anchoring := layout.E
+	anchoring := layout.CenterEnd
 	if l.state.Axis == layout.Horizontal {
 		anchoring = layout.S
 	}
@@ -309,3 +330,47 @@ func (l ListStyle) Layout(gtx layout.Context, length int, w layout.ListElement)
 
 	return listDims
 }
+
+// layoutGlow draws a glow over the overscrolled end of a list of the
+// given size.
+func (l ListStyle) layoutGlow(gtx layout.Context, size image.Point) {
+	over := l.state.Overscrolled()
+	if over == 0 {
+		return
+	}
+	axis := l.state.Axis
+	sz := axis.Convert(size)
+	glow := image.Rectangle{Max: image.Pt(abs(over), sz.Y)}
+	edge, inner := float32(0), float32(glow.Max.X)
+	if over > 0 {
+		// Glow at the end of the list.
+		glow = glow.Add(image.Pt(sz.X-glow.Max.X, 0))
+		edge, inner = float32(sz.X), float32(glow.Min.X)
+	}
+	transparent := l.GlowColor
+	transparent.A = 0
+	pt := func(main float32) f32.Point {
+		if axis == layout.Horizontal {
+			return f32.Pt(main, 0)
+		}
+		return f32.Pt(0, main)
+	}
+	defer clip.Rect(image.Rectangle{
+		Min: axis.Convert(glow.Min),
+		Max: axis.Convert(glow.Max),
+	}).Push(gtx.Ops).Pop()
+	paint.LinearGradientOp{
+		Stop1:  pt(edge),
+		Color1: l.GlowColor,
+		Stop2:  pt(inner),
+		Color2: transparent,
+	}.Add(gtx.Ops)
+	paint.PaintOp{}.Add(gtx.Ops)
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,95 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package material
+
+import (
+	"image"
+
+	"github.com/xiaoshengduan/gio-fly/io/system"
+	"github.com/xiaoshengduan/gio-fly/layout"
+	"github.com/xiaoshengduan/gio-fly/op"
+	"github.com/xiaoshengduan/gio-fly/widget"
+)
+
+// ScrollViewStyle configures the presentation of a widget.ScrollView
+// with vertical and horizontal scrollbars.
+type ScrollViewStyle struct {
+	state                *widget.ScrollView
+	Vertical, Horizontal ScrollbarStyle
+	AnchorStrategy
+}
+
+// ScrollView constructs a ScrollViewStyle using the provided theme and
+// state.
+func ScrollView(th *Theme, state *widget.ScrollView) ScrollViewStyle {
+	return ScrollViewStyle{
+		state:      state,
+		Vertical:   Scrollbar(th, &state.Vertical),
+		Horizontal: Scrollbar(th, &state.Horizontal),
+	}
+}
+
+// Layout the scroll view and its scrollbars.
+func (s ScrollViewStyle) Layout(gtx layout.Context, w layout.Widget) layout.Dimensions {
+	originalConstraints := gtx.Constraints
+
+	// Determine how much space the scrollbars occupy.
+	vWidth := gtx.Dp(s.Vertical.Width())
+	hWidth := gtx.Dp(s.Horizontal.Width())
+	if s.AnchorStrategy == Occupy {
+		// Reserve space for the scrollbars using the gtx constraints.
+		gtx.Constraints.Max.X = max(gtx.Constraints.Max.X-vWidth, 0)
+		gtx.Constraints.Max.Y = max(gtx.Constraints.Max.Y-hWidth, 0)
+		gtx.Constraints.Min.X = max(gtx.Constraints.Min.X-vWidth, 0)
+		gtx.Constraints.Min.Y = max(gtx.Constraints.Min.Y-hWidth, 0)
+	}
+
+	// The vertical scrollbar is on the left for right-to-left locales.
+	var barStart image.Point
+	if s.AnchorStrategy == Occupy && gtx.Locale.Direction.Progression() == system.TowardOrigin {
+		barStart.X = vWidth
+	}
+	trans := op.Offset(barStart).Push(gtx.Ops)
+	dims := s.state.Layout(gtx, w)
+	trans.Pop()
+	gtx.Constraints = originalConstraints
+
+	// Draw the scrollbars along the edges of the view.
+	gtx.Constraints.Min = dims.Size
+	if s.AnchorStrategy == Occupy {
+		gtx.Constraints.Min.X += vWidth
+		gtx.Constraints.Min.Y += hWidth
+	}
+	barGtx := gtx
+	barGtx.Constraints.Min.Y = dims.Size.Y
+	start, end := s.state.Viewport(layout.Vertical)
+	layout.CenterEnd.Layout(barGtx, func(gtx layout.Context) layout.Dimensions {
+		gtx.Constraints.Max.Y = dims.Size.Y
+		return s.Vertical.Layout(gtx, layout.Vertical, start, end)
+	})
+	barGtx = gtx
+	barGtx.Constraints.Min.X = dims.Size.X
+	start, end = s.state.Viewport(layout.Horizontal)
+	// The horizontal scrollbar is offset with the view.
+	trans = op.Offset(barStart).Push(gtx.Ops)
+	layout.S.Layout(barGtx, func(gtx layout.Context) layout.Dimensions {
+		gtx.Constraints.Max.X = dims.Size.X
+		return s.Horizontal.Layout(gtx, layout.Horizontal, start, end)
+	})
+	trans.Pop()
+
+	// Handle any changes to the position as a result of user interaction
+	// with the scrollbars.
+	dx, dy := s.state.Horizontal.ScrollDistance(), s.state.Vertical.ScrollDistance()
+	if dx != 0 || dy != 0 {
+		s.state.ScrollBy(dx, dy)
+	}
+
+	if s.AnchorStrategy == Occupy {
+		// Increase the size to account for the space occupied by the
+		// scrollbars.
+		dims.Size.X += vWidth
+		dims.Size.Y += hWidth
+	}
+	return dims
+}
@@ -0,0 +1,67 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package material
+
+import (
+	"image/color"
+
+	"github.com/xiaoshengduan/gio-fly/internal/f32color"
+	"github.com/xiaoshengduan/gio-fly/io/semantic"
+	"github.com/xiaoshengduan/gio-fly/layout"
+	"github.com/xiaoshengduan/gio-fly/op/paint"
+	"github.com/xiaoshengduan/gio-fly/text"
+	"github.com/xiaoshengduan/gio-fly/unit"
+	"github.com/xiaoshengduan/gio-fly/widget"
+)
+
+// SelectableLabelStyle is a label whose text can be selected and copied.
+type SelectableLabelStyle struct {
+	// Font defines the text style.
+	Font text.Font
+	// Color is the text color.
+	Color color.NRGBA
+	// SelectionColor is the color of the background for selected text.
+	SelectionColor color.NRGBA
+	// Alignment specify the text alignment.
+	Alignment text.Alignment
+	Text      string
+	TextSize  unit.Sp
+	// Hint contains the text displayed when Text is empty.
+	Hint string
+	// HintColor is the color of hint text.
+	HintColor color.NRGBA
+	State     *widget.Selectable
+
+	shaper text.Shaper
+}
+
+func SelectableLabel(th *Theme, state *widget.Selectable, size unit.Sp, txt string) SelectableLabelStyle {
+	return SelectableLabelStyle{
+		Text:           txt,
+		Color:          th.Palette.Fg,
+		SelectionColor: f32color.MulAlpha(th.Palette.ContrastBg, 0x60),
+		HintColor:      f32color.MulAlpha(th.Palette.Fg, 0xbb),
+		TextSize:       size,
+		State:          state,
+		shaper:         th.Shaper,
+	}
+}
+
+func (l SelectableLabelStyle) Layout(gtx layout.Context) layout.Dimensions {
+	if l.Text == "" && l.Hint != "" {
+		paint.ColorOp{Color: l.HintColor}.Add(gtx.Ops)
+		tl := widget.Label{Alignment: l.Alignment, TextColor: l.HintColor}
+		return tl.Layout(gtx, l.shaper, l.Font, l.TextSize, l.Hint)
+	}
+	l.State.Alignment = l.Alignment
+	l.State.TextColor = l.Color
+	l.State.SetText(l.Text)
+	return l.State.Layout(gtx, l.shaper, l.Font, l.TextSize, func(gtx layout.Context) layout.Dimensions {
+		semantic.LabelOp(l.Text).Add(gtx.Ops)
+		paint.ColorOp{Color: l.SelectionColor}.Add(gtx.Ops)
+		l.State.PaintSelection(gtx)
+		paint.ColorOp{Color: l.Color}.Add(gtx.Ops)
+		l.State.PaintText(gtx)
+		return layout.Dimensions{}
+	})
+}
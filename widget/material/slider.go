@@ -61,19 +61,26 @@ func (s SliderStyle) Layout(gtx layout.Context) layout.Dimensions {
 		color = f32color.Disabled(color)
 	}
 
+	// The active track is between the start and the thumb, which is
+	// after the thumb if the slider is mirrored.
+	active, inactive := color, f32color.MulAlpha(color, 96)
+	if s.Float.Mirrored() {
+		active, inactive = inactive, active
+	}
+
 	// Draw track before thumb.
 	track := image.Rectangle{
 		Min: axis.Convert(image.Pt(thumbRadius, sizeCross/2-trackWidth/2)),
 		Max: axis.Convert(image.Pt(thumbPos, sizeCross/2+trackWidth/2)),
 	}
-	paint.FillShape(gtx.Ops, color, clip.Rect(track).Op())
+	paint.FillShape(gtx.Ops, active, clip.Rect(track).Op())
 
 	// Draw track after thumb.
 	track = image.Rectangle{
 		Min: axis.Convert(image.Pt(thumbPos, axis.Convert(track.Min).Y)),
 		Max: axis.Convert(image.Pt(sizeMain-thumbRadius, axis.Convert(track.Max).Y)),
 	}
-	paint.FillShape(gtx.Ops, f32color.MulAlpha(color, 96), clip.Rect(track).Op())
+	paint.FillShape(gtx.Ops, inactive, clip.Rect(track).Op())
 
 	// Draw thumb.
 	pt := axis.Convert(image.Pt(thumbPos, sizeCross/2))
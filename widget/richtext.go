@@ -0,0 +1,875 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"encoding/json"
+	"image"
+	"image/color"
+	"sort"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gioui/uax/segment"
+	"github.com/gioui/uax/uax14"
+
+	"github.com/xiaoshengduan/gio-fly/io/system"
+	"github.com/xiaoshengduan/gio-fly/layout"
+	"github.com/xiaoshengduan/gio-fly/op"
+	"github.com/xiaoshengduan/gio-fly/op/clip"
+	"github.com/xiaoshengduan/gio-fly/op/paint"
+	"github.com/xiaoshengduan/gio-fly/text"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// TextStyle describes the inline style of a run of text in an Editor.
+type TextStyle struct {
+	// Bold draws the text with a bold font weight.
+	Bold bool
+	// Italic draws the text with an italic font style.
+	Italic bool
+	// Color overrides the text color, unless its alpha is zero.
+	Color color.NRGBA
+	// Link is the target of a hyperlink, if non-empty.
+	Link string
+	// Object is the inline object displayed in place of the text. Runs
+	// of objects are always a single ObjectRune long.
+	Object *InlineObject `json:"-"`
+}
+
+// StyleRun is a TextStyle applied to a range of runes.
+type StyleRun struct {
+	// Start and End are the rune offsets of the run. End is exclusive.
+	Start, End int
+	Style      TextStyle
+}
+
+// InlineObject is a non-text object such as an image or a chip, embedded
+// in the text of an Editor. An object occupies a single ObjectRune in the
+// text.
+type InlineObject struct {
+	// Widget draws the object. The returned dimensions determine the
+	// space reserved for the object, and its baseline is aligned with the
+	// text baseline.
+	Widget layout.Widget
+
+	dims layout.Dimensions
+	// call is the object laid out in the current frame.
+	call op.CallOp
+}
+
+// RichText is text with style runs, as transferred by the Editor in the
+// RichTextMIME format.
+type RichText struct {
+	Text   string
+	Styles []StyleRun
+}
+
+// A LinkEvent is generated when the user clicks linked text in an Editor
+// while holding the shortcut modifier, or in a ReadOnly Editor.
+type LinkEvent struct {
+	Link string
+}
+
+// ObjectRune is the rune that represents an InlineObject in the text.
+const ObjectRune = '\ufffc'
+
+// RichTextMIME is the MIME type of RichText encoded by MarshalRichText.
+// Editors copy the selection to the clipboard in this type along with
+// its plain text, offer it when the selection is dragged, and accept it
+// from pastes and drops.
+const RichTextMIME = "application/x-gio-richtext+json"
+
+// MarshalRichText encodes rt in the RichTextMIME format. Inline objects
+// are not encoded.
+func MarshalRichText(rt RichText) ([]byte, error) {
+	return json.Marshal(rt)
+}
+
+// UnmarshalRichText decodes text in the RichTextMIME format. It returns
+// an error for style runs outside the text.
+func UnmarshalRichText(data []byte) (RichText, error) {
+	var rt RichText
+	if err := json.Unmarshal(data, &rt); err != nil {
+		return RichText{}, err
+	}
+	if err := checkRuns(rt.Styles, utf8.RuneCountInString(rt.Text)); err != nil {
+		return RichText{}, err
+	}
+	rt.Styles = normalizeRuns(rt.Styles)
+	return rt, nil
+}
+
+// equal reports whether rt and o have the same text and styles.
+func (rt RichText) equal(o RichText) bool {
+	if rt.Text != o.Text || len(rt.Styles) != len(o.Styles) {
+		return false
+	}
+	for i, r := range rt.Styles {
+		if r != o.Styles[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// styleSpan is a run of a line's glyphs that share a style.
+type styleSpan struct {
+	style TextStyle
+	font  text.Font
+	// glyphs is the range of the span within the line's glyphs.
+	glyphs text.Range
+	// x is the offset of the span from the start of the line.
+	x               fixed.Int26_6
+	ascent, descent fixed.Int26_6
+}
+
+// styled reports whether the editor lays out its text with style runs.
+func (e *Editor) styled() bool {
+	return len(e.styles) > 0 && e.Mask == 0
+}
+
+// StyleAt returns the style of the rune at offset pos.
+func (e *Editor) StyleAt(pos int) TextStyle {
+	i := sort.Search(len(e.styles), func(i int) bool {
+		return e.styles[i].End > pos
+	})
+	if i < len(e.styles) && e.styles[i].Start <= pos {
+		return e.styles[i].Style
+	}
+	return TextStyle{}
+}
+
+// Styles returns the style runs of the text, sorted by offset. Unstyled
+// text is not covered by any run.
+func (e *Editor) Styles() []StyleRun {
+	return append([]StyleRun(nil), e.styles...)
+}
+
+// SetStyle applies s to the runes between start and end. Inline objects
+// in the range are kept.
+func (e *Editor) SetStyle(start, end int, s TextStyle) {
+	e.updateStyle(start, end, func(TextStyle) TextStyle {
+		return s
+	})
+}
+
+// ToggleBold toggles bold text for the selection, or for text typed at the
+// caret if there is no selection.
+func (e *Editor) ToggleBold() {
+	e.toggleStyle(func(s *TextStyle) *bool { return &s.Bold })
+}
+
+// ToggleItalic toggles italic text for the selection, or for text typed at
+// the caret if there is no selection.
+func (e *Editor) ToggleItalic() {
+	e.toggleStyle(func(s *TextStyle) *bool { return &s.Italic })
+}
+
+func (e *Editor) toggleStyle(attr func(s *TextStyle) *bool) {
+	start, end := e.caret.start, e.caret.end
+	if start > end {
+		start, end = end, start
+	}
+	if start == end {
+		s := e.insertStyle(start)
+		*attr(&s) = !*attr(&s)
+		e.pending.style = s
+		e.pending.at = start
+		e.pending.set = true
+		return
+	}
+	// Clear the attribute if the whole selection has it, and set it
+	// otherwise.
+	set := false
+	for i := start; i < end; i++ {
+		s := e.StyleAt(i)
+		if !*attr(&s) {
+			set = true
+			break
+		}
+	}
+	e.updateStyle(start, end, func(s TextStyle) TextStyle {
+		*attr(&s) = set
+		return s
+	})
+}
+
+// insertStyle returns the style for text inserted at pos.
+func (e *Editor) insertStyle(pos int) TextStyle {
+	if e.pending.set && e.pending.at == pos && e.caret.start == e.caret.end {
+		return e.pending.style
+	}
+	if pos > 0 {
+		pos--
+	}
+	s := e.StyleAt(pos)
+	s.Object = nil
+	return s
+}
+
+// updateStyle replaces the style of every rune between start and end by
+// the result of f, and records the change in the undo history.
+func (e *Editor) updateStyle(start, end int, f func(TextStyle) TextStyle) {
+	if start > end {
+		start, end = end, start
+	}
+	start = e.closestPosition(combinedPos{runes: start}).runes
+	end = e.closestPosition(combinedPos{runes: end}).runes
+	if start == end {
+		return
+	}
+	old := stylesIn(e.styles, start, end)
+	var runs []StyleRun
+	pos := 0
+	add := func(from, to int, st TextStyle) {
+		n := f(st)
+		n.Object = st.Object
+		runs = append(runs, StyleRun{Start: from, End: to, Style: n})
+	}
+	for _, r := range old {
+		if pos < r.Start {
+			add(pos, r.Start, TextStyle{})
+		}
+		add(r.Start, r.End, r.Style)
+		pos = r.End
+	}
+	if pos < end-start {
+		add(pos, end-start, TextStyle{})
+	}
+	runs = normalizeRuns(runs)
+	content := e.textRange(start, end)
+	e.record(modification{
+		StartRune:      start,
+		ApplyContent:   content,
+		ReverseContent: content,
+		ApplyStyles:    runs,
+		ReverseStyles:  old,
+	})
+	e.restyle(start, end, runs)
+	e.rr.changed = true
+}
+
+// restyle replaces the style runs between start and end with runs, whose
+// offsets are relative to start.
+func (e *Editor) restyle(start, end int, runs []StyleRun) {
+	styles := removeRuns(e.styles, start, end, false)
+	for _, r := range runs {
+		r.Start += start
+		r.End += start
+		if r.End > end {
+			r.End = end
+		}
+		styles = append(styles, r)
+	}
+	e.styles = normalizeRuns(styles)
+	e.invalidate()
+}
+
+// textRange returns the text between the rune offsets start and end.
+func (e *Editor) textRange(start, end int) string {
+	startOff := e.runeOffset(start)
+	endOff := e.runeOffset(end)
+	buf := make([]byte, endOff-startOff)
+	e.rr.Seek(int64(startOff), 0)
+	e.rr.Read(buf)
+	return string(buf)
+}
+
+// SelectedRichText returns the selected text along with its style runs.
+func (e *Editor) SelectedRichText() RichText {
+	start, end := e.caret.start, e.caret.end
+	if start > end {
+		start, end = end, start
+	}
+	return RichText{
+		Text:   e.SelectedText(),
+		Styles: stylesIn(e.styles, start, end),
+	}
+}
+
+// InsertRichText inserts styled text at the caret, moving the caret
+// forward. If there is a selection, InsertRichText overwrites it.
+func (e *Editor) InsertRichText(rt RichText) {
+	e.appendStyled(rt.Text, rt.Styles)
+	e.caret.scroll = true
+}
+
+// InsertObject inserts an inline object at the caret, moving the caret
+// forward. If there is a selection, InsertObject overwrites it.
+func (e *Editor) InsertObject(obj *InlineObject) {
+	s := e.insertStyle(min(e.caret.start, e.caret.end))
+	s.Object = obj
+	e.appendStyled(string(ObjectRune), []StyleRun{{Start: 0, End: 1, Style: s}})
+	e.caret.scroll = true
+}
+
+// Objects returns the inline objects and their rune offsets.
+func (e *Editor) Objects() []StyleRun {
+	var objs []StyleRun
+	for _, r := range e.styles {
+		if r.Style.Object != nil {
+			objs = append(objs, r)
+		}
+	}
+	return objs
+}
+
+// stylesIn returns the runs between start and end, clipped and relative
+// to start.
+func stylesIn(runs []StyleRun, start, end int) []StyleRun {
+	var res []StyleRun
+	for _, r := range runs {
+		if r.End <= start || r.Start >= end {
+			continue
+		}
+		r.Start = max(r.Start, start) - start
+		r.End = min(r.End, end) - start
+		res = append(res, r)
+	}
+	return res
+}
+
+// removeRuns removes the runes between start and end from runs. If shift
+// is set, the runs after end are moved to account for the removed runes.
+func removeRuns(runs []StyleRun, start, end int, shift bool) []StyleRun {
+	n := end - start
+	if !shift {
+		n = 0
+	}
+	res := make([]StyleRun, 0, len(runs)+1)
+	for _, r := range runs {
+		switch {
+		case r.End <= start:
+			res = append(res, r)
+		case r.Start >= end:
+			r.Start -= n
+			r.End -= n
+			res = append(res, r)
+		default:
+			if r.Start < start {
+				res = append(res, StyleRun{Start: r.Start, End: start, Style: r.Style})
+			}
+			if r.End > end {
+				res = append(res, StyleRun{Start: end - n, End: r.End - n, Style: r.Style})
+			}
+		}
+	}
+	return res
+}
+
+// insertRuns opens a gap of n runes at pos and fills it with runs, whose
+// offsets are relative to pos.
+func insertRuns(styles []StyleRun, pos, n int, runs []StyleRun) []StyleRun {
+	res := make([]StyleRun, 0, len(styles)+len(runs)+1)
+	for _, r := range styles {
+		switch {
+		case r.End <= pos:
+			res = append(res, r)
+		case r.Start >= pos:
+			r.Start += n
+			r.End += n
+			res = append(res, r)
+		default:
+			res = append(res,
+				StyleRun{Start: r.Start, End: pos, Style: r.Style},
+				StyleRun{Start: pos + n, End: r.End + n, Style: r.Style},
+			)
+		}
+	}
+	// Runs outside the inserted text are clipped to it, and dropped by
+	// normalizeRuns if they end up empty.
+	for _, r := range runs {
+		r.Start = max(0, min(r.Start, n)) + pos
+		r.End = max(0, min(r.End, n)) + pos
+		res = append(res, r)
+	}
+	return normalizeRuns(res)
+}
+
+// concatRuns appends the runs of b, shifted by off, to a.
+func concatRuns(a, b []StyleRun, off int) []StyleRun {
+	res := append([]StyleRun(nil), a...)
+	for _, r := range b {
+		r.Start += off
+		r.End += off
+		res = append(res, r)
+	}
+	return normalizeRuns(res)
+}
+
+// normalizeRuns sorts runs, merges adjacent runs with equal styles, and
+// removes empty and unstyled runs.
+func normalizeRuns(runs []StyleRun) []StyleRun {
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].Start < runs[j].Start
+	})
+	res := runs[:0]
+	for _, r := range runs {
+		if r.Start >= r.End || r.Style == (TextStyle{}) {
+			continue
+		}
+		if n := len(res); n > 0 {
+			prev := &res[n-1]
+			if prev.End == r.Start && prev.Style == r.Style && r.Style.Object == nil {
+				prev.End = r.End
+				continue
+			}
+		}
+		res = append(res, r)
+	}
+	if len(res) == 0 {
+		return nil
+	}
+	return res
+}
+
+// remapRuns adjusts the offsets of runs for the removal of the runes at
+// the sorted offsets in dropped.
+func remapRuns(runs []StyleRun, dropped []int) []StyleRun {
+	if len(dropped) == 0 {
+		return runs
+	}
+	remap := func(pos int) int {
+		return pos - sort.SearchInts(dropped, pos)
+	}
+	res := make([]StyleRun, 0, len(runs))
+	for _, r := range runs {
+		r.Start, r.End = remap(r.Start), remap(r.End)
+		res = append(res, r)
+	}
+	return normalizeRuns(res)
+}
+
+// styleFont returns the font for text in style s.
+func (e *Editor) styleFont(s TextStyle) text.Font {
+	f := e.font
+	if s.Bold {
+		f.Weight = text.Bold
+	}
+	if s.Italic {
+		f.Style = text.Italic
+	}
+	return f
+}
+
+// measureObjects lays out the inline objects to determine their sizes.
+// The recorded objects are drawn by paintStyledLine.
+func (e *Editor) measureObjects(gtx layout.Context) {
+	for _, r := range e.styles {
+		obj := r.Style.Object
+		if obj == nil || obj.Widget == nil {
+			continue
+		}
+		ogtx := gtx
+		ogtx.Constraints.Min = image.Point{}
+		macro := op.Record(gtx.Ops)
+		dims := obj.Widget(ogtx)
+		obj.call = macro.Stop()
+		if dims != obj.dims {
+			obj.dims = dims
+			e.invalidate()
+		}
+	}
+}
+
+// layoutStyled lays out the editor text with its style runs, shaping every
+// run with its own font. Lines are broken at the line break opportunities
+// of UAX #14, and keep the direction and visual order the shaper gives the
+// paragraph in the base font.
+func (e *Editor) layoutStyled(s text.Shaper) ([]text.Line, [][]styleSpan) {
+	runes := []rune(e.rr.String())
+	params := e.params
+	params.MaxLines = 0
+	var ascent, descent fixed.Int26_6
+	if ls := s.LayoutString(e.font, e.textSize, inf, e.locale, params, " "); len(ls) > 0 {
+		ascent, descent = ls[0].Ascent, ls[0].Descent
+	}
+	p := styledParagraph{
+		e:       e,
+		shaper:  s,
+		runes:   runes,
+		params:  params,
+		ascent:  ascent,
+		descent: descent,
+	}
+	for start := 0; ; {
+		end := start
+		for end < len(runes) && runes[end] != '\n' {
+			end++
+		}
+		p.layout(start, end, end < len(runes))
+		if end == len(runes) {
+			break
+		}
+		start = end + 1
+	}
+	return p.lines, p.spans
+}
+
+// styledParagraph accumulates the lines of styled paragraphs.
+type styledParagraph struct {
+	e      *Editor
+	shaper text.Shaper
+	runes  []rune
+	// params are the layout parameters of the spans, without truncation.
+	params text.Parameters
+	// ascent and descent are the metrics of the base font.
+	ascent, descent fixed.Int26_6
+
+	lines []text.Line
+	spans [][]styleSpan
+
+	// Scratch space for the current paragraph.
+	glyphs   []text.Glyph
+	clusters []text.GlyphCluster
+	pspans   []paragraphSpan
+	// dir is the direction of the paragraph.
+	dir system.TextDirection
+	// rank and rtl are the visual position and direction of every rune
+	// of the paragraph.
+	rank []int
+	rtl  []bool
+	// breaks reports whether a line may be broken after every rune of
+	// the paragraph.
+	breaks []bool
+}
+
+// paragraphSpan is a styleSpan that covers a range of clusters.
+type paragraphSpan struct {
+	styleSpan
+	clusters text.Range
+}
+
+// layout lays out the runes between start and end. If newline is set, the
+// paragraph is terminated by a newline at end.
+func (p *styledParagraph) layout(start, end int, newline bool) {
+	e := p.e
+	p.glyphs = p.glyphs[:0]
+	p.clusters = p.clusters[:0]
+	p.pspans = p.pspans[:0]
+	for pos := start; pos < end; {
+		st := e.StyleAt(pos)
+		next := end
+		i := sort.Search(len(e.styles), func(i int) bool {
+			return e.styles[i].End > pos
+		})
+		if i < len(e.styles) {
+			if r := e.styles[i]; r.Start > pos {
+				next = min(next, r.Start)
+			} else {
+				next = min(next, r.End)
+			}
+		}
+		span := paragraphSpan{
+			styleSpan: styleSpan{
+				style: st,
+				font:  e.styleFont(st),
+				glyphs: text.Range{
+					Offset: len(p.glyphs),
+				},
+			},
+			clusters: text.Range{
+				Offset: len(p.clusters),
+			},
+		}
+		if obj := st.Object; obj != nil {
+			next = pos + 1
+			span.ascent = fixed.I(obj.dims.Size.Y - obj.dims.Baseline)
+			span.descent = fixed.I(obj.dims.Baseline)
+			p.clusters = append(p.clusters, text.GlyphCluster{
+				Advance: fixed.I(obj.dims.Size.X),
+				Runes:   text.Range{Count: 1, Offset: pos},
+				Glyphs:  text.Range{Offset: len(p.glyphs)},
+			})
+		} else {
+			lines := p.shaper.LayoutString(span.font, e.textSize, inf, e.locale, p.params, string(p.runes[pos:next]))
+			for _, l := range lines {
+				glyphOff := len(p.glyphs)
+				for _, g := range l.Layout.Glyphs {
+					g.ClusterIndex += pos
+					p.glyphs = append(p.glyphs, g)
+				}
+				for _, c := range l.Layout.Clusters {
+					c.Runes.Offset += pos
+					c.Glyphs.Offset += glyphOff
+					p.clusters = append(p.clusters, c)
+				}
+				if l.Ascent > span.ascent {
+					span.ascent = l.Ascent
+				}
+				if l.Descent > span.descent {
+					span.descent = l.Descent
+				}
+			}
+		}
+		span.glyphs.Count = len(p.glyphs) - span.glyphs.Offset
+		span.clusters.Count = len(p.clusters) - span.clusters.Offset
+		p.pspans = append(p.pspans, span)
+		pos = next
+	}
+	p.order(start, end)
+	p.breaks = lineBreaks(p.breaks[:0], p.runes[start:end])
+	maxWidth := fixed.I(e.maxWidth)
+	lineStart, lastBreak := 0, -1
+	var width fixed.Int26_6
+	for i, c := range p.clusters {
+		last := c.Runes.Offset + c.Runes.Count - 1 - start
+		space := c.Runes.Count > 0 && unicode.IsSpace(p.runes[start+last])
+		adv := fixedAbs(c.Advance)
+		if !space && i > lineStart && width+adv > maxWidth {
+			brk := lastBreak
+			if brk < lineStart {
+				brk = i - 1
+			}
+			p.emit(start, lineStart, brk+1, false)
+			lineStart = brk + 1
+			width = 0
+			for _, c := range p.clusters[lineStart:i] {
+				width += fixedAbs(c.Advance)
+			}
+		}
+		width += adv
+		if c.Runes.Count > 0 && p.breaks[last] {
+			lastBreak = i
+		}
+	}
+	p.emit(start, lineStart, len(p.clusters), newline)
+}
+
+// order determines the paragraph direction and the visual order of the
+// runes between start and end by laying them out in the base font.
+func (p *styledParagraph) order(start, end int) {
+	e := p.e
+	n := end - start
+	p.rank = append(p.rank[:0], make([]int, n)...)
+	p.rtl = append(p.rtl[:0], make([]bool, n)...)
+	p.dir = e.locale.Direction
+	lines := p.shaper.LayoutString(e.font, e.textSize, inf, e.locale, p.params, string(p.runes[start:end]))
+	if len(lines) > 0 {
+		p.dir = lines[0].Layout.Direction
+	}
+	rank := 0
+	for _, l := range lines {
+		for _, ci := range l.Layout.VisualOrder() {
+			c := l.Layout.Clusters[ci]
+			rtl := c.Advance < 0 || (c.Advance == 0 && l.Layout.Direction.Progression() == system.TowardOrigin)
+			for r := c.Runes.Offset; r < c.Runes.Offset+c.Runes.Count && r < n; r++ {
+				p.rank[r] = rank
+				p.rtl[r] = rtl
+			}
+			rank++
+		}
+	}
+}
+
+// lineBreaks appends to breaks whether a line may be broken after each
+// rune of txt, by the rules of UAX #14.
+func lineBreaks(breaks []bool, txt []rune) []bool {
+	breaks = append(breaks, make([]bool, len(txt))...)
+	segmenter := segment.NewSegmenter(uax14.NewLineWrap())
+	segmenter.InitFromSlice(txt)
+	n := 0
+	for segmenter.Next() {
+		n += len(segmenter.Runes())
+		if n > 0 {
+			breaks[n-1] = true
+		}
+	}
+	return breaks
+}
+
+func fixedAbs(v fixed.Int26_6) fixed.Int26_6 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// emit adds a line made of the clusters between c0 and c1. The glyphs
+// of the line are ordered by the visual order of the paragraph.
+func (p *styledParagraph) emit(paraStart, c0, c1 int, newline bool) {
+	runeOff := paraStart
+	if c0 < len(p.clusters) {
+		runeOff = p.clusters[c0].Runes.Offset
+	}
+	line := text.Line{
+		Ascent:  p.ascent,
+		Descent: p.descent,
+	}
+	line.Layout.Direction = p.dir
+	line.Layout.Runes.Offset = runeOff
+	line.Layout.Clusters = make([]text.GlyphCluster, c1-c0)
+	visual := make([]int, c1-c0)
+	for i := range visual {
+		visual[i] = c0 + i
+	}
+	sort.SliceStable(visual, func(i, j int) bool {
+		ci, cj := p.clusters[visual[i]], p.clusters[visual[j]]
+		return p.rank[ci.Runes.Offset-paraStart] < p.rank[cj.Runes.Offset-paraStart]
+	})
+	var spans []styleSpan
+	spanIdx := -1
+	for _, ci := range visual {
+		c := p.clusters[ci]
+		glyphs := p.glyphs[c.Glyphs.Offset : c.Glyphs.Offset+c.Glyphs.Count]
+		adv := fixedAbs(c.Advance)
+		c.Glyphs.Offset = len(line.Layout.Glyphs)
+		c.X = line.Width
+		c.Advance = adv
+		if p.rtl[c.Runes.Offset-paraStart] {
+			c.X += adv
+			c.Advance = -adv
+		}
+		line.Layout.Glyphs = append(line.Layout.Glyphs, glyphs...)
+		line.Layout.Clusters[ci-c0] = c
+		line.Layout.Runes.Count += c.Runes.Count
+		// Extend the span of the previous cluster if the cluster belongs
+		// to the same paragraph span.
+		psIdx := sort.Search(len(p.pspans), func(i int) bool {
+			ps := p.pspans[i]
+			return ps.clusters.Offset+ps.clusters.Count > ci
+		})
+		if psIdx == spanIdx {
+			spans[len(spans)-1].glyphs.Count += len(glyphs)
+		} else {
+			span := p.pspans[psIdx].styleSpan
+			span.glyphs = text.Range{Offset: c.Glyphs.Offset, Count: len(glyphs)}
+			span.x = line.Width
+			if span.ascent > line.Ascent {
+				line.Ascent = span.ascent
+			}
+			if span.descent > line.Descent {
+				line.Descent = span.descent
+			}
+			spans = append(spans, span)
+			spanIdx = psIdx
+		}
+		line.Width += adv
+	}
+	if newline {
+		nl := text.GlyphCluster{
+			Runes:  text.Range{Count: 1, Offset: runeOff + line.Layout.Runes.Count},
+			Glyphs: text.Range{Offset: len(line.Layout.Glyphs)},
+			X:      line.Width,
+		}
+		if p.dir.Progression() == system.TowardOrigin {
+			nl.Glyphs.Offset = 0
+			nl.X = 0
+		}
+		line.Layout.Clusters = append(line.Layout.Clusters, nl)
+		line.Layout.Runes.Count++
+	}
+	line.Bounds = fixed.Rectangle26_6{
+		Min: fixed.Point26_6{Y: -line.Ascent},
+		Max: fixed.Point26_6{X: line.Width, Y: line.Descent},
+	}
+	p.lines = append(p.lines, line)
+	p.spans = append(p.spans, spans)
+}
+
+// paintStyledText paints the spans of the visible styled lines, from the
+// line at pos. Spans with a color are painted after the others, because
+// their colors replace the paint material. Color glyphs of spans without
+// a color are painted in between.
+func (e *Editor) paintStyledText(gtx layout.Context, pos combinedPos, cl image.Rectangle, scroll image.Point) {
+	type styledLine struct {
+		idx int
+		off image.Point
+	}
+	var lines []styledLine
+	for !posIsBelow(e.lines, pos, cl.Max.Y) {
+		start := e.closestPosition(combinedPos{lineCol: screenPos{Y: pos.lineCol.Y}})
+		line := e.lines[start.lineCol.Y]
+		x := align(e.Alignment, line.Layout.Direction, line.Width, e.viewSize.X)
+		off := image.Point{X: x.Floor(), Y: start.y}.Sub(scroll)
+		lines = append(lines, styledLine{idx: start.lineCol.Y, off: off})
+		if pos.lineCol.Y == len(e.lines)-1 {
+			break
+		}
+		pos = e.closestPosition(combinedPos{lineCol: screenPos{Y: pos.lineCol.Y + 1}})
+	}
+	var colors colorGlyphs
+	for _, l := range lines {
+		e.paintStyledLine(gtx, l.idx, l.off, false, &colors)
+	}
+	colors.paint(gtx.Ops)
+	for _, l := range lines {
+		e.paintStyledLine(gtx, l.idx, l.off, true, nil)
+	}
+}
+
+// paintStyledLine paints the spans of a styled line at off, either the
+// spans with a color or the others. The color glyphs of spans without a
+// color are added to colors; those of spans with a color are painted
+// after their span.
+func (e *Editor) paintStyledLine(gtx layout.Context, idx int, off image.Point, colored bool, colors *colorGlyphs) {
+	line := e.lines[idx]
+	for _, span := range e.spans[idx] {
+		if (span.style.Color.A != 0) != colored {
+			continue
+		}
+		if colored {
+			paint.ColorOp{Color: span.style.Color}.Add(gtx.Ops)
+		}
+		spanOff := off.Add(image.Point{X: span.x.Floor()})
+		if obj := span.style.Object; obj != nil {
+			if obj.Widget == nil {
+				continue
+			}
+			t := op.Offset(spanOff.Sub(image.Point{Y: span.ascent.Ceil()})).Push(gtx.Ops)
+			obj.call.Add(gtx.Ops)
+			t.Pop()
+			continue
+		}
+		glyphs := line.Layout.Glyphs[span.glyphs.Offset : span.glyphs.Offset+span.glyphs.Count]
+		if len(glyphs) == 0 {
+			continue
+		}
+		l := text.Layout{
+			Glyphs:    glyphs,
+			Direction: line.Layout.Direction,
+		}
+		t := op.Offset(spanOff).Push(gtx.Ops)
+		cl := clip.Outline{Path: e.shaper.Shape(span.font, e.textSize, l)}.Op().Push(gtx.Ops)
+		paint.PaintOp{}.Add(gtx.Ops)
+		cl.Pop()
+		// Links are underlined.
+		decoration := e.Decoration
+		if span.style.Link != "" {
+			decoration |= text.Underline
+		}
+		if decoration != 0 {
+			paintDecorations(gtx.Ops, decoration, e.shaper.DecorationMetrics(span.font, e.textSize), l)
+		}
+		fg := e.TextColor
+		if colored {
+			fg = span.style.Color
+		}
+		call := e.shaper.ShapeColor(span.font, e.textSize, l, fg)
+		if colored {
+			call.Add(gtx.Ops)
+		} else {
+			colors.add(spanOff, call)
+		}
+		t.Pop()
+	}
+}
+
+// linkAt returns the link of the rune at the pixel position pos, if any.
+func (e *Editor) linkAt(pos image.Point) string {
+	if len(e.styles) == 0 {
+		return ""
+	}
+	x := fixed.I(pos.X + e.scrollOff.X)
+	y := pos.Y + e.scrollOff.Y
+	p := e.closestPosition(combinedPos{x: x, y: y})
+	r := p.runes
+	if p.x > x && r > 0 {
+		r--
+	}
+	return e.StyleAt(r).Link
+}
+
+func (LinkEvent) isEditorEvent() {}
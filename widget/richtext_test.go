@@ -0,0 +1,376 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+
+	"github.com/xiaoshengduan/gio-fly/f32"
+	"github.com/xiaoshengduan/gio-fly/font/gofont"
+	"github.com/xiaoshengduan/gio-fly/io/clipboard"
+	"github.com/xiaoshengduan/gio-fly/io/key"
+	"github.com/xiaoshengduan/gio-fly/io/pointer"
+	"github.com/xiaoshengduan/gio-fly/io/router"
+	"github.com/xiaoshengduan/gio-fly/io/system"
+	"github.com/xiaoshengduan/gio-fly/layout"
+	"github.com/xiaoshengduan/gio-fly/op"
+	"github.com/xiaoshengduan/gio-fly/text"
+	"github.com/xiaoshengduan/gio-fly/unit"
+
+	"golang.org/x/image/math/fixed"
+)
+
+func richContext(size image.Point) layout.Context {
+	return layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(size),
+		Locale:      english,
+	}
+}
+
+func TestEditorStyleRuns(t *testing.T) {
+	e := new(Editor)
+	e.SetText("hello world")
+	bold := TextStyle{Bold: true}
+	e.SetStyle(0, 5, bold)
+	if got, want := e.Styles(), []StyleRun{{Start: 0, End: 5, Style: bold}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got styles %v, want %v", got, want)
+	}
+	// Typed text inherits the style before the caret.
+	e.SetCaret(5, 5)
+	e.Insert("!")
+	if got, want := e.Styles(), []StyleRun{{Start: 0, End: 6, Style: bold}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got styles %v after insert, want %v", got, want)
+	}
+	// Deleting styled text and undoing restores the style.
+	e.SetCaret(0, 3)
+	e.Delete(1)
+	if got, want := e.Styles(), []StyleRun{{Start: 0, End: 3, Style: bold}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got styles %v after delete, want %v", got, want)
+	}
+	e.Undo()
+	if got, want := e.Styles(), []StyleRun{{Start: 0, End: 6, Style: bold}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got styles %v after undo, want %v", got, want)
+	}
+	// Style changes are undoable.
+	e.Undo()
+	e.Undo()
+	if got := e.Styles(); len(got) != 0 {
+		t.Errorf("got styles %v after undoing SetStyle, want none", got)
+	}
+	e.Redo()
+	if got, want := e.Styles(), []StyleRun{{Start: 0, End: 5, Style: bold}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got styles %v after redo, want %v", got, want)
+	}
+}
+
+func TestEditorToggleStyle(t *testing.T) {
+	e := new(Editor)
+	gtx := richContext(image.Pt(200, 100))
+	cache := text.NewCache(gofont.Collection())
+	e.SetText("one two")
+	e.Layout(gtx, cache, text.Font{}, unit.Sp(10), nil)
+	e.focused = true
+
+	e.SetCaret(4, 7)
+	gtx.Queue = newQueue(key.Event{Name: "B", Modifiers: key.ModShortcut, State: key.Press})
+	e.Layout(gtx, cache, text.Font{}, unit.Sp(10), nil)
+	if got, want := e.Styles(), []StyleRun{{Start: 4, End: 7, Style: TextStyle{Bold: true}}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got styles %v, want %v", got, want)
+	}
+	// Toggling again clears the style.
+	e.ToggleBold()
+	if got := e.Styles(); len(got) != 0 {
+		t.Errorf("got styles %v, want none", got)
+	}
+	// Toggling without a selection styles typed text.
+	e.SetCaret(7, 7)
+	e.ToggleItalic()
+	e.Insert("s")
+	e.Insert("!")
+	if got, want := e.Styles(), []StyleRun{{Start: 7, End: 9, Style: TextStyle{Italic: true}}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got styles %v, want %v", got, want)
+	}
+}
+
+func TestEditorStyledLayout(t *testing.T) {
+	e := new(Editor)
+	gtx := richContext(image.Pt(60, 200))
+	cache := text.NewCache(gofont.Collection())
+	const txt = "the quick brown fox\njumps over the lazy dog"
+	e.SetText(txt)
+	e.SetStyle(4, 15, TextStyle{Bold: true, Color: color.NRGBA{R: 0xff, A: 0xff}})
+	e.SetStyle(30, 34, TextStyle{Link: "https://example.com"})
+	e.Layout(gtx, cache, text.Font{}, unit.Sp(10), nil)
+	if len(e.spans) != len(e.lines) {
+		t.Fatalf("got %d span lines for %d lines", len(e.spans), len(e.lines))
+	}
+	if len(e.lines) < 4 {
+		t.Errorf("styled text was not wrapped: %d lines", len(e.lines))
+	}
+	runes := 0
+	for i, l := range e.lines {
+		if l.Layout.Runes.Offset != runes {
+			t.Errorf("line %d starts at rune %d, want %d", i, l.Layout.Runes.Offset, runes)
+		}
+		runes += l.Layout.Runes.Count
+	}
+	if runes != e.Len() {
+		t.Errorf("lines cover %d runes, want %d", runes, e.Len())
+	}
+	// Ensure all positions are reachable.
+	for i := 0; i <= e.Len(); i++ {
+		if got := e.closestPosition(combinedPos{runes: i}).runes; got != i {
+			t.Errorf("position %d resolved to %d", i, got)
+		}
+	}
+	e.PaintText(gtx)
+}
+
+func TestEditorStyledBidi(t *testing.T) {
+	e := new(Editor)
+	gtx := richContext(image.Pt(1000, 200))
+	cache := text.NewCache(gofont.Collection())
+	// A right-to-left paragraph with an embedded left-to-right word.
+	const txt = "\u05d0\u05d1\u05d2 abc \u05d3\u05d4"
+	e.SetText(txt)
+	e.SetStyle(1, 6, TextStyle{Bold: true})
+	e.Layout(gtx, cache, text.Font{}, unit.Sp(10), nil)
+	if len(e.lines) != 1 {
+		t.Fatalf("got %d lines, want 1", len(e.lines))
+	}
+	l := e.lines[0].Layout
+	if l.Direction != system.RTL {
+		t.Errorf("got direction %v, want %v", l.Direction, system.RTL)
+	}
+	// Visually, the last Hebrew word is leftmost and the first is
+	// rightmost, with the Latin word in between in its own order.
+	left := func(r int) fixed.Int26_6 {
+		for _, c := range l.Clusters {
+			if c.Runes.Offset <= r && r < c.Runes.Offset+c.Runes.Count {
+				if c.Advance < 0 {
+					return c.X + c.Advance
+				}
+				return c.X
+			}
+		}
+		t.Fatalf("no cluster for rune %d", r)
+		return 0
+	}
+	for _, order := range [][2]int{{9, 8}, {8, 4}, {4, 5}, {5, 6}, {6, 2}, {2, 1}, {1, 0}} {
+		if a, b := left(order[0]), left(order[1]); a >= b {
+			t.Errorf("rune %d at %v is not left of rune %d at %v", order[0], a, order[1], b)
+		}
+	}
+	for _, c := range l.Clusters {
+		r := []rune(txt)[c.Runes.Offset]
+		if r == ' ' {
+			continue
+		}
+		if rtl := r >= 0x5d0 && r <= 0x5ea; rtl != (c.Advance < 0) {
+			t.Errorf("rune %q has advance %v", r, c.Advance)
+		}
+	}
+	for i := 0; i <= e.Len(); i++ {
+		if got := e.closestPosition(combinedPos{runes: i}).runes; got != i {
+			t.Errorf("position %d resolved to %d", i, got)
+		}
+	}
+	e.PaintText(gtx)
+}
+
+func TestEditorStyledBreaks(t *testing.T) {
+	e := new(Editor)
+	cache := text.NewCache(gofont.Collection())
+	// The slash is a line break opportunity by UAX #14.
+	e.SetText("aaaa/bbbb")
+	e.SetStyle(5, 9, TextStyle{Bold: true})
+	e.Layout(richContext(image.Pt(40, 200)), cache, text.Font{}, unit.Sp(10), nil)
+	if len(e.lines) != 2 {
+		t.Fatalf("got %d lines, want 2", len(e.lines))
+	}
+	if got := e.lines[0].Layout.Runes.Count; got != 5 {
+		t.Errorf("first line has %d runes, want 5", got)
+	}
+}
+
+func TestEditorInlineObject(t *testing.T) {
+	e := new(Editor)
+	gtx := richContext(image.Pt(200, 100))
+	cache := text.NewCache(gofont.Collection())
+	calls := 0
+	obj := &InlineObject{
+		Widget: func(gtx layout.Context) layout.Dimensions {
+			calls++
+			return layout.Dimensions{Size: image.Pt(30, 20), Baseline: 5}
+		},
+	}
+	e.SetText("ab")
+	e.SetCaret(1, 1)
+	e.InsertObject(obj)
+	if got, want := e.Text(), "a"+string(ObjectRune)+"b"; got != want {
+		t.Fatalf("got text %q, want %q", got, want)
+	}
+	e.Layout(gtx, cache, text.Font{}, unit.Sp(10), nil)
+	calls = 0
+	gtx.Ops.Reset()
+	e.Layout(gtx, cache, text.Font{}, unit.Sp(10), nil)
+	e.PaintText(gtx)
+	if calls != 1 {
+		t.Errorf("object laid out %d times in a frame, want 1", calls)
+	}
+	objs := e.Objects()
+	if len(objs) != 1 || objs[0].Start != 1 || objs[0].Style.Object != obj {
+		t.Fatalf("got objects %v", objs)
+	}
+	if got := e.lines[0].Ascent.Ceil(); got < 15 {
+		t.Errorf("line ascent %d doesn't fit object", got)
+	}
+	cluster := e.lines[0].Layout.Clusters[1]
+	if got, want := cluster.Advance.Round(), 30; got != want {
+		t.Errorf("object advance %d, want %d", got, want)
+	}
+	// Typing after the object doesn't inherit the object.
+	e.SetCaret(2, 2)
+	e.Insert("x")
+	if got := e.Objects(); len(got) != 1 {
+		t.Errorf("got %d objects after insert, want 1", len(got))
+	}
+	e.SetCaret(1, 2)
+	e.Delete(1)
+	if got := e.Objects(); len(got) != 0 {
+		t.Errorf("got %d objects after delete, want 0", len(got))
+	}
+	e.Undo()
+	if got := e.Objects(); len(got) != 1 || got[0].Style.Object != obj {
+		t.Errorf("undo didn't restore object: %v", got)
+	}
+}
+
+func TestEditorRichClipboard(t *testing.T) {
+	src := new(Editor)
+	src.SetText("plain bold")
+	src.SetStyle(6, 10, TextStyle{Bold: true})
+	src.SetCaret(4, 10)
+	rt := src.SelectedRichText()
+	data, err := MarshalRichText(rt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt2, err := UnmarshalRichText(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rt, rt2) {
+		t.Errorf("got %v after round trip, want %v", rt2, rt)
+	}
+	for _, s := range []string{
+		`{"Text":"ab","Styles":[{"Start":-1,"End":1,"Style":{"Bold":true}}]}`,
+		`{"Text":"ab","Styles":[{"Start":0,"End":3,"Style":{"Bold":true}}]}`,
+		`{"Text":"ab","Styles":[{"Start":2,"End":1,"Style":{"Bold":true}}]}`,
+	} {
+		if _, err := UnmarshalRichText([]byte(s)); err == nil {
+			t.Errorf("UnmarshalRichText accepted %s", s)
+		}
+	}
+	// Runs inserted from untrusted data don't style the text around them.
+	bold := TextStyle{Bold: true}
+	runs := insertRuns(nil, 2, 2, []StyleRun{{Start: -2, End: 1, Style: bold}, {Start: 3, End: 5, Style: bold}})
+	if want := []StyleRun{{Start: 2, End: 3, Style: bold}}; !reflect.DeepEqual(runs, want) {
+		t.Errorf("inserted runs %v, want %v", runs, want)
+	}
+
+	dst := new(Editor)
+	dst.SetText("> ")
+	dst.SetCaret(2, 2)
+	var r router.Router
+	gtx := richContext(image.Pt(200, 100))
+	gtx.Queue = &r
+	cache := text.NewCache(gofont.Collection())
+	frame := func() {
+		gtx.Ops.Reset()
+		src.Layout(gtx, cache, text.Font{}, unit.Sp(10), nil)
+		dst.Layout(gtx, cache, text.Font{}, unit.Sp(10), nil)
+		r.Frame(gtx.Ops)
+	}
+	shortcut := func(name string) {
+		r.Queue(key.Event{Name: name, Modifiers: key.ModShortcut, State: key.Press})
+		frame()
+	}
+	src.Focus()
+	frame()
+	shortcut("C")
+	text, ok := r.WriteClipboard()
+	if !ok || text != rt.Text {
+		t.Fatalf("got clipboard text %q, want %q", text, rt.Text)
+	}
+	dst.Focus()
+	frame()
+	shortcut("V")
+	if !r.ReadClipboard() {
+		t.Fatal("paste didn't read the clipboard")
+	}
+	r.Queue(clipboard.Event{Text: rt.Text})
+	frame()
+	if got, want := dst.Text(), "> n bold"; got != want {
+		t.Errorf("got text %q, want %q", got, want)
+	}
+	if got, want := dst.Styles(), []StyleRun{{Start: 4, End: 8, Style: TextStyle{Bold: true}}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got styles %v, want %v", got, want)
+	}
+
+	// Text copied by another application is pasted without styles.
+	shortcut("V")
+	r.Queue(clipboard.Event{Text: "n bold!"})
+	frame()
+	if got, want := dst.Text(), "> n boldn bold!"; got != want {
+		t.Errorf("got text %q, want %q", got, want)
+	}
+}
+
+func TestEditorRichDrag(t *testing.T) {
+	src, dst := new(Editor), new(Editor)
+	src.SetText("plain bold")
+	src.SetStyle(6, 10, TextStyle{Bold: true})
+	src.SetCaret(10, 6)
+	var r router.Router
+	gtx := richContext(image.Pt(200, 50))
+	gtx.Queue = &r
+	cache := text.NewCache(gofont.Collection())
+	frame := func() {
+		gtx.Ops.Reset()
+		src.Layout(gtx, cache, text.Font{}, unit.Sp(10), nil)
+		off := op.Offset(image.Pt(0, 50)).Push(gtx.Ops)
+		dst.Layout(gtx, cache, text.Font{}, unit.Sp(10), nil)
+		off.Pop()
+		r.Frame(gtx.Ops)
+	}
+	frame()
+	sel := src.selectionRects()
+	if len(sel) != 1 {
+		t.Fatalf("got selection %v, want a single rectangle", sel)
+	}
+	from := layout.FPt(sel[0].Min.Add(sel[0].Max).Div(2))
+	to := f32.Pt(20, 60)
+	r.Queue(
+		pointer.Event{Type: pointer.Press, Source: pointer.Mouse, Buttons: pointer.ButtonPrimary, Position: from},
+		pointer.Event{Type: pointer.Move, Source: pointer.Mouse, Buttons: pointer.ButtonPrimary, Position: from.Add(f32.Pt(1, 0))},
+		pointer.Event{Type: pointer.Move, Source: pointer.Mouse, Buttons: pointer.ButtonPrimary, Position: to},
+		pointer.Event{Type: pointer.Release, Source: pointer.Mouse, Position: to},
+	)
+	// Deliver the request to the source, then the data to the target.
+	frame()
+	frame()
+	if got, want := dst.Text(), "bold"; got != want {
+		t.Errorf("got dropped text %q, want %q", got, want)
+	}
+	if got, want := dst.Styles(), []StyleRun{{Start: 0, End: 4, Style: TextStyle{Bold: true}}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got dropped styles %v, want %v", got, want)
+	}
+	if got, want := src.SelectedText(), "bold"; got != want {
+		t.Errorf("dragging changed the selection to %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,152 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"image"
+
+	"github.com/xiaoshengduan/gio-fly/f32"
+	"github.com/xiaoshengduan/gio-fly/gesture"
+	"github.com/xiaoshengduan/gio-fly/layout"
+	"github.com/xiaoshengduan/gio-fly/op"
+	"github.com/xiaoshengduan/gio-fly/op/clip"
+)
+
+// ScrollView displays a widget that may be larger than the view in both
+// directions, and scrolls it in response to user input. If Zoomable is
+// set, the user can also zoom the widget by pinching or by scrolling with
+// the Shortcut modifier. A zoomable ScrollView consumes all vertical
+// scrolling, even when the view is at its vertical limits.
+type ScrollView struct {
+	// Zoomable enables zooming.
+	Zoomable bool
+	// MinZoom and MaxZoom limit the zoom. Zero means no limit.
+	MinZoom, MaxZoom float32
+	// Position is updated during Layout. To save the scroll position,
+	// save Position after Layout. To scroll programmatically, update
+	// Position before Layout.
+	Position ScrollPosition
+
+	// Horizontal and Vertical are the states of the scrollbars, if any.
+	Horizontal, Vertical Scrollbar
+
+	scroll gesture.Scroll
+	// content is the scaled size of the widget at the last Layout.
+	content image.Point
+	// view is the size of the view at the last Layout.
+	view image.Point
+}
+
+// ScrollPosition is the position of a ScrollView.
+type ScrollPosition struct {
+	// Offset is the position of the view in the scaled widget.
+	Offset image.Point
+	// Zoom is the scale of the widget. Zero means 1.
+	Zoom float32
+}
+
+// scrollViewMax is the maximum constraint for the ScrollView widget.
+const scrollViewMax = 1e6
+
+// Layout the widget w with no minimum and unbounded maximum constraints,
+// scaled and offset by the ScrollView position. The view is as large as
+// the scaled widget, within the constraints.
+func (v *ScrollView) Layout(gtx layout.Context, w layout.Widget) layout.Dimensions {
+	pan := v.scroll.Scroll2D(gtx.Metric, gtx, gtx.Now, v.Zoomable)
+	zoom := v.zoom()
+	if pan.Zoom != 1 {
+		newZoom := v.clampZoom(zoom * pan.Zoom)
+		// Keep the point under the zoom center in place.
+		off := layout.FPt(v.Position.Offset).Add(pan.Center)
+		off = off.Mul(newZoom / zoom).Sub(pan.Center)
+		v.Position.Offset = off.Round()
+		zoom = newZoom
+	}
+	v.Position.Zoom = zoom
+	v.Position.Offset = v.Position.Offset.Add(pan.Distance)
+
+	cgtx := gtx
+	cgtx.Constraints = layout.Constraints{Max: image.Pt(scrollViewMax, scrollViewMax)}
+	macro := op.Record(gtx.Ops)
+	dims := w(cgtx)
+	call := macro.Stop()
+
+	v.content = layout.FPt(dims.Size).Mul(zoom).Round()
+	v.view = gtx.Constraints.Constrain(v.content)
+	v.clampOffset()
+
+	defer clip.Rect(image.Rectangle{Max: v.view}).Push(gtx.Ops).Pop()
+	off := v.Position.Offset
+	bounds := image.Rectangle{
+		Min: off.Mul(-1),
+		Max: v.content.Sub(v.view).Sub(off),
+	}
+	if v.Zoomable {
+		// Zoom scrolling is along the vertical axis, and must not be
+		// limited by the vertical scroll position.
+		bounds.Min.Y, bounds.Max.Y = -scrollViewMax, scrollViewMax
+	}
+	v.scroll.Add(gtx.Ops, bounds)
+	trans := op.Offset(off.Mul(-1)).Push(gtx.Ops)
+	scale := op.Affine(f32.Affine2D{}.Scale(f32.Point{}, f32.Pt(zoom, zoom))).Push(gtx.Ops)
+	call.Add(gtx.Ops)
+	scale.Pop()
+	trans.Pop()
+	return layout.Dimensions{Size: v.view}
+}
+
+// ScrollBy scrolls the view by fractions dx and dy of the scaled widget
+// size, such as the distances reported by Scrollbar.ScrollDistance.
+func (v *ScrollView) ScrollBy(dx, dy float32) {
+	v.Position.Offset = v.Position.Offset.Add(image.Pt(
+		int(dx*float32(v.content.X)+.5),
+		int(dy*float32(v.content.Y)+.5),
+	))
+	v.clampOffset()
+}
+
+// Viewport returns the start and end of the view relative to the scaled
+// widget, along axis, as values in the range [0,1].
+func (v *ScrollView) Viewport(axis layout.Axis) (start, end float32) {
+	content := axis.Convert(v.content).X
+	if content == 0 {
+		return 0, 1
+	}
+	off := axis.Convert(v.Position.Offset).X
+	view := axis.Convert(v.view).X
+	return float32(off) / float32(content), float32(off+view) / float32(content)
+}
+
+// Dragging reports whether the view is being scrolled with a drag
+// gesture.
+func (v *ScrollView) Dragging() bool {
+	return v.scroll.State() == gesture.StateDragging
+}
+
+func (v *ScrollView) zoom() float32 {
+	if v.Position.Zoom == 0 {
+		return v.clampZoom(1)
+	}
+	return v.Position.Zoom
+}
+
+func (v *ScrollView) clampZoom(z float32) float32 {
+	if v.MaxZoom != 0 && z > v.MaxZoom {
+		z = v.MaxZoom
+	}
+	if v.MinZoom != 0 && z < v.MinZoom {
+		z = v.MinZoom
+	}
+	return z
+}
+
+// clampOffset keeps the view within the widget.
+func (v *ScrollView) clampOffset() {
+	off := &v.Position.Offset
+	maxOff := v.content.Sub(v.view)
+	off.X = max(min(off.X, maxOff.X), 0)
+	off.Y = max(min(off.Y, maxOff.Y), 0)
+	if (off.X == 0 || off.X == maxOff.X) && (off.Y == 0 || off.Y == maxOff.Y) {
+		v.scroll.Stop()
+	}
+}
@@ -0,0 +1,93 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"image"
+	"testing"
+
+	"github.com/xiaoshengduan/gio-fly/f32"
+	"github.com/xiaoshengduan/gio-fly/io/key"
+	"github.com/xiaoshengduan/gio-fly/io/pointer"
+	"github.com/xiaoshengduan/gio-fly/io/router"
+	"github.com/xiaoshengduan/gio-fly/layout"
+	"github.com/xiaoshengduan/gio-fly/op"
+)
+
+func TestScrollView(t *testing.T) {
+	r := new(router.Router)
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(100, 100)),
+		Queue:       r,
+	}
+	content := func(gtx layout.Context) layout.Dimensions {
+		return layout.Dimensions{Size: image.Pt(400, 300)}
+	}
+	layoutView := func(v *ScrollView) layout.Dimensions {
+		gtx.Ops.Reset()
+		dims := v.Layout(gtx, content)
+		r.Frame(gtx.Ops)
+		return dims
+	}
+	scroll := func(d f32.Point, mods key.Modifiers) {
+		r.Queue(
+			pointer.Event{
+				Source:   pointer.Mouse,
+				Type:     pointer.Move,
+				Position: f32.Pt(50, 50),
+			},
+			pointer.Event{
+				Source:    pointer.Mouse,
+				Type:      pointer.Scroll,
+				Position:  f32.Pt(50, 50),
+				Scroll:    d,
+				Modifiers: mods,
+			},
+		)
+	}
+
+	var v ScrollView
+	if got, want := layoutView(&v).Size, image.Pt(100, 100); got != want {
+		t.Errorf("size: got %v, want %v", got, want)
+	}
+	layoutView(&v)
+	scroll(f32.Pt(30, 40), 0)
+	layoutView(&v)
+	if got, want := v.Position.Offset, image.Pt(30, 40); got != want {
+		t.Errorf("scroll: got offset %v, want %v", got, want)
+	}
+	if start, end := v.Viewport(layout.Horizontal); start != .075 || end != .325 {
+		t.Errorf("viewport: got %v-%v, want 0.075-0.325", start, end)
+	}
+	// Scrolling is limited by the content.
+	scroll(f32.Pt(1000, 1000), 0)
+	layoutView(&v)
+	if got, want := v.Position.Offset, image.Pt(300, 200); got != want {
+		t.Errorf("scroll past end: got offset %v, want %v", got, want)
+	}
+	// Zooming requires Zoomable.
+	scroll(f32.Pt(0, -100), key.ModShortcut)
+	layoutView(&v)
+	if v.Position.Zoom != 1 {
+		t.Errorf("zoom: got %v, want 1 for non-zoomable view", v.Position.Zoom)
+	}
+
+	// Restore a position.
+	v = ScrollView{Zoomable: true, MaxZoom: 2}
+	v.Position = ScrollPosition{Offset: image.Pt(100, 100), Zoom: 1}
+	layoutView(&v)
+	layoutView(&v)
+	if got, want := v.Position.Offset, image.Pt(100, 100); got != want {
+		t.Errorf("restore: got offset %v, want %v", got, want)
+	}
+	// Zoom in around the pointer, limited by MaxZoom.
+	scroll(f32.Pt(0, -1000), key.ModShortcut)
+	layoutView(&v)
+	if got, want := v.Position.Zoom, float32(2); got != want {
+		t.Errorf("zoom: got %v, want %v", got, want)
+	}
+	if got, want := v.Position.Offset, image.Pt(250, 250); got != want {
+		t.Errorf("zoom: got offset %v, want %v", got, want)
+	}
+}
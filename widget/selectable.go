@@ -0,0 +1,86 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"image/color"
+
+	"github.com/xiaoshengduan/gio-fly/layout"
+	"github.com/xiaoshengduan/gio-fly/text"
+	"github.com/xiaoshengduan/gio-fly/unit"
+)
+
+// Selectable displays read-only text that the user can select and copy
+// to the clipboard. Double-clicking selects a word, triple-clicking
+// selects a line.
+type Selectable struct {
+	// Alignment specifies the text alignment.
+	Alignment text.Alignment
+	// TextColor is the color of the parts of color glyphs, such as emoji,
+	// that are drawn in the color of the text.
+	TextColor color.NRGBA
+
+	text   string
+	editor Editor
+}
+
+// SetText replaces the text displayed, clearing the selection if the text
+// changed.
+func (s *Selectable) SetText(txt string) {
+	if s.text == txt {
+		return
+	}
+	s.text = txt
+	s.editor.SetText(txt)
+	s.editor.ClearHistory()
+}
+
+// Text returns the text displayed.
+func (s *Selectable) Text() string {
+	return s.text
+}
+
+// Focused returns whether the text has the input focus.
+func (s *Selectable) Focused() bool {
+	return s.editor.Focused()
+}
+
+// Selection returns the start and end of the selection, as rune offsets.
+// start can be > end.
+func (s *Selectable) Selection() (start, end int) {
+	return s.editor.Selection()
+}
+
+// SetCaret sets the selection to the runes between start and end.
+func (s *Selectable) SetCaret(start, end int) {
+	s.editor.SetCaret(start, end)
+}
+
+// SelectedText returns the currently selected text, if any.
+func (s *Selectable) SelectedText() string {
+	return s.editor.SelectedText()
+}
+
+// ClearSelection clears the selection.
+func (s *Selectable) ClearSelection() {
+	s.editor.ClearSelection()
+}
+
+// Layout lays out the text. If content is not nil, it is laid out on top,
+// and is responsible for calling PaintSelection and PaintText.
+func (s *Selectable) Layout(gtx layout.Context, sh text.Shaper, font text.Font, size unit.Sp, content layout.Widget) layout.Dimensions {
+	s.editor.ReadOnly = true
+	s.editor.Alignment = s.Alignment
+	s.editor.TextColor = s.TextColor
+	return s.editor.Layout(gtx, sh, font, size, content)
+}
+
+// PaintSelection paints the contrasting background for selected text.
+func (s *Selectable) PaintSelection(gtx layout.Context) {
+	s.editor.PaintSelection(gtx)
+}
+
+// PaintText paints the text glyphs.
+func (s *Selectable) PaintText(gtx layout.Context) {
+	s.editor.PaintText(gtx)
+}
@@ -0,0 +1,90 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	"github.com/xiaoshengduan/gio-fly/f32"
+	"github.com/xiaoshengduan/gio-fly/font/gofont"
+	"github.com/xiaoshengduan/gio-fly/gesture"
+	"github.com/xiaoshengduan/gio-fly/io/event"
+	"github.com/xiaoshengduan/gio-fly/io/key"
+	"github.com/xiaoshengduan/gio-fly/io/pointer"
+	"github.com/xiaoshengduan/gio-fly/layout"
+	"github.com/xiaoshengduan/gio-fly/op"
+	"github.com/xiaoshengduan/gio-fly/text"
+	"github.com/xiaoshengduan/gio-fly/unit"
+)
+
+func TestSelectableReadOnly(t *testing.T) {
+	s := new(Selectable)
+	s.SetText("hello world")
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(200, 100)),
+		Locale:      english,
+	}
+	cache := text.NewCache(gofont.Collection())
+	font := text.Font{}
+	fontSize := unit.Sp(10)
+	s.Layout(gtx, cache, font, fontSize, nil)
+	s.editor.focused = true
+
+	gtx.Queue = newQueue(
+		key.EditEvent{Range: key.Range{Start: 0, End: 5}, Text: "bye"},
+		key.Event{Name: key.NameDeleteBackward, State: key.Press},
+		key.Event{Name: key.NameReturn, State: key.Press},
+	)
+	s.SetCaret(5, 5)
+	s.Layout(gtx, cache, font, fontSize, nil)
+	if got, want := s.editor.Text(), "hello world"; got != want {
+		t.Errorf("read-only text modified: got %q, want %q", got, want)
+	}
+	gtx.Queue = newQueue(key.Event{Name: key.NameEnd, Modifiers: key.ModShift, State: key.Press})
+	s.Layout(gtx, cache, font, fontSize, nil)
+	if got, want := s.SelectedText(), " world"; got != want {
+		t.Errorf("got selection %q, want %q", got, want)
+	}
+}
+
+func TestSelectableClicks(t *testing.T) {
+	s := new(Selectable)
+	s.SetText("hello world\nsecond line")
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(200, 100)),
+		Locale:      english,
+	}
+	cache := text.NewCache(gofont.Collection())
+	font := text.Font{}
+	fontSize := unit.Sp(10)
+	s.Layout(gtx, cache, font, fontSize, nil)
+
+	pos := f32.Pt(textWidth(&s.editor, 0, 0, 2), textHeight(&s.editor, 0))
+	clicks := func(n int) []event.Event {
+		var events []event.Event
+		for i := 0; i < n; i++ {
+			at := time.Duration(i) * time.Millisecond
+			events = append(events,
+				pointer.Event{Type: pointer.Press, Source: pointer.Mouse, Buttons: pointer.ButtonPrimary, Position: pos, Time: at},
+				pointer.Event{Type: pointer.Release, Source: pointer.Mouse, Position: pos, Time: at},
+			)
+		}
+		return events
+	}
+	gtx.Queue = newQueue(clicks(2)...)
+	s.Layout(gtx, cache, font, fontSize, nil)
+	if got, want := s.SelectedText(), "hello"; got != want {
+		t.Errorf("double-click selected %q, want %q", got, want)
+	}
+	s.ClearSelection()
+	s.editor.clicker = gesture.Click{}
+	gtx.Queue = newQueue(clicks(3)...)
+	s.Layout(gtx, cache, font, fontSize, nil)
+	if got, want := s.SelectedText(), "hello world"; got != want {
+		t.Errorf("triple-click selected %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,334 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"bufio"
+	"image"
+	"image/color"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/gioui/uax/segment"
+	"github.com/gioui/uax/uax29"
+
+	"github.com/xiaoshengduan/gio-fly/f32"
+	"github.com/xiaoshengduan/gio-fly/io/pointer"
+	"github.com/xiaoshengduan/gio-fly/layout"
+	"github.com/xiaoshengduan/gio-fly/op"
+	"github.com/xiaoshengduan/gio-fly/op/clip"
+	"github.com/xiaoshengduan/gio-fly/op/paint"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// SpellChecker checks the spelling of the text in an Editor.
+type SpellChecker interface {
+	// Misspelled returns the misspelled words of text, given the
+	// ranges of all its words.
+	Misspelled(text string, words []TextRange) []TextRange
+	// Suggest returns replacements for a misspelled word, best first.
+	Suggest(word string) []string
+}
+
+// TextRange is a range of runes, from Start up to but not including End.
+type TextRange struct {
+	Start, End int
+}
+
+// A SpellEvent is generated when the user presses the secondary pointer
+// button over a misspelled word. Applications typically show the
+// suggestions in a context menu, and replace the word with the choice
+// through Editor.Replace.
+type SpellEvent struct {
+	// Range is the rune range of the word.
+	Range TextRange
+	// Word is the misspelled word.
+	Word string
+	// Suggestions are the replacements suggested by the SpellChecker.
+	Suggestions []string
+	// Position is the pointer position relative to the Editor.
+	Position image.Point
+}
+
+// Dictionary is a SpellChecker that accepts the words from a list. Words
+// are matched exactly, or by their lower case form.
+type Dictionary struct {
+	words map[string]struct{}
+}
+
+// maxSuggestions is the maximum number of suggestions returned by a
+// Dictionary.
+const maxSuggestions = 8
+
+// NewDictionary reads a list of words, one per line. Empty lines and
+// lines starting with '#' are ignored.
+func NewDictionary(r io.Reader) (*Dictionary, error) {
+	d := &Dictionary{words: make(map[string]struct{})}
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		w := strings.TrimSpace(s.Text())
+		if w == "" || strings.HasPrefix(w, "#") {
+			continue
+		}
+		d.words[w] = struct{}{}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// LoadDictionary reads a list of words from the named file, in the
+// format accepted by NewDictionary.
+func LoadDictionary(name string) (*Dictionary, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return NewDictionary(f)
+}
+
+// Contains reports whether word is in the dictionary.
+func (d *Dictionary) Contains(word string) bool {
+	if _, ok := d.words[word]; ok {
+		return true
+	}
+	_, ok := d.words[strings.ToLower(word)]
+	return ok
+}
+
+func (d *Dictionary) Misspelled(text string, words []TextRange) []TextRange {
+	var bad []TextRange
+	runes := []rune(text)
+	for _, w := range words {
+		if !d.Contains(string(runes[w.Start:w.End])) {
+			bad = append(bad, w)
+		}
+	}
+	return bad
+}
+
+// Suggest returns the dictionary words closest to word, up to an edit
+// distance of 2.
+func (d *Dictionary) Suggest(word string) []string {
+	type candidate struct {
+		word string
+		dist int
+	}
+	var cands []candidate
+	w := []rune(strings.ToLower(word))
+	for dw := range d.words {
+		r := []rune(strings.ToLower(dw))
+		if n := len(r) - len(w); n > 2 || n < -2 {
+			continue
+		}
+		if dist := editDistance(w, r); dist <= 2 {
+			cands = append(cands, candidate{dw, dist})
+		}
+	}
+	sort.Slice(cands, func(i, j int) bool {
+		if cands[i].dist != cands[j].dist {
+			return cands[i].dist < cands[j].dist
+		}
+		return cands[i].word < cands[j].word
+	})
+	if len(cands) > maxSuggestions {
+		cands = cands[:maxSuggestions]
+	}
+	var res []string
+	for _, c := range cands {
+		res = append(res, c.word)
+	}
+	return res
+}
+
+// editDistance returns the Damerau-Levenshtein distance between a and b,
+// counting transpositions of adjacent runes as a single edit.
+func editDistance(a, b []rune) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(min(d[i-1][j]+1, d[i][j-1]+1), d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+// wordRanges returns the ranges of the words of text, as determined by
+// Unicode word segmentation. Segments without letters, such as spaces
+// and punctuation, are not words.
+func wordRanges(text []rune) []TextRange {
+	var words []TextRange
+	seg := segment.NewSegmenter(uax29.NewWordBreaker(1))
+	seg.InitFromSlice(text)
+	off := 0
+	for seg.Next() {
+		runes := seg.Runes()
+		for _, r := range runes {
+			if unicode.IsLetter(r) {
+				words = append(words, TextRange{Start: off, End: off + len(runes)})
+				break
+			}
+		}
+		off += len(runes)
+	}
+	return words
+}
+
+// Replace replaces the runes between start and end with s.
+func (e *Editor) Replace(start, end int, s string) {
+	e.replace(start, end, s, true)
+	e.caret.xoff = 0
+}
+
+// Misspelled returns the misspelled words found by the SpellChecker.
+func (e *Editor) Misspelled() []TextRange {
+	e.checkSpelling()
+	return e.spell.misspelled
+}
+
+// checkSpelling updates the misspelled words, if the text changed.
+func (e *Editor) checkSpelling() {
+	if e.SpellChecker == nil || e.Mask != 0 {
+		e.spell.misspelled = nil
+		e.spell.valid = false
+		return
+	}
+	if e.spell.valid {
+		return
+	}
+	e.spell.valid = true
+	txt := e.Text()
+	e.spell.misspelled = e.SpellChecker.Misspelled(txt, wordRanges([]rune(txt)))
+}
+
+// misspelledAt returns the misspelled word containing the rune offset r.
+func (e *Editor) misspelledAt(r int) (TextRange, bool) {
+	for _, w := range e.spell.misspelled {
+		if w.Start <= r && r < w.End {
+			return w, true
+		}
+	}
+	return TextRange{}, false
+}
+
+// processSpelling generates SpellEvents for secondary button presses
+// over misspelled words.
+func (e *Editor) processSpelling(gtx layout.Context) {
+	for _, evt := range gtx.Events(&e.spell) {
+		pe, ok := evt.(pointer.Event)
+		if !ok || pe.Type != pointer.Press || pe.Buttons != pointer.ButtonSecondary {
+			continue
+		}
+		e.checkSpelling()
+		pos := pe.Position.Round()
+		x := pos.X + e.scrollOff.X
+		p := e.closestPosition(combinedPos{x: fixed.I(x), y: pos.Y + e.scrollOff.Y})
+		r := p.runes
+		if p.x.Round() > x && r > 0 {
+			r--
+		}
+		w, ok := e.misspelledAt(r)
+		if !ok {
+			continue
+		}
+		word := e.textRange(w.Start, w.End)
+		e.events = append(e.events, SpellEvent{
+			Range:       w,
+			Word:        word,
+			Suggestions: e.SpellChecker.Suggest(word),
+			Position:    pos,
+		})
+	}
+}
+
+// defaultSpellColor is the color of the spelling underlines if the
+// Editor has no SpellColor.
+var defaultSpellColor = color.NRGBA{R: 0xe0, G: 0x20, B: 0x20, A: 0xff}
+
+// paintSpelling underlines the visible misspelled words with wavy lines.
+func (e *Editor) paintSpelling(gtx layout.Context, cl image.Rectangle) {
+	if len(e.spell.misspelled) == 0 || len(e.lines) == 0 {
+		return
+	}
+	// The current material may be the color of the last color glyph.
+	col := e.SpellColor
+	if col == (color.NRGBA{}) {
+		col = defaultSpellColor
+	}
+	paint.ColorOp{Color: col}.Add(gtx.Ops)
+	width := float32(max(1, gtx.Dp(1)))
+	t := op.Offset(e.scrollOff.Mul(-1)).Push(gtx.Ops)
+	defer t.Pop()
+	for _, w := range e.spell.misspelled {
+		start := e.closestPosition(combinedPos{runes: w.Start})
+		end := e.closestPosition(combinedPos{runes: w.End})
+		for y := start.lineCol.Y; y <= end.lineCol.Y; y++ {
+			a, b := start, end
+			if y > start.lineCol.Y {
+				a = e.closestPosition(combinedPos{lineCol: screenPos{Y: y}})
+			}
+			if y < end.lineCol.Y {
+				b = e.closestPosition(combinedPos{lineCol: screenPos{Y: y, X: maxInt}})
+			}
+			line := e.lines[y]
+			base := a.y + (line.Descent.Ceil()+1)/2
+			if base < cl.Min.Y || base-line.Ascent.Ceil() > cl.Max.Y {
+				continue
+			}
+			x0, x1 := a.x.Round(), b.x.Round()
+			if x0 > x1 {
+				x0, x1 = x1, x0
+			}
+			paintWave(gtx, float32(x0), float32(x1), float32(base), width)
+		}
+	}
+}
+
+// paintWave strokes a wavy line from (x0, y) to (x1, y).
+func paintWave(gtx layout.Context, x0, x1, y, width float32) {
+	if x1 <= x0 {
+		return
+	}
+	amp := width * 1.5
+	half := width * 2
+	var p clip.Path
+	p.Begin(gtx.Ops)
+	p.MoveTo(f32.Pt(x0, y))
+	up := true
+	for x := x0; x < x1; x += half {
+		end := x + half
+		if end > x1 {
+			end = x1
+		}
+		dy := amp
+		if up {
+			dy = -amp
+		}
+		p.QuadTo(f32.Pt((x+end)/2, y+dy), f32.Pt(end, y))
+		up = !up
+	}
+	defer clip.Stroke{Path: p.End(), Width: width}.Op().Push(gtx.Ops).Pop()
+	paint.PaintOp{}.Add(gtx.Ops)
+}
+
+func (SpellEvent) isEditorEvent() {}
@@ -0,0 +1,96 @@
+// SPDX-License-Identifier: Unlicense OR MIT
+
+package widget
+
+import (
+	"image"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xiaoshengduan/gio-fly/f32"
+	"github.com/xiaoshengduan/gio-fly/font/gofont"
+	"github.com/xiaoshengduan/gio-fly/io/pointer"
+	"github.com/xiaoshengduan/gio-fly/layout"
+	"github.com/xiaoshengduan/gio-fly/op"
+	"github.com/xiaoshengduan/gio-fly/text"
+	"github.com/xiaoshengduan/gio-fly/unit"
+)
+
+const testDictionary = `# Test words.
+hello
+world
+word
+lord
+wild
+`
+
+func TestDictionary(t *testing.T) {
+	d, err := NewDictionary(strings.NewReader(testDictionary))
+	if err != nil {
+		t.Fatal(err)
+	}
+	txt := "Hello, wrold! 42 hello-world"
+	words := wordRanges([]rune(txt))
+	want := []TextRange{{0, 5}, {7, 12}, {17, 22}, {23, 28}}
+	if !reflect.DeepEqual(words, want) {
+		t.Errorf("got words %v, want %v", words, want)
+	}
+	if got, want := d.Misspelled(txt, words), []TextRange{{7, 12}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got misspelled %v, want %v", got, want)
+	}
+	if got, want := d.Suggest("wrold"), []string{"world", "wild", "word"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got suggestions %v, want %v", got, want)
+	}
+}
+
+func TestEditorSpellCheck(t *testing.T) {
+	d, err := NewDictionary(strings.NewReader(testDictionary))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := &Editor{SpellChecker: d}
+	e.SetText("helo world")
+	gtx := layout.Context{
+		Ops:         new(op.Ops),
+		Constraints: layout.Exact(image.Pt(200, 100)),
+		Locale:      english,
+	}
+	cache := text.NewCache(gofont.Collection())
+	font := text.Font{}
+	fontSize := unit.Sp(10)
+	e.Layout(gtx, cache, font, fontSize, nil)
+	if got, want := e.Misspelled(), []TextRange{{0, 4}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got misspelled %v, want %v", got, want)
+	}
+	e.PaintText(gtx)
+
+	gtx.Queue = newQueue(pointer.Event{
+		Type:     pointer.Press,
+		Source:   pointer.Mouse,
+		Buttons:  pointer.ButtonSecondary,
+		Position: f32.Pt(3, 5),
+	})
+	e.Layout(gtx, cache, font, fontSize, nil)
+	var evt *SpellEvent
+	for _, ev := range e.Events() {
+		if ev, ok := ev.(SpellEvent); ok {
+			evt = &ev
+		}
+	}
+	if evt == nil {
+		t.Fatal("no SpellEvent for secondary press")
+	}
+	if evt.Word != "helo" || evt.Range != (TextRange{0, 4}) || len(evt.Suggestions) == 0 || evt.Suggestions[0] != "hello" {
+		t.Errorf("unexpected SpellEvent %+v", *evt)
+	}
+	e.Replace(evt.Range.Start, evt.Range.End, evt.Suggestions[0])
+	gtx.Queue = nil
+	e.Layout(gtx, cache, font, fontSize, nil)
+	if got := e.Text(); got != "hello world" {
+		t.Errorf("got text %q after replace", got)
+	}
+	if got := e.Misspelled(); len(got) != 0 {
+		t.Errorf("got misspelled %v after replace", got)
+	}
+}